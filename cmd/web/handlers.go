@@ -1,9 +1,16 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/michaelgov-ctrl/bad-chess/game"
+	"github.com/michaelgov-ctrl/bad-chess/internal/models"
 	"github.com/michaelgov-ctrl/bad-chess/internal/validator"
+	"github.com/michaelgov-ctrl/bad-chess/tournament"
 )
 
 func (app *application) home(w http.ResponseWriter, r *http.Request) {
@@ -26,13 +33,363 @@ func (app *application) matchMakingHandler(w http.ResponseWriter, r *http.Reques
 	app.render(w, r, http.StatusOK, "matchmaking.tmpl.html", data)
 }
 
+func (app *application) lobbyHandler(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	app.render(w, r, http.StatusOK, "lobby.tmpl.html", data)
+}
+
 func (app *application) matchesHandler(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	app.render(w, r, http.StatusOK, "match.tmpl.html", data)
 }
 
+func (app *application) spectateHandler(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	app.render(w, r, http.StatusOK, "spectate.tmpl.html", data)
+}
+
+func (app *application) matchPGNHandler(w http.ResponseWriter, r *http.Request) {
+	app.pgnHandler(w, r, game.Matchmaking, app.matchmakingManager.PGN)
+}
+
+func (app *application) enginePGNHandler(w http.ResponseWriter, r *http.Request) {
+	app.pgnHandler(w, r, game.Engine, app.engineManager.PGN)
+}
+
+// pgnHandler serves games still held by a manager first, then falls back to the finished match store
+func (app *application) pgnHandler(w http.ResponseWriter, r *http.Request, matchType game.MatchType, live func(game.MatchId) (string, bool)) {
+	id := r.PathValue("id")
+
+	pgn, ok := live(game.MatchId(id))
+	if !ok {
+		record, err := app.matches.Get(id)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.notFound(w)
+			} else {
+				app.serverError(w, r, err)
+			}
+			return
+		}
+
+		if record.MatchType != matchType.String() {
+			app.notFound(w)
+			return
+		}
+
+		pgn = record.PGN
+	}
+
+	w.Header().Set("Content-Type", "application/x-chess-pgn")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+".pgn"))
+	w.Write([]byte(pgn))
+}
+
+// matchAnalysisHandler serves the engine analysis of a finished match, 202 while it's still waiting on the engine
+func (app *application) matchAnalysisHandler(w http.ResponseWriter, r *http.Request) {
+	record, err := app.analyses.Get(r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if record.Status == game.AnalysisQueued || record.Status == game.AnalysisRunning {
+		status = http.StatusAccepted
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(record.Analysis))
+}
+
+type tournamentCreateForm struct {
+	Name                string `form:"name"`
+	Format              string `form:"format"`
+	Rounds              int    `form:"rounds"`
+	Minutes             int    `form:"minutes"`
+	Double              bool   `form:"double"`
+	Games               int    `form:"games"`
+	TieBreak            string `form:"tiebreak"`
+	TimeControl         string `form:"timecontrol"`
+	Variant             string `form:"variant"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) tournamentsHandler(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Tournaments = app.tournamentManager.List()
+	data.Form = tournamentCreateForm{Format: tournament.SwissFormat, Rounds: 5, Minutes: 60, Games: 2}
+	app.render(w, r, http.StatusOK, "tournaments.tmpl.html", data)
+}
+
+func (app *application) tournamentCreatePost(w http.ResponseWriter, r *http.Request) {
+	var form tournamentCreateForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	timeControl, err := game.ParseTimeControl(form.TimeControl)
+	_, supported := game.SupportedTimeControls[timeControl]
+	variant, variantErr := game.ParseVariant(form.Variant)
+
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 50), "name", "This field cannot be more than 50 characters long")
+	form.CheckField(validator.PermittedValue(form.Format, tournament.SwissFormat, tournament.ArenaFormat, tournament.RoundRobinFormat, tournament.KnockoutFormat), "format", "Unsupported format")
+	switch form.Format {
+	case tournament.SwissFormat:
+		form.CheckField(form.Rounds >= 1 && form.Rounds <= 15, "rounds", "There must be between 1 and 15 rounds")
+	case tournament.ArenaFormat:
+		form.CheckField(form.Minutes >= 10 && form.Minutes <= 180, "minutes", "An arena must last between 10 and 180 minutes")
+	}
+
+	// knockout tie-breaks are played at their own time control
+	var tieBreak game.TimeControl
+	if form.Format == tournament.KnockoutFormat {
+		var tieBreakErr error
+		tieBreak, tieBreakErr = game.ParseTimeControl(form.TieBreak)
+		_, tieBreakSupported := game.SupportedTimeControls[tieBreak]
+
+		form.CheckField(form.Games >= 1 && form.Games <= 6, "games", "A match must be between 1 and 6 games")
+		form.CheckField(tieBreakErr == nil && tieBreakSupported, "tiebreak", "Unsupported time control")
+	}
+	form.CheckField(err == nil && supported, "timecontrol", "Unsupported time control")
+	form.CheckField(variantErr == nil, "variant", "Unsupported variant")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Tournaments = app.tournamentManager.List()
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "tournaments.tmpl.html", data)
+		return
+	}
+
+	var t tournament.Tournament
+	switch form.Format {
+	case tournament.ArenaFormat:
+		t, err = app.tournamentManager.NewArena(tournament.ArenaConfig{
+			Name:        form.Name,
+			Organizer:   game.UserIdFromContext(r.Context()),
+			Duration:    time.Duration(form.Minutes) * time.Minute,
+			TimeControl: timeControl,
+			Variant:     variant,
+		})
+	case tournament.RoundRobinFormat:
+		t, err = app.tournamentManager.NewRoundRobin(tournament.RoundRobinConfig{
+			Name:        form.Name,
+			Organizer:   game.UserIdFromContext(r.Context()),
+			TimeControl: timeControl,
+			Variant:     variant,
+			Double:      form.Double,
+		})
+	case tournament.KnockoutFormat:
+		t, err = app.tournamentManager.NewKnockout(tournament.KnockoutConfig{
+			Name:                form.Name,
+			Organizer:           game.UserIdFromContext(r.Context()),
+			TimeControl:         timeControl,
+			Variant:             variant,
+			Games:               form.Games,
+			TieBreakTimeControl: tieBreak,
+		})
+	default:
+		t, err = app.tournamentManager.NewSwiss(tournament.SwissConfig{
+			Name:        form.Name,
+			Organizer:   game.UserIdFromContext(r.Context()),
+			Rounds:      form.Rounds,
+			TimeControl: timeControl,
+			Variant:     variant,
+		})
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Your tournament has been created, start it once everyone has joined.")
+	http.Redirect(w, r, "/tournaments/"+t.Info().ID, http.StatusSeeOther)
+}
+
+// tournament looks up the tournament in the path, responding with a 404 if there isn't one
+func (app *application) tournament(w http.ResponseWriter, r *http.Request) (tournament.Tournament, bool) {
+	t, err := app.tournamentManager.Get(r.PathValue("id"))
+	if err != nil {
+		app.notFound(w)
+		return nil, false
+	}
+
+	return t, true
+}
+
+func (app *application) tournamentHandler(w http.ResponseWriter, r *http.Request) {
+	t, ok := app.tournament(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Tournament = t.Snapshot()
+	app.render(w, r, http.StatusOK, "tournament.tmpl.html", data)
+}
+
+func (app *application) tournamentStandingsHandler(w http.ResponseWriter, r *http.Request) {
+	t, ok := app.tournament(w, r)
+	if !ok {
+		return
+	}
+
+	b, err := json.Marshal(t.Snapshot())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func (app *application) tournamentJoinPost(w http.ResponseWriter, r *http.Request) {
+	t, ok := app.tournament(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := app.users.Get(game.UserIdFromContext(r.Context()))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	flash := "You've joined the tournament"
+	if err := t.Register(user.ID, user.Username); err != nil {
+		switch {
+		case errors.Is(err, tournament.ErrRegistrationClosed):
+			flash = "Registration for this tournament has closed"
+		case errors.Is(err, tournament.ErrAlreadyRegistered):
+			flash = "You've already joined this tournament"
+		default:
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", flash)
+	http.Redirect(w, r, "/tournaments/"+t.Info().ID, http.StatusSeeOther)
+}
+
+func (app *application) tournamentWithdrawPost(w http.ResponseWriter, r *http.Request) {
+	t, ok := app.tournament(w, r)
+	if !ok {
+		return
+	}
+
+	flash := "You've left the tournament"
+	if err := t.Withdraw(game.UserIdFromContext(r.Context())); err != nil {
+		switch {
+		case errors.Is(err, tournament.ErrRegistrationClosed):
+			flash = "You can't leave a Swiss tournament once it has started"
+		case errors.Is(err, tournament.ErrNotRegistered):
+			flash = "You aren't in this tournament"
+		default:
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", flash)
+	http.Redirect(w, r, "/tournaments/"+t.Info().ID, http.StatusSeeOther)
+}
+
+func (app *application) tournamentStartPost(w http.ResponseWriter, r *http.Request) {
+	t, ok := app.tournament(w, r)
+	if !ok {
+		return
+	}
+
+	flash := "The tournament has started"
+	if err := app.tournamentManager.Start(t.Info().ID, game.UserIdFromContext(r.Context())); err != nil {
+		switch {
+		case errors.Is(err, tournament.ErrNotOrganizer):
+			app.clientError(w, http.StatusForbidden)
+			return
+		case errors.Is(err, tournament.ErrNotEnoughPlayers):
+			flash = "At least two players need to join before the tournament can start"
+		case errors.Is(err, tournament.ErrAlreadyStarted):
+			flash = "The tournament has already started"
+		default:
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", flash)
+	http.Redirect(w, r, "/tournaments/"+t.Info().ID, http.StatusSeeOther)
+}
+
+type userSignupForm struct {
+	Username            string `form:"username"`
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) userSignup(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = userSignupForm{}
+	app.render(w, r, http.StatusOK, "signup.tmpl.html", data)
+}
+
+func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
+	var form userSignupForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Username), "username", "This field cannot be blank")
+	form.CheckField(validator.MinChars(form.Username, 3), "username", "This field must be at least 3 characters long")
+	form.CheckField(validator.MaxChars(form.Username, 20), "username", "This field cannot be more than 20 characters long")
+	form.CheckField(validator.Matches(form.Username, validator.UsernameRX), "username", "This field can only contain letters, numbers, underscores and hyphens")
+	checkPasswordField(&form.Validator, form.Password, "password")
+
+	if form.Valid() {
+		if err := form.CheckUnique(form.Username, app.users.UsernameTaken, "username", "Username is already in use"); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "signup.tmpl.html", data)
+		return
+	}
+
+	if _, err := app.users.Insert(form.Username, form.Password); err != nil {
+		// the name could have been taken between the check and the insert
+		if errors.Is(err, models.ErrDuplicateUsername) {
+			form.AddFieldError("username", "Username is already in use")
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, r, http.StatusUnprocessableEntity, "signup.tmpl.html", data)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Your signup was successful. Please log in.")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
 type userLoginForm struct {
-	Key                 string `form:"key"`
+	Username            string `form:"username"`
+	Password            string `form:"password"`
 	validator.Validator `form:"-"`
 }
 
@@ -49,7 +406,8 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	form.CheckField(validator.NotBlank(form.Key), "key", "This field cannot be blank")
+	form.CheckField(validator.NotBlank(form.Username), "username", "This field cannot be blank")
+	form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank")
 
 	if !form.Valid() {
 		data := app.newTemplateData(r)
@@ -58,14 +416,19 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := app.authentication.Authenticate(form.Key)
+	id, err := app.users.Authenticate(form.Username, form.Password)
 	if err != nil {
-		// TODO: this will have to change to the forwarded for once behind a proxy
-		app.logger.Info("failed authentication attempt", "origin", r.RemoteAddr)
-		form.AddNonFieldError("key is incorrect")
-		data := app.newTemplateData(r)
-		data.Form = form
-		app.render(w, r, http.StatusUnprocessableEntity, "login.tmpl.html", data)
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			// TODO: this will have to change to the forwarded for once behind a proxy
+			app.logger.Info("failed authentication attempt", "origin", r.RemoteAddr)
+			form.AddNonFieldError("Username or password is incorrect")
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, r, http.StatusUnprocessableEntity, "login.tmpl.html", data)
+		} else {
+			app.serverError(w, r, err)
+		}
 		return
 	}
 
@@ -89,3 +452,60 @@ func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
 	app.sessionManager.Put(r.Context(), "flash", "You've been logged out")
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
+
+type accountPasswordUpdateForm struct {
+	CurrentPassword         string `form:"currentPassword"`
+	NewPassword             string `form:"newPassword"`
+	NewPasswordConfirmation string `form:"newPasswordConfirmation"`
+	validator.Validator     `form:"-"`
+}
+
+func (app *application) accountPasswordUpdate(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = accountPasswordUpdateForm{}
+	app.render(w, r, http.StatusOK, "password.tmpl.html", data)
+}
+
+func (app *application) accountPasswordUpdatePost(w http.ResponseWriter, r *http.Request) {
+	var form accountPasswordUpdateForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.CurrentPassword), "currentPassword", "This field cannot be blank")
+	checkPasswordField(&form.Validator, form.NewPassword, "newPassword")
+	form.CheckField(form.NewPassword == form.NewPasswordConfirmation, "newPasswordConfirmation", "Passwords do not match")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "password.tmpl.html", data)
+		return
+	}
+
+	id := app.sessionManager.GetString(r.Context(), authenticatedSessionKeyName)
+
+	if err := app.users.PasswordUpdate(id, form.CurrentPassword, form.NewPassword); err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			form.AddFieldError("currentPassword", "Current password is incorrect")
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, r, http.StatusUnprocessableEntity, "password.tmpl.html", data)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Your password has been updated!")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// checkPasswordField holds the password rules shared by signup and password changes
+func checkPasswordField(v *validator.Validator, password, key string) {
+	v.CheckField(validator.NotBlank(password), key, "This field cannot be blank")
+	v.CheckField(validator.MinChars(password, 8), key, "This field must be at least 8 characters long")
+	v.CheckField(validator.MaxBytes(password, models.MaxPasswordBytes), key, "This field is too long")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"flag"
 	"fmt"
 	"html/template"
@@ -14,8 +15,10 @@ import (
 	"github.com/michaelgov-ctrl/bad-chess/game"
 	"github.com/michaelgov-ctrl/bad-chess/internal/models"
 	"github.com/michaelgov-ctrl/bad-chess/internal/slogloki"
+	"github.com/michaelgov-ctrl/bad-chess/tournament"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
+	_ "modernc.org/sqlite"
 )
 
 type config struct {
@@ -24,16 +27,32 @@ type config struct {
 	logLevel string
 	cert     string
 	key      string
-	cors     struct {
+	db       struct {
+		dsn string
+	}
+	engine struct {
+		path       string
+		configFile string
+		options    map[string]string
+		poolSize   int
+	}
+	analysis struct {
+		depth   int
+		workers int
+	}
+	cors struct {
 		trustedOrigins []string
 	}
 }
 
 type application struct {
 	config             config
-	authentication     *models.LazyAuth
+	users              models.UserStore
+	matches            models.MatchStore
+	analyses           models.AnalysisStore
 	engineManager      *game.EngineManager
 	matchmakingManager *game.MatchmakingManager
+	tournamentManager  *tournament.Manager
 	sessionManager     *scs.SessionManager
 	templateCache      map[string]*template.Template
 	formDecoder        *form.Decoder
@@ -51,6 +70,27 @@ func main() {
 	flag.StringVar(&cfg.cert, "cert", "", "File containing cert for tls")
 	flag.StringVar(&cfg.key, "key", "", "File containing key for tls")
 
+	flag.StringVar(&cfg.db.dsn, "db-dsn", "bad-chess.db", "SQLite data source name")
+
+	flag.StringVar(&cfg.engine.path, "engine-path", "", "Engine executable, overrides the engine config (default stockfish)")
+	flag.StringVar(&cfg.engine.configFile, "engine-config", "", "JSON file with the engine path, UCI options and ELO levels")
+	flag.Func("engine-option", "UCI option to set on the engine as Name=Value, may be repeated", func(val string) error {
+		name, value, ok := strings.Cut(val, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("engine option must be Name=Value: %q", val)
+		}
+
+		if cfg.engine.options == nil {
+			cfg.engine.options = make(map[string]string)
+		}
+		cfg.engine.options[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		return nil
+	})
+	flag.IntVar(&cfg.engine.poolSize, "engine-pool-size", game.DefaultEnginePoolSize, "Number of engine processes shared by engine matches")
+
+	flag.IntVar(&cfg.analysis.depth, "analysis-depth", game.DefaultAnalysisDepth, "Search depth used when analyzing finished matches")
+	flag.IntVar(&cfg.analysis.workers, "analysis-workers", game.DefaultAnalysisWorkers, "Number of engines analyzing finished matches, separate from the engine pool")
+
 	flag.Func("cors-trusted-origins", "Trusted CORS origins (space seperated)", func(val string) error {
 		cfg.cors.trustedOrigins = strings.Fields(val)
 		return nil
@@ -63,6 +103,24 @@ func main() {
 		logger = slogloki.NewLokiLogger("bad-chess", fmt.Sprintf("http://localhost:%d/loki/api/v1/push", cfg.lokiPort), logLevel(cfg.logLevel))
 	}
 
+	db, err := openDB(cfg.db.dsn)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	engineConfig, err := loadEngineConfig(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	if err := engineConfig.CheckEngine(); err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	templateCache, err := newTemplateCache()
 	if err != nil {
 		logger.Error(err.Error())
@@ -79,11 +137,21 @@ func main() {
 		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 	)
 
+	matches := &models.MatchModel{DB: db}
+	ratings := &models.RatingModel{DB: db}
+	analyses := &models.AnalysisModel{DB: db}
+	analyzer := game.NewAnalyzer(engineConfig.Factory(), analyses, cfg.analysis.depth, cfg.analysis.workers, logger, registry)
+	users := &models.UserModel{DB: db}
+	matchmakingManager := game.NewMatchmakingManager(context.Background(), game.WithLogger(logger), game.WithMetricsRegistry(registry), game.WithMatchStore(matches), game.WithRatingStore(ratings), game.WithUserStore(users), game.WithEngineConfig(engineConfig), game.WithAnalyzer(analyzer))
+
 	app := &application{
 		config:             cfg,
-		authentication:     models.NewLazyAuth(),
-		engineManager:      game.NewEngineManager(context.Background(), game.WithLogger(logger), game.WithMetricsRegistry(registry)),
-		matchmakingManager: game.NewMatchmakingManager(context.Background(), game.WithLogger(logger), game.WithMetricsRegistry(registry)),
+		users:              users,
+		matches:            matches,
+		analyses:           analyses,
+		engineManager:      game.NewEngineManager(context.Background(), game.WithLogger(logger), game.WithMetricsRegistry(registry), game.WithMatchStore(matches), game.WithEngineConfig(engineConfig), game.WithEnginePoolSize(cfg.engine.poolSize), game.WithAnalyzer(analyzer)),
+		matchmakingManager: matchmakingManager,
+		tournamentManager:  tournament.NewManager(matchmakingManager, logger),
 		sessionManager:     sessionManager,
 		templateCache:      templateCache,
 		formDecoder:        form.NewDecoder(),
@@ -96,3 +164,44 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// loadEngineConfig starts from the config file if there is one and lets the command line flags override it
+func loadEngineConfig(cfg config) (game.EngineConfig, error) {
+	engineConfig := game.DefaultEngineConfig()
+	if cfg.engine.configFile != "" {
+		var err error
+		engineConfig, err = game.LoadEngineConfig(cfg.engine.configFile)
+		if err != nil {
+			return game.EngineConfig{}, err
+		}
+	}
+
+	if cfg.engine.path != "" {
+		engineConfig.Path = cfg.engine.path
+	}
+
+	for name, value := range cfg.engine.options {
+		engineConfig.Options[name] = value
+	}
+
+	return engineConfig, engineConfig.Validate()
+}
+
+func openDB(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := models.Migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
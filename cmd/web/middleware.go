@@ -8,6 +8,7 @@ import (
 	"strconv"
 
 	"github.com/justinas/nosurf"
+	"github.com/michaelgov-ctrl/bad-chess/game"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -60,8 +61,15 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		if app.authentication.Exists(id) {
+		exists, err := app.users.Exists(id)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if exists {
 			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+			ctx = game.ContextWithUserId(ctx, id)
 			r = r.WithContext(ctx)
 		}
 
@@ -3,39 +3,60 @@ package main
 import (
 	"net/http"
 
-	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
 	"github.com/michaelgov-ctrl/bad-chess/ui"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// httprouter can't hold a wildcard next to a static route, registering /matches/:id/pgn after /matches/ws
+// panics with "wildcard route ':id' conflicts with existing children". the standard library mux has had
+// method and wildcard patterns since go 1.22 and picks the most specific one, so routing is done with it
 func (app *application) routes() http.Handler {
-	router := httprouter.New()
+	router := http.NewServeMux()
 
-	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	router.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		app.notFound(w)
-	})
+	}))
 
-	router.Handler(http.MethodGet, "/static/*filepath", http.FileServerFS(ui.Files))
+	router.Handle("GET /static/", http.FileServerFS(ui.Files))
 
 	dynamic := alice.New(app.sessionManager.LoadAndSave, noSurf, app.authenticate)
 	protected := dynamic.Append(app.requireAuthentication)
 
-	router.Handler(http.MethodGet, "/", dynamic.ThenFunc(app.home))
+	router.Handle("GET /{$}", dynamic.ThenFunc(app.home))
 
-	router.Handler(http.MethodGet, "/engineselection", protected.ThenFunc(app.engineSelectionHandler))
-	router.Handler(http.MethodGet, "/engines", protected.ThenFunc(app.enginesHandler))
-	router.Handler(http.MethodGet, "/engines/ws", protected.ThenFunc(app.engineManager.ServeWS))
+	router.Handle("GET /engineselection", protected.ThenFunc(app.engineSelectionHandler))
+	router.Handle("GET /engines", protected.ThenFunc(app.enginesHandler))
+	router.Handle("GET /engines/ws", protected.ThenFunc(app.engineManager.ServeWS))
+	router.Handle("GET /engines/{id}/pgn", protected.ThenFunc(app.enginePGNHandler))
 
-	router.Handler(http.MethodGet, "/matchmaking", protected.ThenFunc(app.matchMakingHandler))
-	router.Handler(http.MethodGet, "/matches", protected.ThenFunc(app.matchesHandler))
-	router.Handler(http.MethodGet, "/matches/ws", protected.ThenFunc(app.matchmakingManager.ServeWS))
+	router.Handle("GET /matchmaking", protected.ThenFunc(app.matchMakingHandler))
+	router.Handle("GET /lobby", protected.ThenFunc(app.lobbyHandler))
+	router.Handle("GET /lobby/ws", protected.ThenFunc(app.matchmakingManager.ServeLobbyWS))
+	router.Handle("GET /matches", protected.ThenFunc(app.matchesHandler))
+	router.Handle("GET /matches/ws", protected.ThenFunc(app.matchmakingManager.ServeWS))
+	router.Handle("GET /matches/spectate", protected.ThenFunc(app.spectateHandler))
+	router.Handle("GET /matches/{id}/pgn", protected.ThenFunc(app.matchPGNHandler))
+	router.Handle("GET /matches/{id}/analysis", protected.ThenFunc(app.matchAnalysisHandler))
 
-	router.Handler(http.MethodGet, "/user/login", dynamic.ThenFunc(app.userLogin))
-	router.Handler(http.MethodPost, "/user/login", dynamic.ThenFunc(app.userLoginPost))
-	router.Handler(http.MethodPost, "/user/logout", protected.ThenFunc(app.userLogoutPost))
+	router.Handle("GET /tournaments", protected.ThenFunc(app.tournamentsHandler))
+	router.Handle("POST /tournaments", protected.ThenFunc(app.tournamentCreatePost))
+	router.Handle("GET /tournaments/{id}", protected.ThenFunc(app.tournamentHandler))
+	router.Handle("GET /tournaments/{id}/standings", protected.ThenFunc(app.tournamentStandingsHandler))
+	router.Handle("GET /tournaments/{id}/ws", protected.ThenFunc(app.tournamentManager.ServeWS))
+	router.Handle("POST /tournaments/{id}/join", protected.ThenFunc(app.tournamentJoinPost))
+	router.Handle("POST /tournaments/{id}/withdraw", protected.ThenFunc(app.tournamentWithdrawPost))
+	router.Handle("POST /tournaments/{id}/start", protected.ThenFunc(app.tournamentStartPost))
 
-	router.Handler(http.MethodGet, "/metrics", promhttp.HandlerFor(app.metricsRegistry, promhttp.HandlerOpts{}))
+	router.Handle("GET /user/signup", dynamic.ThenFunc(app.userSignup))
+	router.Handle("POST /user/signup", dynamic.ThenFunc(app.userSignupPost))
+	router.Handle("GET /user/login", dynamic.ThenFunc(app.userLogin))
+	router.Handle("POST /user/login", dynamic.ThenFunc(app.userLoginPost))
+	router.Handle("POST /user/logout", protected.ThenFunc(app.userLogoutPost))
+	router.Handle("GET /account/password/update", protected.ThenFunc(app.accountPasswordUpdate))
+	router.Handle("POST /account/password/update", protected.ThenFunc(app.accountPasswordUpdatePost))
+
+	router.Handle("GET /metrics", promhttp.HandlerFor(app.metricsRegistry, promhttp.HandlerOpts{}))
 
 	standard := alice.New(app.metrics, app.recoverPanic, app.enableCORS, app.logRequest, secureHeaders)
 
@@ -9,39 +9,43 @@ import (
 
 	"github.com/justinas/nosurf"
 	"github.com/michaelgov-ctrl/bad-chess/game"
+	"github.com/michaelgov-ctrl/bad-chess/tournament"
 	"github.com/michaelgov-ctrl/bad-chess/ui"
 )
 
 type templateData struct {
 	IsAuthenticated bool
+	UserId          string
 	CSRFToken       string
 	Flash           string
 	Form            any
 	TimeControls    []game.TimeControl
 	EngineELOs      []game.ELO
+	Variants        []game.Variant
+	EngineVariants  []game.Variant
+	Tournaments     []tournament.Info
+	Tournament      tournament.Snapshot
 }
 
 func (app *application) newTemplateData(r *http.Request) templateData {
 	var td = templateData{
 		IsAuthenticated: app.isAuthenticated(r),
+		UserId:          game.UserIdFromContext(r.Context()),
 		CSRFToken:       nosurf.Token(r),
 		Flash:           app.sessionManager.PopString(r.Context(), "flash"),
 		TimeControls:    []game.TimeControl{},
-		EngineELOs:      []game.ELO{},
+		EngineELOs:      app.engineManager.SupportedELOs(),
+		Variants:        game.SupportedVariants,
+		EngineVariants:  app.engineManager.SupportedVariants(),
 	}
 
 	for k := range game.SupportedTimeControls {
 		td.TimeControls = append(td.TimeControls, k)
 	}
 	sort.Slice(td.TimeControls, func(i, j int) bool {
-		return td.TimeControls[i] < td.TimeControls[j]
+		return td.TimeControls[i].Less(td.TimeControls[j])
 	})
 
-	for k := range game.SupportedEngineELOs {
-		td.EngineELOs = append(td.EngineELOs, k)
-	}
-	sort.Ints(td.EngineELOs)
-
 	return td
 }
 
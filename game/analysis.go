@@ -0,0 +1,374 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/michaelgov-ctrl/bad-chess/internal/models"
+	"github.com/notnil/chess"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	DefaultAnalysisDepth     = 16
+	DefaultAnalysisWorkers   = 1
+	DefaultAnalysisQueueSize = 100
+
+	// evaluations are capped so a single missed mate doesn't swamp the average centipawn loss
+	analysisEvalCap = 1000
+)
+
+var (
+	// a move is classified by how many centipawns of evaluation it gave away
+	InaccuracyThreshold = 50
+	MistakeThreshold    = 100
+	BlunderThreshold    = 300
+
+	ErrAnalysisQueueFull = errors.New("analysis queue is full")
+)
+
+const (
+	AnalysisQueued  = "queued"
+	AnalysisRunning = "running"
+	AnalysisDone    = "done"
+	AnalysisFailed  = "failed"
+)
+
+type MoveClassification string
+
+const (
+	Inaccuracy MoveClassification = "inaccuracy"
+	Mistake    MoveClassification = "mistake"
+	Blunder    MoveClassification = "blunder"
+)
+
+func classifyMove(cpLoss int) MoveClassification {
+	switch {
+	case cpLoss >= BlunderThreshold:
+		return Blunder
+	case cpLoss >= MistakeThreshold:
+		return Mistake
+	case cpLoss >= InaccuracyThreshold:
+		return Inaccuracy
+	default:
+		return ""
+	}
+}
+
+// PlyEvaluation scores are from lights point of view after the move was played, BestMove is what the engine would have played instead
+type PlyEvaluation struct {
+	Ply            int                `json:"ply"`
+	Player         string             `json:"player"`
+	Move           string             `json:"move"`
+	BestMove       string             `json:"best_move"`
+	CP             int                `json:"cp"`
+	Mate           int                `json:"mate"`
+	CPLoss         int                `json:"cp_loss"`
+	Classification MoveClassification `json:"classification,omitempty"`
+}
+
+type Analysis struct {
+	MatchID   MatchId         `json:"match_id"`
+	Status    string          `json:"status"`
+	Depth     int             `json:"depth,omitempty"`
+	Plies     []PlyEvaluation `json:"plies,omitempty"`
+	LightACPL float64         `json:"light_acpl"`
+	DarkACPL  float64         `json:"dark_acpl"`
+	Error     string          `json:"error,omitempty"`
+}
+
+type analysisJob struct {
+	id   MatchId
+	game *Game
+}
+
+// newAnalysisJob copies the game so the analysis can't be changed by anything still holding the match
+func newAnalysisJob(id MatchId, game *Game) analysisJob {
+	return analysisJob{id: id, game: game.Clone()}
+}
+
+// Analyzer runs finished games through the engine on a fixed number of workers, each with its own engine,
+// so analysis never takes an engine away from a live match
+type Analyzer struct {
+	factory EngineFactory
+	store   models.AnalysisStore
+	depth   int
+	jobs    chan analysisJob
+	logger  *slog.Logger
+
+	metrics *AnalyzerMetrics
+}
+
+func NewAnalyzer(factory EngineFactory, store models.AnalysisStore, depth, workers int, logger *slog.Logger, registry *prometheus.Registry) *Analyzer {
+	a := &Analyzer{
+		factory: factory,
+		store:   store,
+		depth:   max(depth, 1),
+		jobs:    make(chan analysisJob, DefaultAnalysisQueueSize),
+		logger:  logger,
+		metrics: &AnalyzerMetrics{},
+	}
+
+	a.registerAnalyzerMetrics(registry)
+
+	for range max(workers, 1) {
+		go a.work()
+	}
+
+	return a
+}
+
+// Enqueue queues a game for analysis without blocking, games without any moves are skipped
+func (a *Analyzer) Enqueue(id MatchId, game *Game) error {
+	if len(game.Moves()) == 0 {
+		return nil
+	}
+
+	a.save(Analysis{MatchID: id, Status: AnalysisQueued})
+
+	select {
+	case a.jobs <- newAnalysisJob(id, game):
+		a.metrics.queued.Inc()
+		return nil
+	default:
+		a.fail(id, ErrAnalysisQueueFull)
+		return ErrAnalysisQueueFull
+	}
+}
+
+func (a *Analyzer) work() {
+	var engine EngineBackend
+	for job := range a.jobs {
+		a.metrics.queued.Dec()
+
+		if engine == nil {
+			var err error
+			if engine, err = a.factory(); err != nil {
+				a.fail(job.id, err)
+				continue
+			}
+		}
+
+		a.save(Analysis{MatchID: job.id, Status: AnalysisRunning, Depth: a.depth})
+
+		start := time.Now()
+		analysis, err := a.analyze(engine, job)
+		if err != nil {
+			// the engine may be in a bad state so the next job gets a fresh one
+			engine.Close()
+			engine = nil
+
+			a.fail(job.id, err)
+			continue
+		}
+
+		a.metrics.duration.Observe(time.Since(start).Seconds())
+		a.metrics.completed.Inc()
+		a.save(analysis)
+	}
+}
+
+func (a *Analyzer) analyze(engine EngineBackend, job analysisJob) (Analysis, error) {
+	if err := engine.SetStrength(0); err != nil {
+		return Analysis{}, err
+	}
+
+	if err := engine.NewGame(); err != nil {
+		return Analysis{}, err
+	}
+
+	positions, moves := job.game.Positions(), job.game.Moves()
+
+	evals := make([]SearchResult, len(positions))
+	for i, position := range positions {
+		result, err := a.evaluate(engine, position)
+		if err != nil {
+			return Analysis{}, err
+		}
+
+		evals[i] = result
+	}
+
+	analysis := Analysis{
+		MatchID: job.id,
+		Status:  AnalysisDone,
+		Depth:   a.depth,
+		Plies:   make([]PlyEvaluation, len(moves)),
+	}
+
+	var lightLoss, darkLoss, lightMoves, darkMoves int
+	for i, move := range moves {
+		before, after := evals[i], evals[i+1]
+
+		cpLoss := centipawns(before) - centipawns(after)
+		mover := Light
+		if positions[i].Turn() == chess.Black {
+			cpLoss, mover = -cpLoss, Dark
+		}
+		cpLoss = max(cpLoss, 0)
+
+		ply := PlyEvaluation{
+			Ply:            i + 1,
+			Player:         mover.String(),
+			Move:           positions[i].SAN(move),
+			CP:             after.CP,
+			Mate:           after.Mate,
+			CPLoss:         cpLoss,
+			Classification: classifyMove(cpLoss),
+		}
+
+		if before.BestMove != nil {
+			ply.BestMove = positions[i].SAN(before.BestMove)
+		}
+
+		analysis.Plies[i] = ply
+
+		if mover == Light {
+			lightLoss, lightMoves = lightLoss+cpLoss, lightMoves+1
+		} else {
+			darkLoss, darkMoves = darkLoss+cpLoss, darkMoves+1
+		}
+	}
+
+	analysis.LightACPL = averageLoss(lightLoss, lightMoves)
+	analysis.DarkACPL = averageLoss(darkLoss, darkMoves)
+
+	return analysis, nil
+}
+
+// evaluate scores a position from lights point of view, positions with no moves left or won by the variants rules are scored without the engine
+func (a *Analyzer) evaluate(engine EngineBackend, position *Position) (SearchResult, error) {
+	var result SearchResult
+
+	outcome, _ := position.Variant().Outcome(position)
+	status := position.Status()
+
+	switch {
+	case outcome == winFor(position.Turn()):
+		result.CP = analysisEvalCap
+	case outcome == winFor(position.Turn().Other()), status == chess.Checkmate:
+		result.CP = -analysisEvalCap
+	case outcome == chess.Draw, status == chess.Stalemate:
+	default:
+		var err error
+		result, err = engine.BestMove(position, SearchLimits{Depth: a.depth})
+		if err != nil {
+			return SearchResult{}, err
+		}
+	}
+
+	// the engine scores from the side to moves point of view
+	if position.Turn() == chess.Black {
+		result.CP, result.Mate = -result.CP, -result.Mate
+	}
+
+	return result, nil
+}
+
+func centipawns(result SearchResult) int {
+	switch {
+	case result.Mate > 0:
+		return analysisEvalCap
+	case result.Mate < 0:
+		return -analysisEvalCap
+	default:
+		return min(max(result.CP, -analysisEvalCap), analysisEvalCap)
+	}
+}
+
+func averageLoss(total, moves int) float64 {
+	if moves == 0 {
+		return 0
+	}
+
+	return float64(total) / float64(moves)
+}
+
+func (a *Analyzer) fail(id MatchId, err error) {
+	a.logger.Error("failed to analyze match", "MatchId", id, "error", err)
+	a.metrics.failed.Inc()
+	a.save(Analysis{MatchID: id, Status: AnalysisFailed, Error: err.Error()})
+}
+
+func (a *Analyzer) save(analysis Analysis) {
+	if a.store == nil {
+		return
+	}
+
+	b, err := json.Marshal(analysis)
+	if err != nil {
+		a.logger.Error("failed to encode analysis", "MatchId", analysis.MatchID, "error", err)
+		return
+	}
+
+	record := models.AnalysisRecord{
+		MatchID:   string(analysis.MatchID),
+		Status:    analysis.Status,
+		Analysis:  string(b),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := a.store.Upsert(record); err != nil {
+		a.logger.Error("failed to store analysis", "MatchId", analysis.MatchID, "error", err)
+	}
+}
+
+// analyzable skips games the analysis engine can't play, crazyhouse drops can't be read back from it
+func (o *ManagerOptions) analyzable(variant Variant) bool {
+	return o.analyzer != nil && variant != Crazyhouse && o.engineConfig.SupportsVariant(variant)
+}
+
+// queueAnalyses is called outside of any manager locks like storeMatchRecords
+func (o *ManagerOptions) queueAnalyses(jobs []analysisJob) {
+	if o.analyzer == nil {
+		return
+	}
+
+	for _, job := range jobs {
+		if err := o.analyzer.Enqueue(job.id, job.game); err != nil {
+			o.logger.Error("failed to queue analysis", "MatchId", job.id, "error", err)
+		}
+	}
+}
+
+type AnalyzerMetrics struct {
+	queued    prometheus.Gauge
+	completed prometheus.Counter
+	failed    prometheus.Counter
+	duration  prometheus.Histogram
+}
+
+func (a *Analyzer) registerAnalyzerMetrics(registry *prometheus.Registry) {
+	a.metrics.queued = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "analysis_jobs_queued",
+			Help: "Number of finished matches waiting to be analyzed",
+		},
+	)
+
+	a.metrics.completed = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "analysis_jobs_completed_total",
+			Help: "Number of matches analyzed",
+		},
+	)
+
+	a.metrics.failed = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "analysis_jobs_failed_total",
+			Help: "Number of matches that could not be analyzed",
+		},
+	)
+
+	a.metrics.duration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "analysis_job_seconds",
+			Help:    "Time taken to analyze a match",
+			Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600},
+		},
+	)
+
+	registry.MustRegister(a.metrics.queued, a.metrics.completed, a.metrics.failed, a.metrics.duration)
+}
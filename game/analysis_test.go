@@ -0,0 +1,212 @@
+package game
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// scoredEngine answers each search with the next of its scores, the move is whatever the fake engine picks
+type scoredEngine struct {
+	*FakeEngine
+	scores   []SearchResult
+	searches int
+}
+
+func (e *scoredEngine) BestMove(position *Position, limits SearchLimits) (SearchResult, error) {
+	if e.searches >= len(e.scores) {
+		return SearchResult{}, errors.New("no score left for the position")
+	}
+
+	result, err := e.FakeEngine.BestMove(position, limits)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	score := e.scores[e.searches]
+	e.searches++
+	result.CP, result.Mate = score.CP, score.Mate
+
+	return result, nil
+}
+
+func newTestAnalyzer() *Analyzer {
+	return &Analyzer{depth: 1, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+func TestAnalyze(t *testing.T) {
+	game := playMoves(t, Standard, "", "e4", "e5", "Qh5", "Nc6")
+
+	// scores are from the side to moves point of view like a real engine's
+	engine := &scoredEngine{FakeEngine: NewFakeEngine(), scores: []SearchResult{
+		{CP: 20},
+		{CP: -20},
+		{CP: 150},
+		{Mate: 2},
+		{CP: -940},
+	}}
+
+	analysis, err := newTestAnalyzer().analyze(engine, newAnalysisJob("match", game))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if engine.searches != 5 {
+		t.Fatalf("engine searched %d positions, want 5", engine.searches)
+	}
+
+	want := []struct {
+		player         string
+		move           string
+		cp, mate       int
+		cpLoss         int
+		classification MoveClassification
+	}{
+		{"light", "e4", 20, 0, 0, ""},
+		// dark gives away 130 from dark's own point of view even though light's score goes up
+		{"dark", "e5", 150, 0, 130, Mistake},
+		// a mate against counts as the cap rather than an unbounded score
+		{"light", "Qh5", 0, -2, 1150, Blunder},
+		{"dark", "Nc6", -940, 0, 60, Inaccuracy},
+	}
+
+	if len(analysis.Plies) != len(want) {
+		t.Fatalf("got %d plies, want %d", len(analysis.Plies), len(want))
+	}
+
+	for i, w := range want {
+		ply := analysis.Plies[i]
+		if ply.Ply != i+1 || ply.Player != w.player || ply.Move != w.move || ply.CP != w.cp || ply.Mate != w.mate ||
+			ply.CPLoss != w.cpLoss || ply.Classification != w.classification {
+			t.Errorf("ply %d = %+v, want %+v", i+1, ply, w)
+		}
+
+		if ply.BestMove == "" {
+			t.Errorf("ply %d has no best move", i+1)
+		}
+	}
+
+	if analysis.Status != AnalysisDone || analysis.LightACPL != 575 || analysis.DarkACPL != 95 {
+		t.Fatalf("analysis = %s, acpl %v/%v, want done with 575/95", analysis.Status, analysis.LightACPL, analysis.DarkACPL)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	a := newTestAnalyzer()
+
+	// fools mate, white is mated so the engine is never asked
+	mated := playMoves(t, Standard, "", "f3", "e5", "g4", "Qh4").Position()
+	notSearched := &scoredEngine{FakeEngine: NewFakeEngine()}
+
+	result, err := a.evaluate(notSearched, mated)
+	if err != nil || result.CP != -analysisEvalCap {
+		t.Fatalf("evaluate(checkmate) = %+v, %v, want %d", result, err, -analysisEvalCap)
+	}
+
+	stalemate, err := parsePosition("7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", Standard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result, err := a.evaluate(notSearched, stalemate); err != nil || result.CP != 0 {
+		t.Fatalf("evaluate(stalemate) = %+v, %v, want 0", result, err)
+	}
+
+	// the king reaching the centre wins without the engine too, scored for the side that just moved
+	hill := playMoves(t, KingOfTheHill, "4k3/8/8/8/8/4K3/8/8 w - - 0 1", "Kd4").Position()
+	if result, err := a.evaluate(notSearched, hill); err != nil || result.CP != analysisEvalCap {
+		t.Fatalf("evaluate(king of the hill) = %+v, %v, want %d", result, err, analysisEvalCap)
+	}
+
+	// with dark to move the engines score is flipped to lights point of view
+	engine := &scoredEngine{FakeEngine: NewFakeEngine(), scores: []SearchResult{{CP: 30}, {Mate: -3}}}
+	afterE4 := playMoves(t, Standard, "", "e4").Position()
+
+	if result, err := a.evaluate(engine, afterE4); err != nil || result.CP != -30 {
+		t.Fatalf("evaluate(1. e4) = %+v, %v, want cp -30", result, err)
+	}
+
+	if result, err := a.evaluate(engine, afterE4); err != nil || result.Mate != 3 {
+		t.Fatalf("evaluate(1. e4) = %+v, %v, want mate 3", result, err)
+	}
+}
+
+func TestCentipawns(t *testing.T) {
+	tests := []struct {
+		result SearchResult
+		want   int
+	}{
+		{SearchResult{CP: 42}, 42},
+		{SearchResult{CP: 5000}, analysisEvalCap},
+		{SearchResult{CP: -5000}, -analysisEvalCap},
+		{SearchResult{Mate: 3}, analysisEvalCap},
+		{SearchResult{Mate: -1}, -analysisEvalCap},
+	}
+
+	for _, tt := range tests {
+		if got := centipawns(tt.result); got != tt.want {
+			t.Errorf("centipawns(%+v) = %d, want %d", tt.result, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyMove(t *testing.T) {
+	tests := []struct {
+		cpLoss int
+		want   MoveClassification
+	}{
+		{0, ""},
+		{InaccuracyThreshold - 1, ""},
+		{InaccuracyThreshold, Inaccuracy},
+		{MistakeThreshold - 1, Inaccuracy},
+		{MistakeThreshold, Mistake},
+		{BlunderThreshold - 1, Mistake},
+		{BlunderThreshold, Blunder},
+		{2 * analysisEvalCap, Blunder},
+	}
+
+	for _, tt := range tests {
+		if got := classifyMove(tt.cpLoss); got != tt.want {
+			t.Errorf("classifyMove(%d) = %q, want %q", tt.cpLoss, got, tt.want)
+		}
+	}
+}
+
+func TestAnalysisJobClonesTheGame(t *testing.T) {
+	game := playMoves(t, Standard, "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", "e4")
+	job := newAnalysisJob("match", game)
+
+	if err := game.MoveStr("Kd7"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(job.game.Moves()) != 1 || !job.game.CustomStart() || job.game.Outcome() != chess.NoOutcome {
+		t.Fatalf("job game has %d moves, custom %v, outcome %s, want the game as queued", len(job.game.Moves()), job.game.CustomStart(), job.game.Outcome())
+	}
+}
+
+func TestAnalyzable(t *testing.T) {
+	cfg := DefaultEngineConfig()
+	cfg.Variants = append(cfg.Variants, KingOfTheHill.String(), Crazyhouse.String())
+
+	o := ManagerOptions{analyzer: newTestAnalyzer(), engineConfig: cfg}
+	for variant, want := range map[Variant]bool{
+		Standard:      true,
+		Chess960:      true,
+		KingOfTheHill: true,
+		ThreeCheck:    false,
+		// drops can't be read back from the engine even when it says it plays crazyhouse
+		Crazyhouse: false,
+	} {
+		if got := o.analyzable(variant); got != want {
+			t.Errorf("analyzable(%s) = %v, want %v", variant, got, want)
+		}
+	}
+
+	if (&ManagerOptions{engineConfig: cfg}).analyzable(Standard) {
+		t.Error("analyzable without an analyzer = true, want false")
+	}
+}
@@ -0,0 +1,219 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/notnil/chess"
+)
+
+// Game stands in for chess.Game, it plays through our own Position so castling works in every variant
+type Game struct {
+	positions []*Position
+	moves     []*Move
+	outcome   chess.Outcome
+	method    chess.Method
+	// variantMethod is set instead of method when the game ended by the variants own rules
+	variantMethod string
+	// custom is set when the players chose the starting position rather than the variant
+	custom bool
+}
+
+func newGameFromPosition(start *Position) *Game {
+	return &Game{
+		positions: []*Position{start},
+		outcome:   chess.NoOutcome,
+		method:    chess.NoMethod,
+	}
+}
+
+func (g *Game) Variant() Variant {
+	return g.positions[0].Variant()
+}
+
+// CustomStart is false for a chess960 back rank drawn at random, only positions the players set up count
+func (g *Game) CustomStart() bool {
+	return g.custom
+}
+
+func (g *Game) Position() *Position {
+	return g.positions[len(g.positions)-1]
+}
+
+func (g *Game) Positions() []*Position {
+	return append([]*Position(nil), g.positions...)
+}
+
+func (g *Game) Moves() []*Move {
+	return append([]*Move(nil), g.moves...)
+}
+
+func (g *Game) FEN() string {
+	return g.Position().String()
+}
+
+func (g *Game) Outcome() chess.Outcome {
+	return g.outcome
+}
+
+func (g *Game) Method() chess.Method {
+	return g.method
+}
+
+// OutcomeMethod is how the game ended as reported in MatchOutcome, variant wins included
+func (g *Game) OutcomeMethod() string {
+	if g.variantMethod != "" {
+		return g.variantMethod
+	}
+
+	return outcomeMethod(g.method)
+}
+
+// Move plays a move from the current positions LegalMoves
+func (g *Game) Move(m *Move) error {
+	if g.outcome != chess.NoOutcome {
+		return errors.New("game is over")
+	}
+
+	g.moves = append(g.moves, m)
+	g.positions = append(g.positions, g.Position().Update(m))
+	g.updateOutcome()
+
+	return nil
+}
+
+// MoveStr plays a move in SAN or UCI
+func (g *Game) MoveStr(s string) error {
+	if g.outcome != chess.NoOutcome {
+		return errors.New("game is over")
+	}
+
+	m, err := g.Position().ParseMove(s)
+	if err != nil {
+		return err
+	}
+
+	return g.Move(m)
+}
+
+func (g *Game) Resign(color chess.Color) {
+	if g.outcome != chess.NoOutcome || color == chess.NoColor {
+		return
+	}
+
+	g.outcome, g.method = chess.WhiteWon, chess.Resignation
+	if color == chess.White {
+		g.outcome = chess.BlackWon
+	}
+}
+
+func (g *Game) Draw(method chess.Method) error {
+	switch method {
+	case chess.DrawOffer:
+	case chess.ThreefoldRepetition:
+		if g.repetitions() < 3 {
+			return errors.New("draw by threefold repetition needs the position to have come up three times")
+		}
+	case chess.FiftyMoveRule:
+		if g.Position().HalfMoveClock() < 100 {
+			return errors.New("draw by the fifty move rule needs fifty moves without a capture or pawn move")
+		}
+	default:
+		return fmt.Errorf("unsupported draw method %s", method)
+	}
+
+	g.outcome, g.method = chess.Draw, method
+
+	return nil
+}
+
+func (g *Game) Clone() *Game {
+	return &Game{
+		positions:     g.Positions(),
+		moves:         g.Moves(),
+		outcome:       g.outcome,
+		method:        g.method,
+		variantMethod: g.variantMethod,
+		custom:        g.custom,
+	}
+}
+
+// updateOutcome ends the game the same ways chess.Game does on its own, after checking the variants own win conditions
+func (g *Game) updateOutcome() {
+	position := g.Position()
+
+	if outcome, method := position.Variant().Outcome(position); outcome != chess.NoOutcome {
+		g.outcome, g.variantMethod = outcome, method
+		return
+	}
+
+	switch position.Status() {
+	case chess.Checkmate:
+		g.outcome, g.method = chess.WhiteWon, chess.Checkmate
+		if position.Turn() == chess.White {
+			g.outcome = chess.BlackWon
+		}
+		return
+	case chess.Stalemate:
+		g.outcome, g.method = chess.Draw, chess.Stalemate
+		return
+	}
+
+	switch {
+	case g.repetitions() >= 5:
+		g.outcome, g.method = chess.Draw, chess.FivefoldRepetition
+	case position.HalfMoveClock() >= 150:
+		g.outcome, g.method = chess.Draw, chess.SeventyFiveMoveRule
+	case !sufficientMaterial(position.Board()) && materialCanRunOut(position.Variant()):
+		g.outcome, g.method = chess.Draw, chess.InsufficientMaterial
+	}
+}
+
+func (g *Game) repetitions() int {
+	key := g.Position().key()
+
+	var count int
+	for _, position := range g.positions {
+		if position.key() == key {
+			count++
+		}
+	}
+
+	return count
+}
+
+// materialCanRunOut is false for variants a lone king can still win by walking somewhere, and for crazyhouse
+// where captured pieces come back
+func materialCanRunOut(variant Variant) bool {
+	switch variant {
+	case KingOfTheHill, RacingKings, Crazyhouse:
+		return false
+	default:
+		return true
+	}
+}
+
+// sufficientMaterial is false for king against king with at most a minor piece, or bishops all on one color
+func sufficientMaterial(board *chess.Board) bool {
+	var knights int
+	bishopColors := make(map[int]bool)
+	for sq, piece := range board.SquareMap() {
+		switch piece.Type() {
+		case chess.Queen, chess.Rook, chess.Pawn:
+			return true
+		case chess.Knight:
+			knights++
+		case chess.Bishop:
+			bishopColors[(int(sq.File())+int(sq.Rank()))%2] = true
+		}
+	}
+
+	switch {
+	case knights == 0:
+		return len(bishopColors) > 1
+	case knights == 1:
+		return len(bishopColors) > 0
+	default:
+		return true
+	}
+}
@@ -4,21 +4,31 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/michaelgov-ctrl/bad-chess/internal/rating"
 )
 
 var (
 	pongWait     = 10 * time.Second
 	pingInterval = (pongWait * 9) / 10 // 90% of pongWait
+
+	// a small buffer lets non-blocking sends to spectators survive the writer being mid-write
+	egressBufferSize = 8
 )
 
 type Client struct {
 	connection *websocket.Conn
 	manager    Manager
+	userId     string
+	name       string
+	ratings    map[rating.Category]rating.Rating
 
+	// currentMatch is guarded by matchMu, accepting a seek seats the seeker from the accepters goroutine
 	currentMatch ClientMatchInfo
+	matchMu      sync.Mutex
 
 	// egress is used to avoid concurrent writes on the websocket connection for events
 	egress chan Event
@@ -32,13 +42,18 @@ type ClientMatchInfo struct {
 	TimeControl TimeControl `json:"time_control"`
 	EngineELO   ELO         `json:"engine_elo"`
 	Pieces      PieceColor  `json:"pieces"`
+	Spectating  bool        `json:"spectating"`
+	Private     bool        `json:"private"`
+	// BerserkAllowed is set for arena tournament matches
+	BerserkAllowed bool `json:"berserk_allowed,omitempty"`
 }
 
-func NewClient(conn *websocket.Conn, manager Manager) *Client {
+func NewClient(conn *websocket.Conn, manager Manager, userId string) *Client {
 	return &Client{
 		connection: conn,
 		manager:    manager,
-		egress:     make(chan Event),
+		userId:     userId,
+		egress:     make(chan Event, egressBufferSize),
 	}
 }
 
@@ -52,9 +67,48 @@ func NewClientMatchInfo(id MatchId, t MatchType, timeControl TimeControl, engine
 	}
 }
 
+func (c *Client) matchInfo() ClientMatchInfo {
+	c.matchMu.Lock()
+	defer c.matchMu.Unlock()
+
+	return c.currentMatch
+}
+
+func (c *Client) setMatchInfo(info ClientMatchInfo) {
+	c.matchMu.Lock()
+	defer c.matchMu.Unlock()
+
+	c.currentMatch = info
+}
+
+func (c *Client) UserId() string {
+	if c == nil {
+		return ""
+	}
+
+	return c.userId
+}
+
+// LogValue keeps handler logs from copying the client, and its match lock, field by field
+func (c *Client) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("user_id", c.UserId()),
+		slog.Any("match", c.matchInfo()),
+	)
+}
+
+// displayName is the clients username, anonymous clients have none
+func (c *Client) displayName() string {
+	if c == nil || c.name == "" {
+		return "anonymous"
+	}
+
+	return c.name
+}
+
 func (c *Client) readEvents(logger *slog.Logger) {
 	defer func() {
-		c.manager.removeClient(c)
+		c.manager.disconnectClient(c)
 	}()
 
 	if err := c.connection.SetReadDeadline(time.Now().Add(pongWait)); err != nil {
@@ -95,7 +149,7 @@ func (c *Client) readEvents(logger *slog.Logger) {
 
 func (c *Client) writeEvents(logger *slog.Logger) {
 	defer func() {
-		c.manager.removeClient(c)
+		c.manager.disconnectClient(c)
 	}()
 
 	ticker := time.NewTicker(pingInterval)
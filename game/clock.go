@@ -15,21 +15,36 @@ const (
 
 type Clock struct {
 	sync.Mutex
-	Done     <-chan time.Time
-	lifeTime time.Duration
-	started  time.Time
-	elapsed  time.Duration
-	state    ClockState
+	Done      <-chan time.Time
+	lifeTime  time.Duration
+	increment time.Duration
+	mode      DelayMode
+	started   time.Time
+	elapsed   time.Duration
+	// time spent on the current move before any delay is taken off
+	moveElapsed time.Duration
+	state       ClockState
 }
 
 func NewClock(timeControl TimeControl) *Clock {
+	return newClock(timeControl, running)
+}
+
+// NewPausedClock is for the player waiting on the first move so they aren't credited for a move they haven't made
+func NewPausedClock(timeControl TimeControl) *Clock {
+	return newClock(timeControl, paused)
+}
+
+func newClock(timeControl TimeControl, state ClockState) *Clock {
 	doneChan := make(chan time.Time)
 
 	clock := &Clock{
-		Done:     doneChan,
-		lifeTime: time.Duration(timeControl),
-		started:  time.Now(),
-		state:    running,
+		Done:      doneChan,
+		lifeTime:  timeControl.Base,
+		increment: timeControl.Increment,
+		mode:      timeControl.Mode,
+		started:   time.Now(),
+		state:     state,
 	}
 
 	ticker := time.NewTicker(500 * time.Millisecond)
@@ -37,12 +52,12 @@ func NewClock(timeControl TimeControl) *Clock {
 		for range ticker.C {
 			clock.Lock()
 			if clock.state == running {
-				clock.elapsed += time.Since(clock.started)
-				clock.started = time.Now()
+				clock.update()
 			}
 
 			if clock.lifeTime <= clock.elapsed {
 				clock.state = expired
+				clock.Unlock()
 				doneChan <- time.Now()
 				close(doneChan)
 				return
@@ -54,6 +69,22 @@ func NewClock(timeControl TimeControl) *Clock {
 	return clock
 }
 
+// update charges the time since the clock was last updated, callers must hold the lock
+func (c *Clock) update() {
+	spent := time.Since(c.started)
+	c.started = time.Now()
+
+	charged := spent
+	if c.mode == SimpleDelay {
+		// the start of every move is free up to the delay
+		charged = max(0, spent-max(0, c.increment-c.moveElapsed))
+	}
+
+	c.moveElapsed += spent
+	c.elapsed += charged
+}
+
+// Pause is called at the end of a move so this is where increments and delays are settled
 func (c *Clock) Pause() {
 	c.Lock()
 	defer c.Unlock()
@@ -62,7 +93,16 @@ func (c *Clock) Pause() {
 		return
 	}
 
-	c.elapsed += time.Since(c.started)
+	c.update()
+
+	switch c.mode {
+	case FischerIncrement:
+		c.elapsed -= c.increment
+	case BronsteinDelay:
+		c.elapsed -= min(c.moveElapsed, c.increment)
+	}
+
+	c.moveElapsed = 0
 	c.state = paused
 }
 
@@ -76,8 +116,40 @@ func (c *Clock) Start() {
 
 	c.state = running
 	c.started = time.Now()
+	c.moveElapsed = 0
+}
+
+// Berserk halves the time left and gives up the increment for the rest of the game
+func (c *Clock) Berserk() {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.state == running {
+		c.update()
+	}
+
+	c.lifeTime -= (c.lifeTime - c.elapsed) / 2
+	c.increment = 0
+}
+
+// SetTimeRemaining puts the clock back to an earlier reading, e.g. when moves are taken back. a clock
+// that has already run out stays out
+func (c *Clock) SetTimeRemaining(remaining time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.state == expired {
+		return
+	}
+
+	c.elapsed = c.lifeTime - remaining
+	c.started = time.Now()
+	c.moveElapsed = 0
 }
 
 func (c *Clock) TimeRemaining() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
 	return c.lifeTime - c.elapsed
 }
@@ -0,0 +1,123 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+// think charges d to the running clock as if that long had passed since it was last updated
+func think(c *Clock, d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.started = c.started.Add(-d)
+}
+
+func expectRemaining(t *testing.T, c *Clock, want time.Duration) {
+	t.Helper()
+
+	// only the few microseconds the test itself takes may have been charged on top
+	if got := c.TimeRemaining(); got > want || got < want-50*time.Millisecond {
+		t.Fatalf("time remaining = %v, want %v", got, want)
+	}
+}
+
+func TestClockModes(t *testing.T) {
+	tests := []struct {
+		name string
+		mode DelayMode
+		// thinks are the lengths of consecutive moves, want is the time left after each
+		thinks []time.Duration
+		want   []time.Duration
+	}{
+		{"no delay", NoDelay, []time.Duration{2 * time.Second, 5 * time.Second}, []time.Duration{58 * time.Second, 53 * time.Second}},
+		{"fischer increment", FischerIncrement, []time.Duration{2 * time.Second, 5 * time.Second}, []time.Duration{61 * time.Second, 59 * time.Second}},
+		{"bronstein delay", BronsteinDelay, []time.Duration{2 * time.Second, 5 * time.Second}, []time.Duration{60 * time.Second, 58 * time.Second}},
+		{"simple delay", SimpleDelay, []time.Duration{2 * time.Second, 5 * time.Second}, []time.Duration{60 * time.Second, 58 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewPausedClock(NewTimeControl(time.Minute, 3*time.Second, tt.mode))
+
+			for i, d := range tt.thinks {
+				c.Start()
+				think(c, d)
+				c.Pause()
+
+				expectRemaining(t, c, tt.want[i])
+			}
+		})
+	}
+}
+
+// TestClockDelayMidMove reads the clock while the move is still being played, which is where
+// bronstein and simple delay differ: bronstein counts down and refunds on the move, simple delay waits
+func TestClockDelayMidMove(t *testing.T) {
+	tests := []struct {
+		name string
+		mode DelayMode
+		want []time.Duration
+	}{
+		{"bronstein delay", BronsteinDelay, []time.Duration{58 * time.Second, 56 * time.Second}},
+		// the ticker updates the clock mid move so the delay has to carry over between updates
+		{"simple delay", SimpleDelay, []time.Duration{60 * time.Second, 59 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClock(NewTimeControl(time.Minute, 3*time.Second, tt.mode))
+
+			for _, want := range tt.want {
+				think(c, 2*time.Second)
+
+				c.Lock()
+				c.update()
+				c.Unlock()
+
+				expectRemaining(t, c, want)
+			}
+		})
+	}
+}
+
+func TestClockPausedDoesntRun(t *testing.T) {
+	c := NewPausedClock(NewTimeControl(time.Minute, 0, NoDelay))
+	think(c, 10*time.Second)
+	c.Pause()
+
+	expectRemaining(t, c, time.Minute)
+
+	// starting again doesn't charge the time spent paused either
+	c.Start()
+	c.Pause()
+
+	expectRemaining(t, c, time.Minute)
+}
+
+func TestClockBerserk(t *testing.T) {
+	c := NewClock(NewTimeControl(time.Minute, 3*time.Second, FischerIncrement))
+	think(c, 10*time.Second)
+	c.Berserk()
+
+	expectRemaining(t, c, 25*time.Second)
+
+	// berserking gives up the increment
+	think(c, 5*time.Second)
+	c.Pause()
+
+	expectRemaining(t, c, 20*time.Second)
+}
+
+func TestClockSetTimeRemaining(t *testing.T) {
+	c := NewClock(NewTimeControl(time.Minute, 0, NoDelay))
+	think(c, 30*time.Second)
+	c.SetTimeRemaining(45 * time.Second)
+
+	expectRemaining(t, c, 45*time.Second)
+
+	// the time spent before the reset isn't charged again
+	c.Pause()
+
+	expectRemaining(t, c, 45*time.Second)
+}
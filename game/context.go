@@ -0,0 +1,21 @@
+package game
+
+import "context"
+
+type contextKey string
+
+const userIdContextKey = contextKey("userId")
+
+// ContextWithUserId is used by the web application to hand the authenticated user to the managers websocket handlers
+func ContextWithUserId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userIdContextKey, id)
+}
+
+func UserIdFromContext(ctx context.Context) string {
+	id, ok := ctx.Value(userIdContextKey).(string)
+	if !ok {
+		return ""
+	}
+
+	return id
+}
@@ -0,0 +1,231 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// pocket counts the pieces a player holds to drop, indexed by piece type
+type pocket [chess.Pawn + 1]int
+
+// dropPieces are the piece types that can be in a pocket, in the order they're written in a fen
+var dropPieces = []chess.PieceType{chess.Queen, chess.Rook, chess.Bishop, chess.Knight, chess.Pawn}
+
+func (p *Position) pocket(color chess.Color) pocket {
+	return p.pockets[color-1]
+}
+
+// dropMoves are the drops the side to move can make, pawns can't go on the back ranks
+// and since a drop can only ever block a check it's only checked when in check
+func (p *Position) dropMoves() []*Move {
+	if p.variant != Crazyhouse {
+		return nil
+	}
+
+	turn, board := p.Turn(), p.pos.Board()
+	inCheck := p.InCheck()
+
+	var moves []*Move
+	for _, pieceType := range dropPieces {
+		if p.pocket(turn)[pieceType] == 0 {
+			continue
+		}
+
+		for sq := chess.A1; sq <= chess.H8; sq++ {
+			if board.Piece(sq) != chess.NoPiece {
+				continue
+			}
+
+			if pieceType == chess.Pawn && (sq.Rank() == chess.Rank1 || sq.Rank() == chess.Rank8) {
+				continue
+			}
+
+			if inCheck && !p.dropBlocksCheck(pieceType, sq) {
+				continue
+			}
+
+			moves = append(moves, &Move{from: chess.NoSquare, to: sq, drop: pieceType})
+		}
+	}
+
+	return moves
+}
+
+func (p *Position) dropBlocksCheck(pieceType chess.PieceType, sq chess.Square) bool {
+	turn := p.Turn()
+
+	squares := p.pos.Board().SquareMap()
+	squares[sq] = chess.NewPiece(pieceType, turn)
+
+	board := chess.NewBoard(squares)
+	return !attacked(board, kingSquare(board, turn), turn.Other())
+}
+
+// pocketCapture puts whatever m captures in the movers pocket, promoted pieces go back to being pawns
+func (p *Position) pocketCapture(next *Position, m *Move) {
+	captured, sq := p.pos.Board().Piece(m.to), m.to
+	if m.move.HasTag(chess.EnPassant) {
+		sq = chess.NewSquare(m.to.File(), m.from.Rank())
+		captured = p.pos.Board().Piece(sq)
+	}
+
+	if captured != chess.NoPiece {
+		pieceType := captured.Type()
+		if p.isPromoted(sq) {
+			pieceType = chess.Pawn
+		}
+		next.pockets[p.Turn()-1][pieceType]++
+	}
+
+	// promoted pieces are remembered wherever they go so they can be given back as pawns
+	next.promoted &^= squareBit(m.to)
+	if p.isPromoted(m.from) || m.move.Promo() != chess.NoPieceType {
+		next.promoted |= squareBit(m.to)
+	}
+	next.promoted &^= squareBit(m.from)
+}
+
+func (p *Position) isPromoted(sq chess.Square) bool {
+	return p.promoted&squareBit(sq) != 0
+}
+
+func squareBit(sq chess.Square) uint64 {
+	return 1 << uint(sq)
+}
+
+// parseCrazyhouseBoard splits the pockets in brackets off the board field and drops the ~ marking promoted pieces,
+// leaving a board notnil can read
+func parseCrazyhouseBoard(field string) (string, [2]pocket, uint64, error) {
+	var pockets [2]pocket
+
+	board, held, ok := strings.Cut(field, "[")
+	if ok {
+		if !strings.HasSuffix(held, "]") {
+			return "", pockets, 0, fmt.Errorf("invalid pockets in %q", field)
+		}
+
+		for _, r := range strings.TrimSuffix(held, "]") {
+			piece, ok := pieceFromFEN(r)
+			if !ok || piece.Type() == chess.King {
+				return "", pockets, 0, fmt.Errorf("invalid piece %q in pockets", r)
+			}
+			pockets[piece.Color()-1][piece.Type()]++
+		}
+	}
+
+	var promoted uint64
+	var sb strings.Builder
+	file, rank := 0, 7
+	for _, r := range board {
+		switch {
+		case r == '/':
+			file, rank = 0, rank-1
+		case r == '~':
+			if file == 0 || rank < 0 {
+				return "", pockets, 0, fmt.Errorf("invalid promoted piece in %q", field)
+			}
+			promoted |= squareBit(chess.NewSquare(chess.File(file-1), chess.Rank(rank)))
+			continue
+		case '1' <= r && r <= '8':
+			file += int(r - '0')
+		default:
+			file++
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String(), pockets, promoted, nil
+}
+
+// crazyhouseBoard is the board field with promoted pieces marked by ~ and the pockets in brackets
+func (p *Position) crazyhouseBoard() string {
+	board := p.pos.Board()
+
+	var sb strings.Builder
+	for rank := chess.Rank8; rank >= chess.Rank1; rank-- {
+		empty := 0
+		for file := chess.FileA; file <= chess.FileH; file++ {
+			sq := chess.NewSquare(file, rank)
+
+			piece := board.Piece(sq)
+			if piece == chess.NoPiece {
+				empty++
+				continue
+			}
+
+			if empty > 0 {
+				fmt.Fprint(&sb, empty)
+				empty = 0
+			}
+
+			sb.WriteString(pieceFEN(piece))
+			if p.isPromoted(sq) {
+				sb.WriteRune('~')
+			}
+		}
+
+		if empty > 0 {
+			fmt.Fprint(&sb, empty)
+		}
+
+		if rank != chess.Rank1 {
+			sb.WriteRune('/')
+		}
+	}
+
+	sb.WriteRune('[')
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		for _, pieceType := range dropPieces {
+			sb.WriteString(strings.Repeat(pieceFEN(chess.NewPiece(pieceType, color)), p.pocket(color)[pieceType]))
+		}
+	}
+	sb.WriteRune(']')
+
+	return sb.String()
+}
+
+func pieceFEN(piece chess.Piece) string {
+	s := piece.Type().String()
+	if piece.Color() == chess.White {
+		return strings.ToUpper(s)
+	}
+
+	return s
+}
+
+func pieceFromFEN(r rune) (chess.Piece, bool) {
+	for _, pieceType := range []chess.PieceType{chess.King, chess.Queen, chess.Rook, chess.Bishop, chess.Knight, chess.Pawn} {
+		for _, color := range []chess.Color{chess.White, chess.Black} {
+			if piece := chess.NewPiece(pieceType, color); pieceFEN(piece) == string(r) {
+				return piece, true
+			}
+		}
+	}
+
+	return chess.NoPiece, false
+}
+
+// Pockets are the pieces each player holds in crazyhouse, keyed by piece letter
+type Pockets struct {
+	Light map[string]int `json:"light"`
+	Dark  map[string]int `json:"dark"`
+}
+
+// newPockets is nil for variants without drops so events leave pockets out
+func newPockets(position *Position) *Pockets {
+	if position.Variant() != Crazyhouse {
+		return nil
+	}
+
+	pockets := &Pockets{Light: make(map[string]int), Dark: make(map[string]int)}
+	for _, pieceType := range dropPieces {
+		letter := strings.ToUpper(pieceType.String())
+		pockets.Light[letter] = position.pocket(chess.White)[pieceType]
+		pockets.Dark[letter] = position.pocket(chess.Black)[pieceType]
+	}
+
+	return pockets
+}
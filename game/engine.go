@@ -12,7 +12,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/notnil/chess"
+	"github.com/michaelgov-ctrl/bad-chess/internal/models"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -25,6 +25,8 @@ type EngineManager struct {
 	matchesMu        sync.RWMutex
 	matchCleanupChan chan EngineMatchOutcome
 
+	engines *EnginePool
+
 	handlers map[string]EventHandler
 
 	ManagerOptions
@@ -41,8 +43,10 @@ func NewEngineManager(ctx context.Context, opts ...ManagerOption) *EngineManager
 	}
 
 	defaults := &ManagerOptions{
-		logger:   slog.New(slog.NewTextHandler(os.Stdout, nil)),
-		registry: prometheus.NewRegistry(),
+		logger:         slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		registry:       prometheus.NewRegistry(),
+		engineConfig:   DefaultEngineConfig(),
+		enginePoolSize: DefaultEnginePoolSize,
 	}
 
 	for _, opt := range opts {
@@ -51,6 +55,13 @@ func NewEngineManager(ctx context.Context, opts ...ManagerOption) *EngineManager
 
 	m.ManagerOptions = *defaults
 
+	// an explicit factory wins over the configured binary so fakes still get the configured ladder
+	if m.engineFactory == nil {
+		m.engineFactory = m.engineConfig.Factory()
+	}
+
+	m.engines = NewEnginePool(m.engineFactory, m.enginePoolSize, m.registry)
+
 	m.registerMatchmakingManagerMetrics()
 	m.registerSupportedEngineELOs()
 	m.registerEventHandlers()
@@ -62,17 +73,35 @@ func NewEngineManager(ctx context.Context, opts ...ManagerOption) *EngineManager
 func (m *EngineManager) registerEventHandlers() {
 	m.handlers[EventNewEngineMatchRequest] = m.engineMatchRequestHandler
 	m.handlers[EventMakeMove] = m.makeMoveHandler
+	m.handlers[EventResign] = m.matchActionHandler((*EngineMatch).Resign)
+	m.handlers[EventOfferDraw] = m.matchActionHandler((*EngineMatch).OfferDraw)
+	m.handlers[EventAcceptDraw] = m.matchActionHandler((*EngineMatch).AcceptDraw)
+	m.handlers[EventDeclineDraw] = m.matchActionHandler((*EngineMatch).DeclineDraw)
+	m.handlers[EventAbort] = m.matchActionHandler((*EngineMatch).Abort)
+	m.handlers[EventToggleEvaluation] = m.toggleEvaluationHandler
+	m.handlers[EventRequestHint] = m.matchActionHandler((*EngineMatch).RequestHint)
+	m.handlers[EventTakeback] = m.matchActionHandler((*EngineMatch).Takeback)
 }
 
 func (m *EngineManager) registerSupportedEngineELOs() {
 	m.matchesMu.Lock()
 	defer m.matchesMu.Unlock()
 
-	for ee := range SupportedEngineELOs {
-		m.matches[ee] = make(EngineMatchList)
+	for _, level := range m.engineConfig.Levels {
+		m.matches[level.ELO] = make(EngineMatchList)
 	}
 }
 
+// SupportedELOs is the configured ELO ladder from weakest to strongest
+func (m *EngineManager) SupportedELOs() []ELO {
+	return m.engineConfig.ELOs()
+}
+
+// SupportedVariants are the variants the configured engine can play
+func (m *EngineManager) SupportedVariants() []Variant {
+	return m.engineConfig.EngineVariants()
+}
+
 func (m *EngineManager) addClient(c *Client) {
 	m.logger.Debug("new client", "client", c)
 
@@ -105,14 +134,73 @@ func (m *EngineManager) ServeWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := NewClient(conn, m)
+	client := NewClient(conn, m, UserIdFromContext(r.Context()))
 
 	m.addClient(client)
 
+	if err := m.resumeMatch(client); err != nil {
+		m.logger.Error("failed to resume match", "error", err)
+	}
+
 	go client.readEvents(m.logger)
 	go client.writeEvents(m.logger)
 }
 
+// disconnectClient keeps a dropped players seat open so they can come back to it
+func (m *EngineManager) disconnectClient(c *Client) {
+	m.matchesMu.Lock()
+	if match, ok := m.matches[c.matchInfo().EngineELO][c.matchInfo().ID]; ok {
+		match.Disconnect(c)
+	}
+	m.matchesMu.Unlock()
+
+	m.removeClient(c)
+}
+
+// resumeMatch re-seats a returning user into an engine match they dropped out of and catches them up on it
+func (m *EngineManager) resumeMatch(c *Client) error {
+	if c.UserId() == "" {
+		return nil
+	}
+
+	m.matchesMu.Lock()
+	defer m.matchesMu.Unlock()
+
+	for elo, matchList := range m.matches {
+		for _, match := range matchList {
+			if !match.resumable(c.UserId()) {
+				continue
+			}
+
+			if err := match.Reconnect(c); err != nil {
+				return err
+			}
+
+			info := NewClientMatchInfo(match.ID, Engine, match.TimeControl, elo, match.PlayerPieces)
+			c.setMatchInfo(info)
+
+			assignedEvent, err := NewOutgoingEvent(EventAssignedMatch, info)
+			if err != nil {
+				return err
+			}
+
+			stateEvent, err := match.StateEvent()
+			if err != nil {
+				return err
+			}
+
+			c.egress <- assignedEvent
+			c.egress <- stateEvent
+
+			m.logger.Info("player reconnected", "MatchId", match.ID)
+
+			return nil
+		}
+	}
+
+	return nil
+}
+
 func (m *EngineManager) routeEvent(event Event, c *Client) error {
 	handler, ok := m.handlers[event.Type]
 	if !ok {
@@ -127,30 +215,41 @@ func (m *EngineManager) routeEvent(event Event, c *Client) error {
 }
 
 func (m *EngineManager) engineMatchRequestHandler(event Event, c *Client) error {
-	m.logger.Info("match making handler", "event", event, "client", *c)
+	m.logger.Info("match making handler", "event", event, "client", c)
 
-	newMatchEvent, err := m.parseMatchRequest(event)
+	newMatchEvent, variant, err := m.parseMatchRequest(event)
 	if err != nil {
 		return err
 	}
 
+	if c.matchInfo().ID != "" {
+		return errors.New("already in a match")
+	}
+
 	m.matchesMu.RLock()
 	matchId := m.newMatchId(newMatchEvent.ELO)
 	m.matchesMu.RUnlock()
 
 	playerPieces := assignPlayerPieces()
 
-	match, err := m.newEngineMatch(matchId, newMatchEvent.ELO, c, playerPieces)
+	game, err := newGame(variant, newMatchEvent.FEN)
+	if err != nil {
+		return err
+	}
+
+	match, err := m.newEngineMatch(matchId, newMatchEvent.ELO, newMatchEvent.TimeControl, game, c, playerPieces)
 	if err != nil {
 		return err
 	}
+	match.SetEvaluation(newMatchEvent.Evaluation)
 
 	m.matchesMu.Lock()
 	m.matches[newMatchEvent.ELO][matchId] = match
 	m.matchesMu.Unlock()
 
-	c.currentMatch = NewClientMatchInfo(matchId, Engine, EngineMatchTimeControl, newMatchEvent.ELO, playerPieces)
-	outgoingEvent, err := NewOutgoingEvent(EventAssignedMatch, c.currentMatch)
+	info := NewClientMatchInfo(matchId, Engine, newMatchEvent.TimeControl, newMatchEvent.ELO, playerPieces)
+	c.setMatchInfo(info)
+	outgoingEvent, err := NewOutgoingEvent(EventAssignedMatch, info)
 	if err != nil {
 		return err
 	}
@@ -161,42 +260,61 @@ func (m *EngineManager) engineMatchRequestHandler(event Event, c *Client) error
 		return err
 	}
 
-	if playerPieces != Light {
-		return match.EngineMove()
+	if match.Turn != playerPieces {
+		go m.engineMove(match)
 	}
 
 	return nil
 }
 
-func (m *EngineManager) parseMatchRequest(event Event) (NewEngineMatchEvent, error) {
+func (m *EngineManager) parseMatchRequest(event Event) (NewEngineMatchEvent, Variant, error) {
 	var newMatchEvent NewEngineMatchEvent
 	if err := json.Unmarshal(event.Payload, &newMatchEvent); err != nil {
-		return NewEngineMatchEvent{}, fmt.Errorf("bad payload in request: %w", err)
+		return NewEngineMatchEvent{}, nil, fmt.Errorf("bad payload in request: %w", err)
 	}
 
-	if _, ok := SupportedEngineELOs[newMatchEvent.ELO]; !ok {
-		return NewEngineMatchEvent{}, fmt.Errorf("unsupported engine ELO: %d", newMatchEvent.ELO)
+	if _, ok := m.engineConfig.Level(newMatchEvent.ELO); !ok {
+		return NewEngineMatchEvent{}, nil, fmt.Errorf("unsupported engine ELO: %d", newMatchEvent.ELO)
 	}
 
-	return newMatchEvent, nil
-}
+	if newMatchEvent.TimeControl == (TimeControl{}) {
+		newMatchEvent.TimeControl = EngineMatchTimeControl
+	} else if _, ok := SupportedTimeControls[newMatchEvent.TimeControl]; !ok {
+		return NewEngineMatchEvent{}, nil, fmt.Errorf("unsupported time control: %s", newMatchEvent.TimeControl)
+	}
 
-func (m *EngineManager) newEngineMatch(matchId MatchId, elo ELO, c *Client, playerPieces PieceColor) (*EngineMatch, error) {
-	engine, err := NewEngine(elo)
+	variant, err := ParseVariant(newMatchEvent.Variant)
 	if err != nil {
-		return nil, err
+		return NewEngineMatchEvent{}, nil, err
+	}
+
+	if !m.engineConfig.SupportsVariant(variant) {
+		return NewEngineMatchEvent{}, nil, fmt.Errorf("%w: the engine can't play %s", ErrUnsupportedVariant, variant)
+	}
+
+	return newMatchEvent, variant, nil
+}
+
+func (m *EngineManager) newEngineMatch(matchId MatchId, elo ELO, timeControl TimeControl, game *Game, c *Client, playerPieces PieceColor) (*EngineMatch, error) {
+	level, ok := m.engineConfig.Level(elo)
+	if !ok {
+		return nil, fmt.Errorf("unsupported engine ELO: %d", elo)
 	}
 
 	match := &EngineMatch{
-		ID:     matchId,
-		Engine: engine,
+		ID:          matchId,
+		ELO:         elo,
+		HintELO:     m.engineConfig.HintELO,
+		TimeControl: timeControl,
+		Limits:      level.Limits(),
+		Engines:     m.engines,
 		Player: &Player{
 			Client: c,
-			Clock:  NewClock(EngineMatchTimeControl),
+			UserId: c.UserId(),
 		},
 		PlayerPieces: playerPieces,
-		Game:         chess.NewGame(),
-		Turn:         Light,
+		Game:         game,
+		Turn:         pieceColorOf(game.Position().Turn()),
 		State:        Waiting,
 		Logger:       m.logger,
 	}
@@ -205,6 +323,20 @@ func (m *EngineManager) newEngineMatch(matchId MatchId, elo ELO, c *Client, play
 	return match, nil
 }
 
+// PGN returns the PGN of a match the manager is still holding on to
+func (m *EngineManager) PGN(id MatchId) (string, bool) {
+	m.matchesMu.RLock()
+	defer m.matchesMu.RUnlock()
+
+	for _, matchList := range m.matches {
+		if match, ok := matchList[id]; ok {
+			return match.PGN(), true
+		}
+	}
+
+	return "", false
+}
+
 func (m *EngineManager) newMatchId(elo ELO) MatchId {
 	matchId := MatchId(uuid.NewString())
 
@@ -217,7 +349,7 @@ func (m *EngineManager) newMatchId(elo ELO) MatchId {
 }
 
 func (m *EngineManager) makeMoveHandler(event Event, c *Client) error {
-	m.logger.Info("make move handler", "event", event, "client", *c)
+	m.logger.Info("make move handler", "event", event, "client", c)
 
 	var moveEvent MakeMoveEvent
 	if err := json.Unmarshal(event.Payload, &moveEvent); err != nil {
@@ -227,22 +359,66 @@ func (m *EngineManager) makeMoveHandler(event Event, c *Client) error {
 	m.matchesMu.RLock()
 	defer m.matchesMu.RUnlock()
 
-	match, ok := m.matches[c.currentMatch.EngineELO][c.currentMatch.ID]
+	match, ok := m.matches[c.matchInfo().EngineELO][c.matchInfo().ID]
 	if !ok {
 		return errors.New("no match")
 	}
 
-	if err := match.MakeMove(c.currentMatch.Pieces, moveEvent.Move); err != nil {
+	if err := match.MakeMove(c.matchInfo().Pieces, moveEvent.Move); err != nil {
 		return err
 	}
 
-	match.Player.Clock.Pause()
-	defer match.Player.Clock.Start()
-	// if engine move errors player clock will be started
-	// which is fine for now since the game state will be ruined if the engine move fails
-	// TODO: handle that
+	go m.engineMove(match)
+
+	return nil
+}
+
+// engineMove runs outside the handler since the engine may think for as long as its clock allows
+func (m *EngineManager) engineMove(match *EngineMatch) {
+	if err := match.EngineMove(); err != nil {
+		m.logger.Error("engine failed to move", "MatchId", match.ID, "error", err)
+	}
+}
+
+func (m *EngineManager) toggleEvaluationHandler(event Event, c *Client) error {
+	var toggleEvent ToggleEvaluationEvent
+	if err := json.Unmarshal(event.Payload, &toggleEvent); err != nil {
+		return fmt.Errorf("bad payload in request: %v", err)
+	}
+
+	m.matchesMu.RLock()
+	defer m.matchesMu.RUnlock()
+
+	match, ok := m.matches[c.matchInfo().EngineELO][c.matchInfo().ID]
+	if !ok {
+		return errors.New("no match")
+	}
+
+	match.SetEvaluation(toggleEvent.Enabled)
+
+	return nil
+}
+
+// matchActionHandler wraps match actions like resigning that only need the acting players pieces, actions
+// run under matchesMu so anything waiting on the engine has to be sent off in the background
+func (m *EngineManager) matchActionHandler(action func(*EngineMatch, PieceColor) error) EventHandler {
+	return func(event Event, c *Client) error {
+		m.logger.Info("match action handler", "event", event, "client", c)
+
+		m.matchesMu.RLock()
+		defer m.matchesMu.RUnlock()
 
-	return match.EngineMove()
+		match, ok := m.matches[c.matchInfo().EngineELO][c.matchInfo().ID]
+		if !ok {
+			return errors.New("no match")
+		}
+
+		if c.matchInfo().Pieces != match.PlayerPieces {
+			return fmt.Errorf("player pieces are borked")
+		}
+
+		return action(match, match.PlayerPieces)
+	}
 }
 
 // From the context of games coming from the website it makes sense to close client connections here
@@ -258,22 +434,44 @@ func (m *EngineManager) cleanupMatches() {
 
 			finishedMatches = append(finishedMatches, matchInfo)
 		case <-cleanupTime.C:
+			var records []models.MatchRecord
+			var analyses []analysisJob
+
 			m.matchesMu.Lock()
 			for _, finishedMatch := range finishedMatches {
 				m.logger.Debug("removing match from Engine Manager", "match info", finishedMatch)
 
 				if match, ok := m.matches[finishedMatch.ELO][finishedMatch.ID]; ok {
-					match.messagePlayer(Event{Type: EventMatchOver})
+					outgoingEvent, err := NewOutgoingEvent(EventMatchOver, finishedMatch)
+					if err != nil {
+						m.logger.Error("failed to create match over event", "error", err)
+						outgoingEvent = Event{Type: EventMatchOver}
+					}
+
+					records = append(records, match.Record(finishedMatch))
+
+					match.mu.Lock()
+					match.messagePlayer(outgoingEvent)
+					if m.analyzable(match.Game.Variant()) {
+						analyses = append(analyses, newAnalysisJob(match.ID, match.Game))
+					}
 
+					var client *Client
 					if match.Player != nil {
-						m.removeClient(match.Player.Client)
+						client = match.Player.Client
 					}
+					match.mu.Unlock()
+
+					m.removeClient(client)
 				}
 
 				delete(m.matches[finishedMatch.ELO], finishedMatch.ID)
 			}
 			m.matchesMu.Unlock()
 
+			m.storeMatchRecords(records)
+			m.queueAnalyses(analyses)
+
 			finishedMatches = nil
 		}
 	}
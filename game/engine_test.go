@@ -0,0 +1,390 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	fenAfterE4   = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+	fenAfterE4E5 = "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"
+	// the fake engine only has e2e4 scripted, as dark it falls back to the first legal move in UCI order
+	fenAfterE4A5   = "rnbqkbnr/1ppppppp/8/p7/4P3/8/PPPP1PPP/RNBQKBNR w KQkq a6 0 2"
+	fenAfterE4E5A3 = "rnbqkbnr/pppp1ppp/8/4p3/4P3/P7/1PPP1PPP/RNBQKBNR b KQkq - 0 2"
+)
+
+func newTestEngineManager(t *testing.T, script ...string) *EngineManager {
+	t.Helper()
+
+	return NewEngineManager(context.Background(),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithEngineFactory(FakeEngineFactory(script...)),
+	)
+}
+
+func newTestClient(m Manager, userId string) *Client {
+	return &Client{manager: m, userId: userId, egress: make(chan Event, egressBufferSize)}
+}
+
+func newEvent(t *testing.T, eventType string, payload any) Event {
+	t.Helper()
+
+	event, err := NewOutgoingEvent(eventType, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return event
+}
+
+// nextEvent waits for the next event sent to the client, clock updates are skipped as they come every second
+func nextEvent(t *testing.T, c *Client) Event {
+	t.Helper()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-c.egress:
+			if event.Type == EventClockUpdate {
+				continue
+			}
+			return event
+		case <-timeout:
+			t.Fatal("timed out waiting for an event")
+		}
+	}
+}
+
+func expectEvent(t *testing.T, c *Client, eventType string, payload any) {
+	t.Helper()
+
+	event := nextEvent(t, c)
+	if event.Type != eventType {
+		t.Fatalf("got %s event %s, want %s", event.Type, event.Payload, eventType)
+	}
+
+	if payload != nil {
+		if err := json.Unmarshal(event.Payload, payload); err != nil {
+			t.Fatalf("bad %s payload %s: %v", eventType, event.Payload, err)
+		}
+	}
+}
+
+func expectPosition(t *testing.T, c *Client, player PieceColor, fen string) {
+	t.Helper()
+
+	var position PropagatePositionEvent
+	expectEvent(t, c, EventPropagatePosition, &position)
+
+	if position.PlayerColor != player.String() || position.FEN != fen {
+		t.Fatalf("got %s moving to %s, want %s moving to %s", position.PlayerColor, position.FEN, player, fen)
+	}
+}
+
+func TestEngineMatchRequestHandler(t *testing.T) {
+	m := newTestEngineManager(t, "e2e4")
+	elo := m.SupportedELOs()[0]
+
+	c := newTestClient(m, "alice")
+	if err := m.engineMatchRequestHandler(newEvent(t, EventNewEngineMatchRequest, map[string]any{"elo": elo, "variant": "standard"}), c); err != nil {
+		t.Fatal(err)
+	}
+
+	// the default engine time control isn't one players can pick so it can't be read back into a ClientMatchInfo
+	var assigned struct {
+		ID        MatchId   `json:"match_id"`
+		MatchType MatchType `json:"match_type"`
+		EngineELO ELO       `json:"engine_elo"`
+		Pieces    string    `json:"pieces"`
+	}
+	expectEvent(t, c, EventAssignedMatch, &assigned)
+	expectEvent(t, c, EventMatchStarted, nil)
+
+	if assigned.ID == "" || assigned.ID != c.matchInfo().ID || assigned.EngineELO != elo || assigned.MatchType != Engine {
+		t.Fatalf("assigned %+v, client is in %+v", assigned, c.matchInfo())
+	}
+
+	m.matchesMu.RLock()
+	match, ok := m.matches[elo][assigned.ID]
+	m.matchesMu.RUnlock()
+	if !ok {
+		t.Fatalf("match %s isn't held by the manager", assigned.ID)
+	}
+
+	if match.State != Started || match.PlayerPieces.String() != assigned.Pieces || match.TimeControl != EngineMatchTimeControl {
+		t.Fatalf("match is %v with the player on %v at %v", match.State, match.PlayerPieces, match.TimeControl)
+	}
+
+	// the engine opens the game when it has light
+	if match.PlayerPieces == Dark {
+		expectPosition(t, c, Light, fenAfterE4)
+	}
+
+	if match.Turn != match.PlayerPieces {
+		t.Fatalf("it's %v's turn, want the players", match.Turn)
+	}
+}
+
+func TestEngineMatchRequestHandlerErrors(t *testing.T) {
+	m := newTestEngineManager(t)
+	elo := m.SupportedELOs()[0]
+
+	seated := newTestClient(m, "bob")
+	seated.setMatchInfo(ClientMatchInfo{ID: "already-playing"})
+
+	tests := []struct {
+		name    string
+		client  *Client
+		request map[string]any
+	}{
+		{"unsupported elo", newTestClient(m, "alice"), map[string]any{"elo": elo + 1, "variant": "standard"}},
+		{"unsupported time control", newTestClient(m, "alice"), map[string]any{"elo": elo, "variant": "standard", "time_control": "7+0"}},
+		{"variant the engine can't play", newTestClient(m, "alice"), map[string]any{"elo": elo, "variant": "crazyhouse"}},
+		{"bad starting position", newTestClient(m, "alice"), map[string]any{"elo": elo, "variant": "standard", "fen": "not a fen"}},
+		{"already in a match", seated, map[string]any{"elo": elo, "variant": "standard"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.engineMatchRequestHandler(newEvent(t, EventNewEngineMatchRequest, tt.request), tt.client); err == nil {
+				t.Fatal("engineMatchRequestHandler() succeeded, want an error")
+			}
+
+			if len(tt.client.egress) != 0 {
+				t.Fatalf("client was sent %d events for a rejected request", len(tt.client.egress))
+			}
+		})
+	}
+}
+
+func TestEngineMakeMoveHandler(t *testing.T) {
+	m := newTestEngineManager(t, "e2e4")
+	elo := m.SupportedELOs()[0]
+
+	c := newTestClient(m, "alice")
+	if err := m.engineMatchRequestHandler(newEvent(t, EventNewEngineMatchRequest, map[string]any{"elo": elo, "variant": "standard"}), c); err != nil {
+		t.Fatal(err)
+	}
+
+	expectEvent(t, c, EventAssignedMatch, nil)
+	expectEvent(t, c, EventMatchStarted, nil)
+
+	m.matchesMu.RLock()
+	match := m.matches[elo][c.matchInfo().ID]
+	m.matchesMu.RUnlock()
+
+	move := func(san string) error {
+		return m.makeMoveHandler(newEvent(t, EventMakeMove, MakeMoveEvent{Move: san}), c)
+	}
+
+	// the pieces are picked at random so both sides are covered across runs
+	if match.PlayerPieces == Dark {
+		if err := move("e4"); err == nil {
+			t.Fatal("moved on the engines turn")
+		}
+
+		expectPosition(t, c, Light, fenAfterE4)
+
+		if err := move("e5"); err != nil {
+			t.Fatal(err)
+		}
+
+		expectPosition(t, c, Dark, fenAfterE4E5)
+		expectPosition(t, c, Light, fenAfterE4E5A3)
+	} else {
+		if err := move("e5"); err == nil {
+			t.Fatal("made an illegal move")
+		}
+
+		if err := move("e4"); err != nil {
+			t.Fatal(err)
+		}
+
+		expectPosition(t, c, Light, fenAfterE4)
+		expectPosition(t, c, Dark, fenAfterE4A5)
+	}
+
+	want := 2
+	if match.PlayerPieces == Dark {
+		want = 3
+	}
+
+	if got := len(match.Game.Moves()); got != want {
+		t.Fatalf("match has %d moves, want %d", got, want)
+	}
+
+	if match.Turn != match.PlayerPieces {
+		t.Fatalf("it's %v's turn after the engine replied, want the players", match.Turn)
+	}
+
+	// each move records the movers clock for the PGN
+	if len(match.moveClocks) != len(match.Game.Moves()) {
+		t.Fatalf("%d clock times for %d moves", len(match.moveClocks), len(match.Game.Moves()))
+	}
+
+	if match.Player.Clock.TimeRemaining() <= 0 || match.EngineClock.TimeRemaining() <= 0 {
+		t.Fatal("a clock ran out during the test")
+	}
+}
+
+// actionReturns runs a match action handler and fails if it's still waiting after a second
+func actionReturns(t *testing.T, m *EngineManager, c *Client, eventType string) error {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.handlers[eventType](Event{Type: eventType}, c)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		t.Fatalf("%s handler is waiting on the engine", eventType)
+		return nil
+	}
+}
+
+func TestEngineSearchesRunOutsideTheHandlers(t *testing.T) {
+	engine := newBlockingEngine()
+	m := NewEngineManager(context.Background(),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithEngineFactory(func() (EngineBackend, error) { return engine, nil }),
+		WithEnginePoolSize(1),
+	)
+	elo := m.SupportedELOs()[0]
+
+	c := newTestClient(m, "alice")
+	if err := m.engineMatchRequestHandler(newEvent(t, EventNewEngineMatchRequest, map[string]any{"elo": elo, "variant": "standard"}), c); err != nil {
+		t.Fatal(err)
+	}
+
+	expectEvent(t, c, EventAssignedMatch, nil)
+	expectEvent(t, c, EventMatchStarted, nil)
+
+	m.matchesMu.RLock()
+	match := m.matches[elo][c.matchInfo().ID]
+	m.matchesMu.RUnlock()
+
+	if match.PlayerPieces == Dark {
+		engine.Stop()
+		expectEvent(t, c, EventPropagatePosition, nil)
+	}
+
+	if err := actionReturns(t, m, c, EventRequestHint); err != nil {
+		t.Fatal(err)
+	}
+
+	engine.Stop()
+
+	var hint HintEvent
+	expectEvent(t, c, EventHint, &hint)
+	if hint.Move == "" || !match.assisted {
+		t.Fatalf("got hint %+v with the match assisted %v", hint, match.assisted)
+	}
+
+	if err := actionReturns(t, m, c, EventOfferDraw); err != nil {
+		t.Fatal(err)
+	}
+
+	// the fake engine scores every position level so it takes the draw
+	engine.Stop()
+
+	outcome := func() chess.Outcome {
+		match.mu.Lock()
+		defer match.mu.Unlock()
+
+		return match.Game.Outcome()
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for outcome() != chess.Draw {
+		if time.Now().After(deadline) {
+			t.Fatal("engine never accepted the draw")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEngineTakeback(t *testing.T) {
+	m := newTestEngineManager(t, "e2e4")
+	elo := m.SupportedELOs()[0]
+
+	c := newTestClient(m, "alice")
+	if err := m.engineMatchRequestHandler(newEvent(t, EventNewEngineMatchRequest, map[string]any{"elo": elo, "variant": "standard"}), c); err != nil {
+		t.Fatal(err)
+	}
+
+	expectEvent(t, c, EventAssignedMatch, nil)
+	expectEvent(t, c, EventMatchStarted, nil)
+
+	m.matchesMu.RLock()
+	match := m.matches[elo][c.matchInfo().ID]
+	m.matchesMu.RUnlock()
+
+	moves := []string{"e4", "d4"}
+	if match.PlayerPieces == Dark {
+		moves = []string{"e5", "d5"}
+		expectEvent(t, c, EventPropagatePosition, nil)
+	}
+
+	for _, san := range moves {
+		if err := m.makeMoveHandler(newEvent(t, EventMakeMove, MakeMoveEvent{Move: san}), c); err != nil {
+			t.Fatal(err)
+		}
+
+		expectEvent(t, c, EventPropagatePosition, nil)
+		expectEvent(t, c, EventPropagatePosition, nil)
+	}
+
+	// the recorded times are made up so the restored clocks can be told apart
+	for i := range match.moveClocks {
+		match.moveClocks[i] = EngineMatchTimeControl.Base - time.Duration(i+1)*time.Minute
+	}
+
+	base := EngineMatchTimeControl.Base
+	tests := []struct {
+		player, engine time.Duration
+	}{
+		{29 * time.Minute, 28 * time.Minute},
+		{base, base},
+	}
+
+	// with the engine on light its opening move stays on the board and keeps its clock reading
+	if match.PlayerPieces == Dark {
+		tests = []struct {
+			player, engine time.Duration
+		}{
+			{28 * time.Minute, 27 * time.Minute},
+			{base, 29 * time.Minute},
+		}
+	}
+
+	for _, tt := range tests {
+		moves := len(match.Game.Moves())
+		if err := actionReturns(t, m, c, EventTakeback); err != nil {
+			t.Fatal(err)
+		}
+
+		expectEvent(t, c, EventMatchState, nil)
+
+		if got := len(match.Game.Moves()); got != moves-2 {
+			t.Fatalf("match has %d moves after the takeback, want %d", got, moves-2)
+		}
+
+		// the players clock is running again so it's allowed to have lost a little
+		if got := match.Player.Clock.TimeRemaining(); got > tt.player || got < tt.player-time.Second {
+			t.Errorf("player clock = %v, want %v", got, tt.player)
+		}
+
+		if got := match.EngineClock.TimeRemaining(); got != tt.engine {
+			t.Errorf("engine clock = %v, want %v", got, tt.engine)
+		}
+	}
+}
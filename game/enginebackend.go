@@ -0,0 +1,220 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+var (
+	ErrNoBestMove = errors.New("engine returned no move")
+	// ErrEngineFailed wraps errors talking to the engine process, an engine that returns one can't be trusted again
+	ErrEngineFailed = errors.New("engine failed")
+)
+
+// EngineBackend is everything a match needs from a chess engine
+type EngineBackend interface {
+	NewGame() error
+	// SetStrength limits the engine to playing at elo, zero lets it play at full strength
+	SetStrength(elo ELO) error
+	BestMove(position *Position, limits SearchLimits) (SearchResult, error)
+	// Stop cuts a running search short, BestMove still returns with the best move found so far
+	Stop() error
+	Close() error
+}
+
+// EngineFactory starts a new engine, the engine manager calls it once for every match
+type EngineFactory func() (EngineBackend, error)
+
+// SearchLimits left at zero aren't sent, the engine stops at whichever limit it reaches first
+// and budgets its own time from the clocks when they're given
+type SearchLimits struct {
+	MoveTime time.Duration
+	Depth    int
+	Nodes    int
+
+	WhiteTime      time.Duration
+	BlackTime      time.Duration
+	WhiteIncrement time.Duration
+	BlackIncrement time.Duration
+}
+
+// SearchResult scores are from the perspective of the side to move, Mate is 0 unless a mate was found
+type SearchResult struct {
+	BestMove *Move
+	CP       int
+	Mate     int
+	Depth    int
+	// PV is the line the engine expects starting with BestMove, each move is legal in the position before it
+	PV []*Move
+}
+
+// UCIEngine runs a UCI engine binary such as stockfish as a subprocess
+type UCIEngine struct {
+	engine *uci.Engine
+	// variants are the UCI_Variant values the engine reported, empty for engines that only play chess
+	variants []string
+	variant  string
+	chess960 bool
+}
+
+// NewUCIEngine starts the engine at path and sets each of options on it once up front
+func NewUCIEngine(path string, options map[string]string) (*UCIEngine, error) {
+	engine, err := uci.New(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := []uci.Cmd{uci.CmdUCI}
+	for _, name := range slices.Sorted(maps.Keys(options)) {
+		cmds = append(cmds, uci.CmdSetOption{Name: name, Value: options[name]})
+	}
+	cmds = append(cmds, uci.CmdIsReady)
+
+	if err := engine.Run(cmds...); err != nil {
+		engine.Close()
+		return nil, err
+	}
+
+	return &UCIEngine{
+		engine:   engine,
+		variants: engine.Options()["UCI_Variant"].Vars,
+		variant:  Standard.UCIVariant(),
+	}, nil
+}
+
+// run sends cmds to the engine, anything going wrong here is the process or its pipes
+func (e *UCIEngine) run(cmds ...uci.Cmd) error {
+	if err := e.engine.Run(cmds...); err != nil {
+		return fmt.Errorf("%w: %w", ErrEngineFailed, err)
+	}
+
+	return nil
+}
+
+func (e *UCIEngine) NewGame() error {
+	return e.run(uci.CmdUCINewGame, uci.CmdIsReady)
+}
+
+func (e *UCIEngine) SetStrength(elo ELO) error {
+	if elo <= 0 {
+		return e.run(uci.CmdSetOption{Name: "UCI_LimitStrength", Value: "false"})
+	}
+
+	return e.run(
+		uci.CmdSetOption{Name: "UCI_LimitStrength", Value: "true"},
+		uci.CmdSetOption{Name: "UCI_Elo", Value: strconv.Itoa(elo)},
+	)
+}
+
+// setVariant only tells the engine about the variant when a search is for a different one than the last
+func (e *UCIEngine) setVariant(variant Variant) error {
+	if uciVariant := variant.UCIVariant(); uciVariant != e.variant {
+		// the uci package can't decode drops in the engines bestmove so crazyhouse is off the table
+		if !slices.Contains(e.variants, uciVariant) || variant == Crazyhouse {
+			return fmt.Errorf("%w: engine can't play %s", ErrUnsupportedVariant, variant)
+		}
+
+		if err := e.run(uci.CmdSetOption{Name: "UCI_Variant", Value: uciVariant}); err != nil {
+			return err
+		}
+		e.variant = uciVariant
+	}
+
+	if variant.Chess960() != e.chess960 {
+		if err := e.run(uci.CmdSetOption{Name: "UCI_Chess960", Value: strconv.FormatBool(variant.Chess960())}); err != nil {
+			return err
+		}
+		e.chess960 = variant.Chess960()
+	}
+
+	return nil
+}
+
+func (e *UCIEngine) BestMove(position *Position, limits SearchLimits) (SearchResult, error) {
+	// stockfish answers bestmove (none) which the uci package can't read, so the engine isn't asked
+	if len(position.LegalMoves()) == 0 {
+		return SearchResult{}, ErrNoBestMove
+	}
+
+	if err := e.setVariant(position.Variant()); err != nil {
+		return SearchResult{}, err
+	}
+
+	cmdGo := uci.CmdGo{
+		MoveTime:       limits.MoveTime,
+		Depth:          limits.Depth,
+		Nodes:          limits.Nodes,
+		WhiteTime:      limits.WhiteTime,
+		BlackTime:      limits.BlackTime,
+		WhiteIncrement: limits.WhiteIncrement,
+		BlackIncrement: limits.BlackIncrement,
+	}
+	if err := e.run(cmdPosition{fen: position.String()}, cmdGo); err != nil {
+		return SearchResult{}, err
+	}
+
+	// the uci package only comes back without a bestmove when the engines output closed
+	results := e.engine.SearchResults()
+	if results.BestMove == nil {
+		return SearchResult{}, fmt.Errorf("%w: %w", ErrEngineFailed, ErrNoBestMove)
+	}
+
+	// moves from the engine are only squares so they're matched back up with a legal move
+	bestMove, err := position.ParseMove(results.BestMove.String())
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	return SearchResult{
+		BestMove: bestMove,
+		CP:       results.Info.Score.CP,
+		Mate:     results.Info.Score.Mate,
+		Depth:    results.Info.Depth,
+		PV:       resolvePV(position, results.Info.PV),
+	}, nil
+}
+
+// resolvePV replays the engines line and stops at the first move that isn't legal, e.g. from a stale info line
+func resolvePV(position *Position, pv []*chess.Move) []*Move {
+	moves := make([]*Move, 0, len(pv))
+	for _, pvMove := range pv {
+		move, err := position.ParseMove(pvMove.String())
+		if err != nil {
+			break
+		}
+
+		moves = append(moves, move)
+		position = position.Update(move)
+	}
+
+	return moves
+}
+
+// cmdPosition sends our own fen, uci.CmdPosition would write castling rights the way notnil sees them
+type cmdPosition struct {
+	fen string
+}
+
+func (c cmdPosition) String() string {
+	return "position fen " + c.fen
+}
+
+func (cmdPosition) ProcessResponse(*uci.Engine) error {
+	return nil
+}
+
+// Stop skips the uci packages lock so it can be sent while BestMove waits on the engine
+func (e *UCIEngine) Stop() error {
+	return e.run(uci.CmdStop)
+}
+
+func (e *UCIEngine) Close() error {
+	return e.engine.Close()
+}
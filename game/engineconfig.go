@@ -0,0 +1,262 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/notnil/chess/uci"
+)
+
+const DefaultEnginePath = "stockfish"
+
+// EngineConfig picks the engine binary, the UCI options it runs with and the ELO ladder players can choose from
+type EngineConfig struct {
+	Path    string            `json:"path"`
+	Options map[string]string `json:"options"`
+	Levels  []EngineLevel     `json:"levels"`
+	// HintELO is how strong the engine plays when suggesting a move, zero is full strength
+	HintELO ELO `json:"hint_elo"`
+	// Variants are the variants players may pick against the engine, anything past chess960 needs an engine with UCI_Variant
+	Variants []string `json:"variants"`
+}
+
+// EngineLevel is one rung of the ELO ladder, the clock bounds every search
+// but movetime, depth and nodes can cap it further to weaken the engine
+type EngineLevel struct {
+	ELO ELO `json:"elo"`
+	// MoveTime is in milliseconds like the UCI movetime
+	MoveTime int `json:"movetime"`
+	Depth    int `json:"depth"`
+	Nodes    int `json:"nodes"`
+}
+
+func (l EngineLevel) Limits() SearchLimits {
+	return SearchLimits{
+		MoveTime: time.Duration(l.MoveTime) * time.Millisecond,
+		Depth:    l.Depth,
+		Nodes:    l.Nodes,
+	}
+}
+
+// DefaultEngineVariants are the variants stockfish can play
+var DefaultEngineVariants = []string{Standard.String(), Chess960.String()}
+
+// DefaultEngineConfig is stockfish on the PATH playing the SupportedEngineELOs
+func DefaultEngineConfig() EngineConfig {
+	cfg := EngineConfig{
+		Path:     DefaultEnginePath,
+		Options:  make(map[string]string),
+		Variants: DefaultEngineVariants,
+	}
+
+	for elo := range SupportedEngineELOs {
+		cfg.Levels = append(cfg.Levels, EngineLevel{ELO: elo})
+	}
+
+	slices.SortFunc(cfg.Levels, func(a, b EngineLevel) int {
+		return a.ELO - b.ELO
+	})
+
+	return cfg
+}
+
+// LoadEngineConfig reads a json engine config, anything left out of the file keeps its default
+func LoadEngineConfig(path string) (EngineConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return EngineConfig{}, err
+	}
+	defer f.Close()
+
+	cfg := DefaultEngineConfig()
+	cfg.Levels = nil
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return EngineConfig{}, fmt.Errorf("bad engine config %s: %w", path, err)
+	}
+
+	if cfg.Levels == nil {
+		cfg.Levels = DefaultEngineConfig().Levels
+	}
+
+	if cfg.Options == nil {
+		cfg.Options = make(map[string]string)
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func (c EngineConfig) Validate() error {
+	if c.Path == "" {
+		return errors.New("engine path must be set")
+	}
+
+	if len(c.Levels) == 0 {
+		return errors.New("engine config needs at least one level")
+	}
+
+	if c.HintELO < 0 {
+		return fmt.Errorf("invalid hint elo: %d", c.HintELO)
+	}
+
+	if len(c.Variants) == 0 {
+		return errors.New("engine config needs at least one variant")
+	}
+
+	for _, name := range c.Variants {
+		variant, err := ParseVariant(name)
+		if err != nil {
+			return fmt.Errorf("engine variant: %w", err)
+		}
+
+		if variant == Crazyhouse {
+			return fmt.Errorf("engine variant: %w: %s, drops can't be read back from the engine", ErrUnsupportedVariant, variant)
+		}
+	}
+
+	seen := make(map[ELO]bool)
+	for _, level := range c.Levels {
+		if level.ELO <= 0 {
+			return fmt.Errorf("invalid engine level elo: %d", level.ELO)
+		}
+
+		if seen[level.ELO] {
+			return fmt.Errorf("duplicate engine level elo: %d", level.ELO)
+		}
+		seen[level.ELO] = true
+
+		if level.MoveTime < 0 || level.Depth < 0 || level.Nodes < 0 {
+			return fmt.Errorf("engine level %d has a negative search limit", level.ELO)
+		}
+	}
+
+	return nil
+}
+
+func (c EngineConfig) Factory() EngineFactory {
+	return func() (EngineBackend, error) {
+		return NewUCIEngine(c.Path, c.Options)
+	}
+}
+
+func (c EngineConfig) Level(elo ELO) (EngineLevel, bool) {
+	for _, level := range c.Levels {
+		if level.ELO == elo {
+			return level, true
+		}
+	}
+
+	return EngineLevel{}, false
+}
+
+// EngineVariants are the configured variants in the order they're offered to players
+func (c EngineConfig) EngineVariants() []Variant {
+	var variants []Variant
+	for _, variant := range SupportedVariants {
+		if c.SupportsVariant(variant) {
+			variants = append(variants, variant)
+		}
+	}
+
+	return variants
+}
+
+func (c EngineConfig) SupportsVariant(variant Variant) bool {
+	return slices.ContainsFunc(c.Variants, func(name string) bool {
+		configured, err := ParseVariant(name)
+		return err == nil && configured == variant
+	})
+}
+
+func (c EngineConfig) ELOs() []ELO {
+	elos := make([]ELO, 0, len(c.Levels))
+	for _, level := range c.Levels {
+		elos = append(elos, level.ELO)
+	}
+
+	slices.Sort(elos)
+
+	return elos
+}
+
+// CheckEngine launches the engine and makes sure it reports every configured option, plus the options used to limit its strength
+// and play each configured variant
+func (c EngineConfig) CheckEngine() error {
+	engine, err := uci.New(c.Path)
+	if err != nil {
+		return fmt.Errorf("failed to start engine %s: %w", c.Path, err)
+	}
+	defer engine.Close()
+
+	if err := engine.Run(uci.CmdUCI); err != nil {
+		return fmt.Errorf("engine %s did not respond to uci: %w", c.Path, err)
+	}
+
+	reported := engine.Options()
+	required := []string{"UCI_LimitStrength", "UCI_Elo"}
+	if c.SupportsVariant(Chess960) {
+		required = append(required, "UCI_Chess960")
+	}
+
+	for _, name := range required {
+		if _, ok := reported[name]; !ok {
+			return fmt.Errorf("engine %s does not support %s", c.Path, name)
+		}
+	}
+
+	for _, variant := range c.EngineVariants() {
+		if variant.UCIVariant() == Standard.UCIVariant() {
+			continue
+		}
+
+		if !slices.Contains(reported["UCI_Variant"].Vars, variant.UCIVariant()) {
+			return fmt.Errorf("engine %s does not support UCI_Variant %s", c.Path, variant.UCIVariant())
+		}
+	}
+
+	for name, value := range c.Options {
+		option, ok := reported[name]
+		if !ok {
+			return fmt.Errorf("engine %s does not support option %q", c.Path, name)
+		}
+
+		if err := checkOptionValue(option, value); err != nil {
+			return fmt.Errorf("engine %s option %q: %w", c.Path, name, err)
+		}
+	}
+
+	return nil
+}
+
+func checkOptionValue(option uci.Option, value string) error {
+	switch option.Type {
+	case uci.OptionSpin:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+
+		min, minErr := strconv.Atoi(option.Min)
+		max, maxErr := strconv.Atoi(option.Max)
+		if minErr == nil && maxErr == nil && (v < min || max < v) {
+			return fmt.Errorf("%d is outside of %d-%d", v, min, max)
+		}
+	case uci.OptionCheck:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+	case uci.OptionCombo:
+		if !slices.Contains(option.Vars, value) {
+			return fmt.Errorf("%q is not one of %v", value, option.Vars)
+		}
+	}
+
+	return nil
+}
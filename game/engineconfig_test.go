@@ -0,0 +1,37 @@
+package game
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadEngineConfigLevels(t *testing.T) {
+	// configs written before the engine played on the clock still set a movetime per level
+	path := filepath.Join(t.TempDir(), "engine.json")
+	config := `{"path": "stockfish", "levels": [{"elo": 1400, "movetime": 500, "depth": 8}, {"elo": 2200, "nodes": 2000000}]}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadEngineConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SearchLimits{
+		{MoveTime: 500 * time.Millisecond, Depth: 8},
+		{Nodes: 2000000},
+	}
+
+	if len(cfg.Levels) != len(want) {
+		t.Fatalf("loaded %d levels, want %d", len(cfg.Levels), len(want))
+	}
+
+	for i, level := range cfg.Levels {
+		if got := level.Limits(); got != want[i] {
+			t.Errorf("level %d limits = %+v, want %+v", level.ELO, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const DefaultEnginePoolSize = 4
+
+var (
+	// EnginePoolWaitTimeout bounds how long a search queues for a free engine
+	EnginePoolWaitTimeout = 30 * time.Second
+	// EngineStopTimeout is how long a stopped search has to report its move before the engine is given up on
+	EngineStopTimeout = 5 * time.Second
+
+	ErrEnginePoolTimeout = errors.New("timed out waiting for a free engine")
+)
+
+// EnginePool shares a fixed number of engine processes between every engine match,
+// engines are started as they're first needed and leased out for a single search at a time
+type EnginePool struct {
+	factory EngineFactory
+	size    int
+	idle    chan EngineBackend
+
+	created   int
+	createdMu sync.Mutex
+
+	metrics *EnginePoolMetrics
+}
+
+func NewEnginePool(factory EngineFactory, size int, registry *prometheus.Registry) *EnginePool {
+	if size < 1 {
+		size = 1
+	}
+
+	p := &EnginePool{
+		factory: factory,
+		size:    size,
+		idle:    make(chan EngineBackend, size),
+		metrics: &EnginePoolMetrics{},
+	}
+
+	p.registerEnginePoolMetrics(registry)
+
+	return p
+}
+
+type searched struct {
+	result SearchResult
+	err    error
+}
+
+// Search runs a single search on a leased engine playing at elo, when ctx is done first the search is
+// abandoned and the engine is stopped and returned to the pool in the background
+func (p *EnginePool) Search(ctx context.Context, elo ELO, position *Position, limits SearchLimits) (SearchResult, error) {
+	engine, err := p.lease(ctx, elo)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	done := make(chan searched, 1)
+	go func() {
+		result, err := engine.BestMove(position, limits)
+		done <- searched{result: result, err: err}
+	}()
+
+	select {
+	case s := <-done:
+		p.release(engine, s.err)
+		return s.result, s.err
+	case <-ctx.Done():
+		go p.stop(engine, done)
+		return SearchResult{}, ctx.Err()
+	}
+}
+
+// stop ends an abandoned search, the engine is only replaced if it doesn't come back with its move
+func (p *EnginePool) stop(engine EngineBackend, done <-chan searched) {
+	if err := engine.Stop(); err != nil {
+		p.release(engine, err)
+		return
+	}
+
+	select {
+	case s := <-done:
+		p.release(engine, s.err)
+	case <-time.After(EngineStopTimeout):
+		p.release(engine, fmt.Errorf("%w: no move %v after stop", ErrEngineFailed, EngineStopTimeout))
+	}
+}
+
+// Close shuts down every idle engine, engines still leased are closed as they're returned
+func (p *EnginePool) Close() {
+	p.createdMu.Lock()
+	p.size = 0
+	p.createdMu.Unlock()
+
+	for {
+		select {
+		case engine := <-p.idle:
+			engine.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (p *EnginePool) lease(ctx context.Context, elo ELO) (EngineBackend, error) {
+	start := time.Now()
+	engine, err := p.acquire(ctx)
+	p.metrics.waitTime.Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	p.metrics.busy.Inc()
+
+	// every search starts from a fresh game so matches never share a hash table or strength
+	if err := engine.SetStrength(elo); err != nil {
+		p.release(engine, err)
+		return nil, err
+	}
+
+	if err := engine.NewGame(); err != nil {
+		p.release(engine, err)
+		return nil, err
+	}
+
+	return engine, nil
+}
+
+func (p *EnginePool) acquire(ctx context.Context) (EngineBackend, error) {
+	select {
+	case engine := <-p.idle:
+		return engine, nil
+	default:
+	}
+
+	p.createdMu.Lock()
+	if p.created < p.size {
+		p.created++
+		p.createdMu.Unlock()
+
+		engine, err := p.factory()
+		if err != nil {
+			p.forget()
+			return nil, err
+		}
+
+		return engine, nil
+	}
+	p.createdMu.Unlock()
+
+	p.metrics.waiting.Inc()
+	defer p.metrics.waiting.Dec()
+
+	select {
+	case engine := <-p.idle:
+		return engine, nil
+	case <-time.After(EnginePoolWaitTimeout):
+		return nil, ErrEnginePoolTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// release hands an engine back to the pool. only an engine whose process failed is replaced, searches that
+// fail for other reasons like a variant the engine can't play leave it as good as it was
+func (p *EnginePool) release(engine EngineBackend, err error) {
+	p.metrics.busy.Dec()
+
+	if errors.Is(err, ErrEngineFailed) {
+		engine.Close()
+		go p.replace()
+		return
+	}
+
+	p.createdMu.Lock()
+	closed := p.size == 0
+	p.createdMu.Unlock()
+
+	if closed {
+		engine.Close()
+		return
+	}
+
+	p.idle <- engine
+}
+
+// replace starts a new engine in place of a failed one so anyone queued isn't left waiting on it
+func (p *EnginePool) replace() {
+	engine, err := p.factory()
+	if err != nil {
+		p.forget()
+		return
+	}
+
+	p.idle <- engine
+}
+
+func (p *EnginePool) forget() {
+	p.createdMu.Lock()
+	defer p.createdMu.Unlock()
+
+	p.created--
+}
+
+type EnginePoolMetrics struct {
+	size     prometheus.Gauge
+	busy     prometheus.Gauge
+	waiting  prometheus.Gauge
+	waitTime prometheus.Histogram
+}
+
+func (p *EnginePool) registerEnginePoolMetrics(registry *prometheus.Registry) {
+	p.metrics.size = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "engine_pool_engines",
+			Help: "Number of engines the pool may run at once",
+		},
+	)
+	p.metrics.size.Set(float64(p.size))
+
+	p.metrics.busy = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "engine_pool_engines_busy",
+			Help: "Number of engines currently leased out for a search",
+		},
+	)
+
+	p.metrics.waiting = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "engine_pool_searches_waiting",
+			Help: "Number of searches queued waiting for a free engine",
+		},
+	)
+
+	p.metrics.waitTime = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "engine_pool_wait_seconds",
+			Help:    "Time searches spent waiting to lease an engine",
+			Buckets: []float64{0.001, 0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30},
+		},
+	)
+
+	registry.MustRegister(p.metrics.size, p.metrics.busy, p.metrics.waiting, p.metrics.waitTime)
+}
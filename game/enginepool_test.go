@@ -0,0 +1,144 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// blockingEngine thinks until it's stopped, like an engine given an infinite search
+type blockingEngine struct {
+	*FakeEngine
+	stop   chan struct{}
+	stops  atomic.Int32
+	closed atomic.Bool
+	// err is returned by every search once set
+	err error
+}
+
+func newBlockingEngine() *blockingEngine {
+	return &blockingEngine{FakeEngine: NewFakeEngine(), stop: make(chan struct{}, 1)}
+}
+
+func (e *blockingEngine) BestMove(position *Position, limits SearchLimits) (SearchResult, error) {
+	if e.err != nil {
+		return SearchResult{}, e.err
+	}
+
+	<-e.stop
+	return e.FakeEngine.BestMove(position, limits)
+}
+
+func (e *blockingEngine) Stop() error {
+	e.stops.Add(1)
+	e.stop <- struct{}{}
+	return nil
+}
+
+func (e *blockingEngine) Close() error {
+	e.closed.Store(true)
+	return e.FakeEngine.Close()
+}
+
+// countingFactory hands out the given engines in order and counts how many were asked for
+func countingFactory(engines ...EngineBackend) (EngineFactory, *atomic.Int32) {
+	var created atomic.Int32
+	return func() (EngineBackend, error) {
+		n := int(created.Add(1))
+		if n > len(engines) {
+			return NewFakeEngine(), nil
+		}
+		return engines[n-1], nil
+	}, &created
+}
+
+func startPosition(t *testing.T) *Position {
+	t.Helper()
+
+	game, err := newGame(Standard, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return game.Position()
+}
+
+func TestEnginePoolStopsAbandonedSearches(t *testing.T) {
+	engine := newBlockingEngine()
+	factory, created := countingFactory(engine)
+	pool := NewEnginePool(factory, 1, prometheus.NewRegistry())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := pool.Search(ctx, 0, startPosition(t), SearchLimits{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Search() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// the same engine comes back to the pool once it has answered the stop
+	select {
+	case idle := <-pool.idle:
+		if idle != engine {
+			t.Fatal("a different engine was returned to the pool")
+		}
+		pool.idle <- idle
+	case <-time.After(time.Second):
+		t.Fatal("the stopped engine never came back to the pool")
+	}
+
+	if engine.stops.Load() != 1 || engine.closed.Load() || created.Load() != 1 {
+		t.Fatalf("engine was stopped %d times, closed %v and %d engines were started", engine.stops.Load(), engine.closed.Load(), created.Load())
+	}
+}
+
+func TestEnginePoolRelease(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantReplace bool
+	}{
+		{"no legal moves", ErrNoBestMove, false},
+		{"variant the engine can't play", fmt.Errorf("%w: engine can't play crazyhouse", ErrUnsupportedVariant), false},
+		{"broken pipe", fmt.Errorf("%w: io: read/write on closed pipe", ErrEngineFailed), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newBlockingEngine()
+			engine.err = tt.err
+			factory, created := countingFactory(engine)
+			pool := NewEnginePool(factory, 1, prometheus.NewRegistry())
+
+			if _, err := pool.Search(context.Background(), 0, startPosition(t), SearchLimits{}); !errors.Is(err, tt.err) {
+				t.Fatalf("Search() error = %v, want %v", err, tt.err)
+			}
+
+			select {
+			case idle := <-pool.idle:
+				if replaced := idle != engine; replaced != tt.wantReplace {
+					t.Fatalf("engine replaced = %v, want %v", replaced, tt.wantReplace)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("no engine came back to the pool")
+			}
+
+			if engine.closed.Load() != tt.wantReplace {
+				t.Fatalf("engine closed = %v, want %v", engine.closed.Load(), tt.wantReplace)
+			}
+
+			want := int32(1)
+			if tt.wantReplace {
+				want = 2
+			}
+
+			if created.Load() != want {
+				t.Fatalf("%d engines were started, want %d", created.Load(), want)
+			}
+		})
+	}
+}
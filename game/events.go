@@ -13,17 +13,48 @@ type Event struct {
 type EventHandler func(event Event, c *Client) error
 
 const (
+	EventAbort                 = "abort"
+	EventAcceptDraw            = "accept_draw"
+	EventAcceptSeek            = "accept_seek"
+	EventCancelSeek            = "cancel_seek"
 	EventAssignedMatch         = "assigned_match"
+	EventBerserk               = "berserk"
+	EventBerserked             = "berserked"
 	EventClockUpdate           = "clock_update"
+	EventDeclineDraw           = "decline_draw"
+	EventDrawDeclined          = "draw_declined"
+	EventDrawOffered           = "draw_offered"
+	EventEvaluation            = "evaluation"
+	EventHint                  = "hint"
 	EventNewEngineMatchRequest = "new_engine_match"
 	EventJoinMatchRequest      = "join_match"
+	EventJoinMatchByIdRequest  = "join_match_by_id"
+	EventLobbyMatchEnded       = "lobby_match_ended"
+	EventLobbyMatchStarted     = "lobby_match_started"
+	EventLobbySeekClosed       = "lobby_seek_closed"
+	EventLobbySeekOpened       = "lobby_seek_opened"
+	EventLobbySnapshot         = "lobby_snapshot"
 	EventMakeMove              = "make_move"
 	EventMatchOver             = "match_over"
 	EventMatchStarted          = "match_started"
 	EventMatchError            = "match_error"
+	EventMatchState            = "match_state"
 	EventNewMatchRequest       = "new_match"
+	EventOfferDraw             = "offer_draw"
+	EventOpponentDisconnected  = "opponent_disconnected"
+	EventOpponentReconnected   = "opponent_reconnected"
+	EventPrivateMatchCreated   = "private_match_created"
 	EventPropagateMove         = "propagate_move"
 	EventPropagatePosition     = "propagate_position"
+	EventRequestHint           = "request_hint"
+	EventResign                = "resign"
+	EventSeekAccepted          = "seek_accepted"
+	EventSeekCancelled         = "seek_cancelled"
+	EventSeekCreated           = "seek_created"
+	EventSeekExpired           = "seek_expired"
+	EventSpectateMatch         = "spectate_match"
+	EventTakeback              = "takeback"
+	EventToggleEvaluation      = "toggle_evaluation"
 )
 
 type ClockUpdateEvent struct {
@@ -31,17 +62,118 @@ type ClockUpdateEvent struct {
 	TimeRemaining string `json:"time_remaining"`
 }
 
+type BerserkEvent struct {
+	PlayerColor string `json:"player"`
+}
+
+type DrawOfferEvent struct {
+	PlayerColor string `json:"player"`
+}
+
+// JoinMatchEvent seeks a rated opponent, Color is the pieces wanted and is left empty or random for either
 type JoinMatchEvent struct {
 	TimeControl TimeControl `json:"time_control"`
+	Variant     string      `json:"variant"`
+	Color       string      `json:"color,omitempty"`
+}
+
+type JoinMatchByIdEvent struct {
+	ID MatchId `json:"match_id"`
+}
+
+// NewMatchEvent creates a private match, FEN optionally sets up the starting position
+type NewMatchEvent struct {
+	TimeControl TimeControl `json:"time_control"`
+	Variant     string      `json:"variant"`
+	FEN         string      `json:"fen"`
+}
+
+type PrivateMatchCreatedEvent struct {
+	ID      MatchId `json:"match_id"`
+	JoinURL string  `json:"join_url"`
+}
+
+type MatchStateEvent struct {
+	ID          MatchId     `json:"match_id"`
+	TimeControl TimeControl `json:"time_control"`
+	Variant     string      `json:"variant"`
+	FEN         string      `json:"fen"`
+	Pockets     *Pockets    `json:"pockets,omitempty"`
+	Moves       []string    `json:"moves"`
+	Turn        PieceColor  `json:"turn"`
+	LightClock  string      `json:"light_clock"`
+	DarkClock   string      `json:"dark_clock"`
+}
+
+type SeekEvent struct {
+	TimeControl TimeControl `json:"time_control"`
+	Variant     string      `json:"variant"`
+	Rating      string      `json:"rating"`
+}
+
+type LobbySnapshotEvent struct {
+	Seeks   []LobbySeek  `json:"seeks"`
+	Matches []LobbyMatch `json:"matches"`
+}
+
+type LobbySeekClosedEvent struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
 }
 
+type LobbyMatchEndedEvent struct {
+	ID MatchId `json:"match_id"`
+}
+
+type AcceptSeekEvent struct {
+	ID string `json:"seek_id"`
+}
+
+type SeekAcceptedEvent struct {
+	ID MatchId `json:"match_id"`
+}
+
+// EvaluationEvent is the engines view of the position it just moved from, scores are from lights point of view
+type EvaluationEvent struct {
+	PlayerColor string   `json:"player"`
+	CP          int      `json:"cp"`
+	Mate        int      `json:"mate"`
+	Depth       int      `json:"depth"`
+	PV          []string `json:"pv"`
+}
+
+// HintEvent carries the suggested move in SAN for display and UCI for highlighting on the board
+type HintEvent struct {
+	Move string `json:"move"`
+	UCI  string `json:"uci"`
+}
+
+type ToggleEvaluationEvent struct {
+	Enabled bool `json:"enabled"`
+}
+
+type MatchStartedEvent struct {
+	LightRating string `json:"light_rating"`
+	DarkRating  string `json:"dark_rating"`
+}
+
+type OpponentConnectionEvent struct {
+	PlayerColor string `json:"player"`
+	GracePeriod string `json:"grace_period,omitempty"`
+}
+
+// MakeMoveEvent moves are SAN or UCI, crazyhouse drops are written as the piece letter and square e.g. P@e4
 type MakeMoveEvent struct {
 	Move string `json:"move"`
 	//Player string `json:"player"`
 }
 
 type NewEngineMatchEvent struct {
-	ELO ELO `json:"elo"`
+	ELO         ELO         `json:"elo"`
+	TimeControl TimeControl `json:"time_control"`
+	Evaluation  bool        `json:"evaluation"`
+	Variant     string      `json:"variant"`
+	FEN         string      `json:"fen"`
 }
 
 type PropagateMoveEvent struct {
@@ -50,8 +182,13 @@ type PropagateMoveEvent struct {
 }
 
 type PropagatePositionEvent struct {
-	PlayerColor string `json:"player"`
-	FEN         string `json:"fen"`
+	PlayerColor string   `json:"player"`
+	FEN         string   `json:"fen"`
+	Pockets     *Pockets `json:"pockets,omitempty"`
+}
+
+type SpectateMatchEvent struct {
+	ID MatchId `json:"match_id"`
 }
 
 type ErrorEvent struct {
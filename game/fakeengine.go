@@ -0,0 +1,99 @@
+package game
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// FakeEngine is a deterministic in-process EngineBackend for tests, it plays its scripted moves
+// in order while they're legal and otherwise the first legal move in UCI order
+type FakeEngine struct {
+	script []string
+	next   int
+	elo    ELO
+	closed bool
+	sync.Mutex
+}
+
+func NewFakeEngine(script ...string) *FakeEngine {
+	return &FakeEngine{script: script}
+}
+
+// FakeEngineFactory hands every match its own FakeEngine playing the same script
+func FakeEngineFactory(script ...string) EngineFactory {
+	return func() (EngineBackend, error) {
+		return NewFakeEngine(script...), nil
+	}
+}
+
+func (e *FakeEngine) NewGame() error {
+	e.Lock()
+	defer e.Unlock()
+
+	e.next = 0
+
+	return nil
+}
+
+func (e *FakeEngine) SetStrength(elo ELO) error {
+	e.Lock()
+	defer e.Unlock()
+
+	e.elo = elo
+
+	return nil
+}
+
+func (e *FakeEngine) ELO() ELO {
+	e.Lock()
+	defer e.Unlock()
+
+	return e.elo
+}
+
+func (e *FakeEngine) BestMove(position *Position, limits SearchLimits) (SearchResult, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.closed {
+		return SearchResult{}, errors.New("fake engine is closed")
+	}
+
+	moves := position.LegalMoves()
+	if len(moves) == 0 {
+		return SearchResult{}, ErrNoBestMove
+	}
+
+	if e.next < len(e.script) {
+		scripted := e.script[e.next]
+		e.next++
+
+		for _, move := range moves {
+			if position.UCI(move) == scripted {
+				return SearchResult{BestMove: move, PV: []*Move{move}}, nil
+			}
+		}
+	}
+
+	move := slices.MinFunc(moves, func(a, b *Move) int {
+		return cmp.Compare(position.UCI(a), position.UCI(b))
+	})
+
+	return SearchResult{BestMove: move, PV: []*Move{move}}, nil
+}
+
+// Stop has nothing to cut short, the fake engine answers straight away
+func (e *FakeEngine) Stop() error {
+	return nil
+}
+
+func (e *FakeEngine) Close() error {
+	e.Lock()
+	defer e.Unlock()
+
+	e.closed = true
+
+	return nil
+}
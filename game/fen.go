@@ -0,0 +1,106 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+var ErrInvalidStartingPosition = errors.New("invalid starting position")
+
+// newGame starts a game of variant from fen, an empty fen is the variants own starting position
+func newGame(variant Variant, fen string) (*Game, error) {
+	fen = strings.TrimSpace(fen)
+	custom := fen != ""
+	if !custom {
+		fen = variant.StartingFEN()
+	}
+
+	start, err := parsePosition(fen, variant)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStartingPosition, err)
+	}
+
+	if custom {
+		if err := checkStartingPosition(start); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStartingPosition, err)
+		}
+	}
+
+	game := newGameFromPosition(start)
+	game.custom = custom
+
+	return game, nil
+}
+
+// checkStartingPosition catches positions that parse but can't be played, engines tend to crash on them
+func checkStartingPosition(position *Position) error {
+	kings := map[chess.Color]int{}
+	for _, piece := range position.Board().SquareMap() {
+		if piece.Type() == chess.King {
+			kings[piece.Color()]++
+		}
+	}
+
+	if kings[chess.White] != 1 || kings[chess.Black] != 1 {
+		return errors.New("each side needs exactly one king")
+	}
+
+	if position.Status() != chess.NoMethod {
+		return errors.New("the game is already over")
+	}
+
+	if kingCapturable(position) {
+		return errors.New("the side not to move is in check")
+	}
+
+	return nil
+}
+
+// kingCapturable checks if the side that just moved left its king in check
+func kingCapturable(position *Position) bool {
+	board := position.Board()
+
+	king := kingSquare(board, position.Turn().Other())
+	return king != chess.NoSquare && attacked(board, king, position.Turn())
+}
+
+// startingFEN is the fen a game was set up from, empty for the variants usual starting position
+func startingFEN(game *Game) string {
+	fen := game.Positions()[0].String()
+	if game.Variant() != Chess960 && fen == game.Variant().StartingFEN() {
+		return ""
+	}
+
+	return fen
+}
+
+// fullMoveNumber reads the move number field of a position since chess.Position doesn't expose it
+func fullMoveNumber(position *Position) int {
+	fields := strings.Fields(position.pos.String())
+	if len(fields) < 6 {
+		return 1
+	}
+
+	n, err := strconv.Atoi(fields[5])
+	if err != nil || n < 1 {
+		return 1
+	}
+
+	return n
+}
+
+// replayGame rebuilds a game from its starting position and moves, a game has no way to undo a move in place
+func replayGame(start *Position, moves []*Move) (*Game, error) {
+	game := newGameFromPosition(start)
+	for _, move := range moves {
+		if err := game.Move(move); err != nil {
+			return nil, err
+		}
+	}
+
+	return game, nil
+}
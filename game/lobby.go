@@ -0,0 +1,263 @@
+package game
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// lobby clients can fall behind on a busy lobby, their buffer is bigger than a players so updates aren't dropped
+var lobbyBufferSize = 64
+
+var ErrOwnSeek = errors.New("can't accept your own seek")
+
+// reasons a seek leaves the lobby
+const (
+	SeekAccepted  = "accepted"
+	SeekCancelled = "cancelled"
+	SeekExpired   = "expired"
+)
+
+// LobbySeek is an open seek as the lobby shows it
+type LobbySeek struct {
+	ID          string      `json:"id"`
+	Creator     string      `json:"creator"`
+	Rating      string      `json:"rating"`
+	TimeControl TimeControl `json:"time_control"`
+	Variant     string      `json:"variant"`
+	Color       string      `json:"color"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// LobbyMatch is a running game as the lobby shows it
+type LobbyMatch struct {
+	ID          MatchId     `json:"match_id"`
+	TimeControl TimeControl `json:"time_control"`
+	Variant     string      `json:"variant"`
+	Light       string      `json:"light"`
+	LightRating string      `json:"light_rating"`
+	Dark        string      `json:"dark"`
+	DarkRating  string      `json:"dark_rating"`
+}
+
+// lobby is the publish/subscribe side of matchmaking. It keeps its own listing of open seeks and running matches
+// which is changed and published under one lock, so a new subscriber's snapshot is never older than the updates
+// queued after it. mu is never held while taking another lock so it can be published to from under any of them
+type lobby struct {
+	mu          sync.Mutex
+	seeks       map[string]LobbySeek
+	matches     map[MatchId]LobbyMatch
+	subscribers map[*Client]bool
+}
+
+func newLobby() *lobby {
+	return &lobby{
+		seeks:       make(map[string]LobbySeek),
+		matches:     make(map[MatchId]LobbyMatch),
+		subscribers: make(map[*Client]bool),
+	}
+}
+
+// subscribe sends the client the lobby as it is and then every change after it
+func (l *lobby) subscribe(c *Client) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	snapshot := LobbySnapshotEvent{
+		Seeks:   slices.Collect(maps.Values(l.seeks)),
+		Matches: slices.Collect(maps.Values(l.matches)),
+	}
+	slices.SortFunc(snapshot.Seeks, func(a, b LobbySeek) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	slices.SortFunc(snapshot.Matches, func(a, b LobbyMatch) int { return cmp.Compare(a.ID, b.ID) })
+
+	event, err := NewOutgoingEvent(EventLobbySnapshot, snapshot)
+	if err != nil {
+		return err
+	}
+
+	c.egress <- event
+	l.subscribers[c] = true
+
+	return nil
+}
+
+func (l *lobby) unsubscribe(c *Client) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.subscribers, c)
+}
+
+func (l *lobby) seekOpened(s *seek) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info := s.lobbySeek()
+	l.seeks[info.ID] = info
+	l.publish(EventLobbySeekOpened, info)
+}
+
+func (l *lobby) seekClosed(s *seek, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.seeks[s.id]; !ok {
+		return
+	}
+
+	delete(l.seeks, s.id)
+	l.publish(EventLobbySeekClosed, LobbySeekClosedEvent{ID: s.id, Reason: reason})
+}
+
+func (l *lobby) matchStarted(m *Match) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info := LobbyMatch{
+		ID:          m.ID,
+		TimeControl: m.TimeControl,
+		Variant:     m.Game.Variant().String(),
+		Light:       m.LightPlayer.displayName(),
+		LightRating: m.LightPlayer.Rating.String(),
+		Dark:        m.DarkPlayer.displayName(),
+		DarkRating:  m.DarkPlayer.Rating.String(),
+	}
+
+	l.matches[info.ID] = info
+	l.publish(EventLobbyMatchStarted, info)
+}
+
+func (l *lobby) matchEnded(id MatchId) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.matches[id]; !ok {
+		return
+	}
+
+	delete(l.matches, id)
+	l.publish(EventLobbyMatchEnded, LobbyMatchEndedEvent{ID: id})
+}
+
+// publish never blocks, callers must hold mu
+func (l *lobby) publish(eventType string, payload any) {
+	event, err := NewOutgoingEvent(eventType, payload)
+	if err != nil {
+		return
+	}
+
+	for subscriber := range l.subscribers {
+		select {
+		case subscriber.egress <- event:
+		default:
+		}
+	}
+}
+
+// ServeLobbyWS streams the open seeks and running matches, lobby clients can accept a seek but don't play from the lobby
+func (m *MatchmakingManager) ServeLobbyWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		m.logger.Error(err.Error())
+		return
+	}
+
+	client := NewClient(conn, m, UserIdFromContext(r.Context()))
+	client.egress = make(chan Event, lobbyBufferSize)
+
+	m.addClient(client)
+	m.loadClient(client)
+
+	if err := m.lobby.subscribe(client); err != nil {
+		m.logger.Error("failed to subscribe to the lobby", "error", err)
+	}
+
+	go client.readEvents(m.logger)
+	go client.writeEvents(m.logger)
+}
+
+// acceptSeekHandler seats the seeker in a new match and holds the other seat for the client, who takes it
+// by going to the match page
+func (m *MatchmakingManager) acceptSeekHandler(event Event, c *Client) error {
+	m.logger.Info("accept seek handler", "event", event, "client", c)
+
+	var acceptEvent AcceptSeekEvent
+	if err := json.Unmarshal(event.Payload, &acceptEvent); err != nil {
+		return fmt.Errorf("bad payload in request: %v", err)
+	}
+
+	if c.UserId() == "" {
+		return errors.New("only signed in players can accept a seek")
+	}
+
+	if c.matchInfo().ID != "" {
+		return errors.New("already in a match")
+	}
+
+	s, err := m.takeSeek(acceptEvent.ID, c.UserId())
+	if err != nil {
+		return err
+	}
+
+	matchId, err := m.startAcceptedSeek(s, c)
+	if err != nil {
+		return err
+	}
+
+	outgoingEvent, err := NewOutgoingEvent(EventSeekAccepted, SeekAcceptedEvent{ID: matchId})
+	if err != nil {
+		return err
+	}
+
+	c.egress <- outgoingEvent
+
+	return nil
+}
+
+// startAcceptedSeek gives the seeker their preferred pieces, or a random side if they don't mind
+func (m *MatchmakingManager) startAcceptedSeek(s *seek, c *Client) (MatchId, error) {
+	m.matchesMu.Lock()
+	defer m.matchesMu.Unlock()
+
+	m.clientsMu.RLock()
+	_, seekerConnected := m.clients[s.client]
+	m.clientsMu.RUnlock()
+
+	if !seekerConnected {
+		return "", ErrNoSeek
+	}
+
+	game, err := newGame(s.variant, "")
+	if err != nil {
+		return "", err
+	}
+
+	pieces := s.color
+	if pieces == NoColor {
+		pieces = PieceColor(rand.IntN(2))
+	}
+
+	match := m.buildMatch(s.timeControl, game)
+	accepter := &Player{UserId: c.UserId(), Name: c.name, Rating: c.Rating(RatingCategory(s.variant, s.timeControl))}
+	if pieces == Light {
+		match.DarkPlayer = accepter
+	} else {
+		match.LightPlayer = accepter
+	}
+
+	matchId := m.addMatch(match)
+
+	s.client.setMatchInfo(NewClientMatchInfo(matchId, Matchmaking, s.timeControl, 0, pieces))
+	if err := m.addClientToMatch(s.client); err != nil {
+		return "", err
+	}
+
+	return matchId, nil
+}
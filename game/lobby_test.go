@@ -0,0 +1,171 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func newTestMatchmakingManager(t *testing.T) *MatchmakingManager {
+	t.Helper()
+
+	return NewMatchmakingManager(context.Background(), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+}
+
+func lobbyTestSeek(id string) *seek {
+	s := testSeek("user-"+id, 1500, NoColor, time.Now())
+	s.id, s.timeControl = id, NewTimeControl(3*time.Minute, 0, NoDelay)
+
+	return s
+}
+
+func TestLobbySnapshotThenUpdates(t *testing.T) {
+	l := newLobby()
+	l.seekOpened(lobbyTestSeek("before"))
+
+	c := &Client{egress: make(chan Event, lobbyBufferSize)}
+	if err := l.subscribe(c); err != nil {
+		t.Fatal(err)
+	}
+
+	l.seekOpened(lobbyTestSeek("after"))
+	l.seekClosed(lobbyTestSeek("before"), SeekAccepted)
+	// closing a seek the lobby doesn't list isn't published
+	l.seekClosed(lobbyTestSeek("unknown"), SeekCancelled)
+
+	var snapshot LobbySnapshotEvent
+	expectEvent(t, c, EventLobbySnapshot, &snapshot)
+	if len(snapshot.Seeks) != 1 || snapshot.Seeks[0].ID != "before" {
+		t.Fatalf("snapshot = %+v, want only the seek opened before subscribing", snapshot)
+	}
+
+	var opened LobbySeek
+	expectEvent(t, c, EventLobbySeekOpened, &opened)
+	if opened.ID != "after" {
+		t.Fatalf("opened %s, want after", opened.ID)
+	}
+
+	var closed LobbySeekClosedEvent
+	expectEvent(t, c, EventLobbySeekClosed, &closed)
+	if closed.ID != "before" || closed.Reason != SeekAccepted {
+		t.Fatalf("closed %+v, want before accepted", closed)
+	}
+
+	select {
+	case event := <-c.egress:
+		t.Fatalf("unexpected %s event %s", event.Type, event.Payload)
+	default:
+	}
+}
+
+func TestLobbySubscribeWhilePublishing(t *testing.T) {
+	l := newLobby()
+	seeks := lobbyBufferSize - 1
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range seeks {
+			l.seekOpened(lobbyTestSeek(fmt.Sprint(i)))
+		}
+	}()
+
+	c := &Client{egress: make(chan Event, lobbyBufferSize)}
+	if err := l.subscribe(c); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	// every seek is either in the snapshot or published after it, never both and never neither
+	var snapshot LobbySnapshotEvent
+	expectEvent(t, c, EventLobbySnapshot, &snapshot)
+
+	seen := make(map[string]int)
+	for _, s := range snapshot.Seeks {
+		seen[s.ID]++
+	}
+
+	for len(c.egress) > 0 {
+		var opened LobbySeek
+		expectEvent(t, c, EventLobbySeekOpened, &opened)
+		seen[opened.ID]++
+	}
+
+	for i := range seeks {
+		if n := seen[fmt.Sprint(i)]; n != 1 {
+			t.Fatalf("seek %d seen %d times", i, n)
+		}
+	}
+}
+
+func TestAcceptSeekHandler(t *testing.T) {
+	m := newTestMatchmakingManager(t)
+	timeControl := NewTimeControl(3*time.Minute, 0, NoDelay)
+
+	seeker := newTestClient(m, "alice")
+	m.addClient(seeker)
+
+	s := newSeek(seeker, Standard, timeControl, Light)
+	if _, err := m.addSeek(s); err != nil {
+		t.Fatal(err)
+	}
+
+	accept := newEvent(t, EventAcceptSeek, AcceptSeekEvent{ID: s.id})
+
+	playing := newTestClient(m, "bob")
+	playing.setMatchInfo(ClientMatchInfo{ID: "already-playing"})
+	m.addClient(playing)
+
+	for _, tt := range []struct {
+		name    string
+		client  *Client
+		wantErr error
+	}{
+		{"anonymous", newTestClient(m, ""), nil},
+		{"already in a match", playing, nil},
+		{"own seek", seeker, ErrOwnSeek},
+	} {
+		err := m.acceptSeekHandler(accept, tt.client)
+		if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+			t.Fatalf("%s: acceptSeekHandler error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	m.seeksMu.Lock()
+	stillOpen := m.seekers[seeker] == s
+	m.seeksMu.Unlock()
+	if !stillOpen {
+		t.Fatal("a rejected accept took the seek")
+	}
+
+	accepter := newTestClient(m, "carol")
+	m.addClient(accepter)
+
+	if err := m.acceptSeekHandler(accept, accepter); err != nil {
+		t.Fatal(err)
+	}
+
+	var accepted SeekAcceptedEvent
+	expectEvent(t, accepter, EventSeekAccepted, &accepted)
+
+	var assigned ClientMatchInfo
+	expectEvent(t, seeker, EventAssignedMatch, &assigned)
+	if assigned.ID != accepted.ID || assigned.Pieces != Light || seeker.matchInfo() != assigned {
+		t.Fatalf("seeker assigned %+v, holds %+v, want light in %s", assigned, seeker.matchInfo(), accepted.ID)
+	}
+
+	m.matchesMu.Lock()
+	match := m.matches[timeControl][accepted.ID]
+	m.matchesMu.Unlock()
+	if match == nil || match.LightPlayer.UserId != "alice" || match.DarkPlayer.UserId != "carol" {
+		t.Fatalf("match = %+v, want alice against carol", match)
+	}
+
+	if err := m.acceptSeekHandler(accept, newTestClient(m, "dave")); !errors.Is(err, ErrNoSeek) {
+		t.Fatalf("accepting a taken seek error = %v, want ErrNoSeek", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/michaelgov-ctrl/bad-chess/internal/models"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -11,12 +12,21 @@ type Manager interface {
 	ServeWS(w http.ResponseWriter, r *http.Request)
 	addClient(c *Client)
 	removeClient(c *Client)
+	disconnectClient(c *Client)
 	routeEvent(req Event, c *Client) error
 }
 
 type ManagerOptions struct {
-	logger   *slog.Logger
-	registry *prometheus.Registry
+	logger      *slog.Logger
+	registry    *prometheus.Registry
+	matchStore  models.MatchStore
+	ratingStore models.RatingStore
+	userStore   models.UserStore
+	analyzer    *Analyzer
+
+	engineConfig   EngineConfig
+	engineFactory  EngineFactory
+	enginePoolSize int
 }
 
 type ManagerOption func(*ManagerOptions)
@@ -32,3 +42,53 @@ func WithMetricsRegistry(registry *prometheus.Registry) ManagerOption {
 		m.registry = registry
 	}
 }
+
+// WithMatchStore records every finished match, without one finished matches are only logged
+func WithMatchStore(store models.MatchStore) ManagerOption {
+	return func(m *ManagerOptions) {
+		m.matchStore = store
+	}
+}
+
+// WithEngineConfig sets the engine binary, its options and the ELO ladder offered for engine matches,
+// matchmaking only uses its variants to know which finished games can be analyzed
+func WithEngineConfig(cfg EngineConfig) ManagerOption {
+	return func(m *ManagerOptions) {
+		m.engineConfig = cfg
+	}
+}
+
+// WithEngineFactory swaps the engine used for engine matches, e.g. for a FakeEngine in tests
+func WithEngineFactory(factory EngineFactory) ManagerOption {
+	return func(m *ManagerOptions) {
+		m.engineFactory = factory
+	}
+}
+
+// WithEnginePoolSize caps how many engine processes run at once, searches queue when they're all busy
+func WithEnginePoolSize(size int) ManagerOption {
+	return func(m *ManagerOptions) {
+		m.enginePoolSize = size
+	}
+}
+
+// WithRatingStore rates finished matches, without one every player stays at the starting rating
+func WithRatingStore(store models.RatingStore) ManagerOption {
+	return func(m *ManagerOptions) {
+		m.ratingStore = store
+	}
+}
+
+// WithUserStore shows players by username in the lobby, without one everyone is anonymous
+func WithUserStore(store models.UserStore) ManagerOption {
+	return func(m *ManagerOptions) {
+		m.userStore = store
+	}
+}
+
+// WithAnalyzer queues every finished match for engine analysis
+func WithAnalyzer(analyzer *Analyzer) ManagerOption {
+	return func(m *ManagerOptions) {
+		m.analyzer = analyzer
+	}
+}
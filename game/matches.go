@@ -1,27 +1,37 @@
 package game
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"math/rand"
-	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/michaelgov-ctrl/bad-chess/internal/rating"
 	"github.com/notnil/chess"
-	"github.com/notnil/chess/uci"
 )
 
 var (
 	SupportedTimeControls = map[TimeControl]bool{
-		TimeControl(1 * float64(time.Minute)):  true, // 1 minute
-		TimeControl(3 * float64(time.Minute)):  true, // 3 minutes
-		TimeControl(5 * float64(time.Minute)):  true, // 5 minutes
-		TimeControl(10 * float64(time.Minute)): true, // 10 minutes
-		TimeControl(20 * float64(time.Minute)): true, // 20 minutes
+		NewTimeControl(1*time.Minute, 0, NoDelay):                        true, // 1+0
+		NewTimeControl(2*time.Minute, 1*time.Second, FischerIncrement):   true, // 2+1
+		NewTimeControl(3*time.Minute, 0, NoDelay):                        true, // 3+0
+		NewTimeControl(3*time.Minute, 2*time.Second, FischerIncrement):   true, // 3+2
+		NewTimeControl(5*time.Minute, 0, NoDelay):                        true, // 5+0
+		NewTimeControl(5*time.Minute, 3*time.Second, FischerIncrement):   true, // 5+3
+		NewTimeControl(5*time.Minute, 3*time.Second, BronsteinDelay):     true, // 5b3
+		NewTimeControl(5*time.Minute, 3*time.Second, SimpleDelay):        true, // 5d3
+		NewTimeControl(10*time.Minute, 0, NoDelay):                       true, // 10+0
+		NewTimeControl(10*time.Minute, 5*time.Second, FischerIncrement):  true, // 10+5
+		NewTimeControl(15*time.Minute, 10*time.Second, FischerIncrement): true, // 15+10
+		NewTimeControl(20*time.Minute, 0, NoDelay):                       true, // 20+0
 	}
 
+	// SupportedEngineELOs is the default ladder, an engine config can replace it
 	SupportedEngineELOs = map[ELO]bool{
 		600:  true,
 		1000: true,
@@ -31,6 +41,13 @@ var (
 	}
 
 	ErrNonExistentPiece = errors.New("non-existent piece color")
+
+	// private matches wait on a shared link rather than the matchmaking pool so they get longer to fill
+	PrivateMatchWaitTime = 10 * time.Minute
+	PrivateMatchJoinPath = "/matches?id=%s"
+
+	// ReconnectGracePeriod is how long a dropped player has to come back before forfeiting
+	ReconnectGracePeriod = 30 * time.Second
 )
 
 type MatchId string
@@ -49,7 +66,14 @@ const (
 	Draw     = "1/2-1/2"
 )
 
-type TimeControl time.Duration
+const (
+	MethodAbandonment = "abandonment"
+	MethodAborted     = "aborted"
+	MethodAgreement   = "agreement"
+	MethodFlagged     = "flagged"
+	MethodForfeit     = "forfeit"
+	MethodResignation = "resignation"
+)
 
 type ELO = int
 
@@ -61,9 +85,49 @@ type EngineMatchList map[MatchId]*EngineMatch
 
 type ELOMatchList map[ELO]EngineMatchList
 
+// a players seat is bound to their session user so a new connection can take it back
 type Player struct {
 	Client *Client
 	Clock  *Clock
+	UserId string
+	Name   string
+	Rating rating.Rating
+	// Berserked players gave up half their clock for a bigger reward in arena tournaments
+	Berserked bool
+
+	disconnectedAt time.Time
+}
+
+func NewPlayer(c *Client) *Player {
+	return &Player{Client: c, UserId: c.UserId(), Name: c.name}
+}
+
+// displayName is what others see of the player, anonymous players have no name
+func (p *Player) displayName() string {
+	if p == nil || p.Name == "" {
+		return "anonymous"
+	}
+
+	return p.Name
+}
+
+// abandoned is true once a player has been gone for longer than the grace period
+func (p *Player) abandoned() bool {
+	if p == nil || p.Client != nil || p.disconnectedAt.IsZero() {
+		return false
+	}
+
+	return time.Since(p.disconnectedAt) > ReconnectGracePeriod
+}
+
+func (p *Player) disconnect() {
+	p.Client = nil
+	p.disconnectedAt = time.Now()
+}
+
+func (p *Player) reconnect(c *Client) {
+	p.Client = c
+	p.disconnectedAt = time.Time{}
 }
 
 type MatchState int
@@ -81,53 +145,54 @@ const (
 	Matchmaking
 )
 
-// TODO: Spectators  []*Client
+func (t MatchType) String() string {
+	switch t {
+	case Engine:
+		return "engine"
+	case Matchmaking:
+		return "matchmaking"
+	default:
+		return "unknown"
+	}
+}
+
 // TODO: evaluate passing the application logger to each match for logging
 type Match struct {
-	ID          MatchId
-	TimeControl TimeControl
-	LightPlayer *Player
-	DarkPlayer  *Player
-	Game        *chess.Game
-	Turn        PieceColor
-	State       MatchState
-	Logger      *slog.Logger
-}
+	ID           MatchId
+	TimeControl  TimeControl
+	LightPlayer  *Player
+	DarkPlayer   *Player
+	Spectators   ClientList
+	spectatorsMu sync.RWMutex
+	Private      bool
+	Scheduled    bool
+	Game         *Game
+	Turn         PieceColor
+	State        MatchState
+	StartedAt    time.Time
+	Logger       *slog.Logger
 
-func (tc TimeControl) MarshalJSON() ([]byte, error) {
-	return json.Marshal(time.Duration(tc).String())
+	// BerserkAllowed lets players halve their own clock before their first move
+	BerserkAllowed bool
+
+	drawOfferedBy PieceColor
+	aborted       bool
+	// the movers time remaining after each move, used for the PGN clock comments
+	moveClocks []time.Duration
+	outcome    MatchOutcome
+	// results is where a scheduled match reports its outcome
+	results chan<- MatchOutcome
+	// darkBase gives dark a different starting time from the time control, e.g. for an armageddon game
+	darkBase time.Duration
 }
 
-func (tc *TimeControl) UnmarshalJSON(b []byte) error {
-	var v any
-	if err := json.Unmarshal(b, &v); err != nil {
-		return err
-	}
-
-	switch value := v.(type) {
-	case string:
-		tmp, err := time.ParseDuration(value)
-		if err != nil {
-			return err
-		}
-
-		if !SupportedTimeControls[TimeControl(tmp)] {
-			return errors.New("unsupported time control")
-		}
-
-		*tc = TimeControl(tmp)
-		return nil
-	default:
-		return errors.New("invalid time control")
+// timeControl is the time control a side plays with, which is only different for dark when darkBase is set
+func (m *Match) timeControl(pieces PieceColor) TimeControl {
+	if pieces == Dark && m.darkBase > 0 {
+		return NewTimeControl(m.darkBase, m.TimeControl.Increment, m.TimeControl.Mode)
 	}
-}
 
-func (tc TimeControl) ToDuration() time.Duration {
-	return time.Duration(tc)
-}
-
-func (tc TimeControl) String() string {
-	return tc.ToDuration().String()
+	return m.TimeControl
 }
 
 func (m *Match) ClientPieceColor(client *Client) PieceColor {
@@ -142,18 +207,31 @@ func (m *Match) ClientPieceColor(client *Client) PieceColor {
 	return NoColor
 }
 
+func (m *Match) player(pieces PieceColor) *Player {
+	switch pieces {
+	case Light:
+		return m.LightPlayer
+	case Dark:
+		return m.DarkPlayer
+	default:
+		return nil
+	}
+}
+
 type MatchOutcome struct {
-	ID          MatchId
-	TimeControl TimeControl
-	Outcome     string
-	Method      string
+	ID             MatchId     `json:"match_id"`
+	TimeControl    TimeControl `json:"time_control"`
+	Outcome        string      `json:"outcome"`
+	Method         string      `json:"method"`
+	LightBerserked bool        `json:"light_berserked,omitempty"`
+	DarkBerserked  bool        `json:"dark_berserked,omitempty"`
 }
 
 func (m *Match) Start(cleanupChan chan<- MatchOutcome) error {
 	var outcome = MatchOutcome{ID: m.ID, TimeControl: m.TimeControl}
 	if m.LightPlayer == nil || m.DarkPlayer == nil {
 		outcome.Outcome = "0-0"
-		outcome.Method = "abandonment"
+		outcome.Method = MethodAbandonment
 		cleanupChan <- outcome
 		return fmt.Errorf("failed to start match")
 	}
@@ -161,13 +239,29 @@ func (m *Match) Start(cleanupChan chan<- MatchOutcome) error {
 	go m.notifyWhenOver(cleanupChan)
 
 	m.State = Started
-	m.MessagePlayers(Event{Type: EventMatchStarted}, Light, Dark)
+	m.StartedAt = time.Now()
+
+	outgoingEvent, err := NewOutgoingEvent(EventMatchStarted, MatchStartedEvent{
+		LightRating: m.LightPlayer.Rating.String(),
+		DarkRating:  m.DarkPlayer.Rating.String(),
+	})
+	if err != nil {
+		outgoingEvent = Event{Type: EventMatchStarted}
+	}
+	m.MessagePlayers(outgoingEvent, Light, Dark)
 
-	m.LightPlayer.Clock = NewClock(m.TimeControl)
-	m.DarkPlayer.Clock = NewClock(m.TimeControl)
-	m.DarkPlayer.Clock.Pause()
+	// only the side to move has its clock running, which isn't always light when starting from a fen
+	m.LightPlayer.Clock, m.DarkPlayer.Clock = NewPausedClock(m.timeControl(Light)), NewPausedClock(m.timeControl(Dark))
+	m.player(m.Turn).Clock.Start()
 	go m.sendClockUpdates()
 
+	// players boards start from the standard position so they need to be sent any other
+	if startingFEN(m.Game) != "" {
+		if stateEvent, err := m.StateEvent(); err == nil {
+			m.MessagePlayers(stateEvent, Light, Dark)
+		}
+	}
+
 	return nil
 }
 
@@ -206,7 +300,7 @@ func (m *Match) sendClockUpdates() {
 			return
 		}
 
-		m.MessagePlayers(outgoingEvent, Light, Dark)
+		m.Broadcast(outgoingEvent)
 	}
 }
 
@@ -220,31 +314,57 @@ OUTER:
 		case <-ticker.C:
 			if m.Game.Outcome() != chess.NoOutcome {
 				outcome.Outcome = m.Game.Outcome().String()
-				outcome.Method = m.Game.Method().String()
+				outcome.Method = m.Game.OutcomeMethod()
+				break OUTER
+			}
+			if m.aborted {
+				outcome.Outcome = chess.NoOutcome.String()
+				outcome.Method = MethodAborted
 				break OUTER
 			}
 			if m.State != Started {
 				outcome.Outcome = "abandoned"
 				break OUTER
 			}
+			if m.LightPlayer.abandoned() {
+				outcome.Outcome = DarkWon
+				outcome.Method = MethodAbandonment
+				break OUTER
+			}
+			if m.DarkPlayer.abandoned() {
+				outcome.Outcome = LightWon
+				outcome.Method = MethodAbandonment
+				break OUTER
+			}
 		case <-safePlayerClockChannel(m.LightPlayer):
 			outcome.Outcome = DarkWon
-			outcome.Method = "flagged"
+			outcome.Method = MethodFlagged
 			break OUTER
 		case <-safePlayerClockChannel(m.DarkPlayer):
 			outcome.Outcome = LightWon
-			outcome.Method = "flagged"
+			outcome.Method = MethodFlagged
 			break OUTER
 		}
 	}
 
+	m.outcome = outcome
 	m.State = Over
 	cleanupChan <- outcome
 }
 
 func (m *Match) notifyIfStale(cleanupChan chan<- MatchOutcome) {
 	ticker := time.NewTicker(500 * time.Millisecond)
-	startTime, waitTime := time.Now(), (m.TimeControl.ToDuration()*2)+(30*time.Second) // max wait time is for each players clock with a 30 second buffer
+	startTime, waitTime := time.Now(), (m.TimeControl.EstimatedDuration()*2)+(30*time.Second) // max wait time is for each players clock with a 30 second buffer
+
+	waitingTime := 20 * time.Second
+	switch {
+	case m.Private:
+		waitingTime = PrivateMatchWaitTime
+		waitTime += PrivateMatchWaitTime
+	case m.Scheduled:
+		waitingTime = ScheduledMatchWaitTime
+		waitTime += ScheduledMatchWaitTime
+	}
 
 	outcome := MatchOutcome{
 		ID:          m.ID,
@@ -253,8 +373,8 @@ func (m *Match) notifyIfStale(cleanupChan chan<- MatchOutcome) {
 	}
 
 	for range ticker.C {
-		if time.Since(startTime) >= 20*time.Second && m.State == Waiting { // if the match hasnt started after 20 seconds kill it
-			cleanupChan <- outcome
+		if time.Since(startTime) >= waitingTime && m.State == Waiting { // if the match hasnt started in time kill it
+			cleanupChan <- m.noShowOutcome(outcome)
 			return
 		}
 
@@ -265,6 +385,27 @@ func (m *Match) notifyIfStale(cleanupChan chan<- MatchOutcome) {
 	}
 }
 
+// noShowOutcome forfeits a scheduled match to whoever turned up for it, if nobody did it's abandoned
+func (m *Match) noShowOutcome(abandoned MatchOutcome) MatchOutcome {
+	if !m.Scheduled || m.LightPlayer == nil || m.DarkPlayer == nil {
+		return abandoned
+	}
+
+	forfeit := abandoned
+	forfeit.Method = MethodForfeit
+
+	switch lightShowed, darkShowed := m.LightPlayer.Client != nil, m.DarkPlayer.Client != nil; {
+	case lightShowed && !darkShowed:
+		forfeit.Outcome = wonBy(Light)
+	case darkShowed && !lightShowed:
+		forfeit.Outcome = wonBy(Dark)
+	default:
+		return abandoned
+	}
+
+	return forfeit
+}
+
 func (m *Match) MakeMove(pieces PieceColor, move string) error {
 	if m.Turn != pieces {
 		return errors.New("not players turn")
@@ -274,15 +415,135 @@ func (m *Match) MakeMove(pieces PieceColor, move string) error {
 		return fmt.Errorf("invalid move: %w", err)
 	}
 
+	// moving instead of answering a draw offer declines it
+	if m.drawOfferedBy == OpponentPieceColor(pieces) {
+		m.drawOfferedBy = NoColor
+	}
+
 	if err := m.swapRunningClock(pieces); err != nil {
 		return err
 	}
+	m.moveClocks = append(m.moveClocks, playerTimeRemaining(m.player(pieces), m.TimeControl))
 
 	m.Turn = OpponentPieceColor(pieces)
 
 	return nil
 }
 
+func (m *Match) Resign(pieces PieceColor) error {
+	if m.State != Started {
+		return errors.New("match not in progress")
+	}
+
+	m.Game.Resign(pieces.ChessColor())
+
+	return nil
+}
+
+func (m *Match) OfferDraw(pieces PieceColor) error {
+	if m.State != Started {
+		return errors.New("match not in progress")
+	}
+
+	if m.drawOfferedBy != NoColor {
+		return errors.New("draw already offered")
+	}
+
+	outgoingEvent, err := NewOutgoingEvent(EventDrawOffered, DrawOfferEvent{PlayerColor: pieces.String()})
+	if err != nil {
+		return err
+	}
+
+	m.drawOfferedBy = pieces
+	m.MessagePlayers(outgoingEvent, OpponentPieceColor(pieces))
+
+	return nil
+}
+
+func (m *Match) AcceptDraw(pieces PieceColor) error {
+	if m.drawOfferedBy != OpponentPieceColor(pieces) {
+		return errors.New("no draw offer to accept")
+	}
+
+	return m.Game.Draw(chess.DrawOffer)
+}
+
+func (m *Match) DeclineDraw(pieces PieceColor) error {
+	if m.drawOfferedBy != OpponentPieceColor(pieces) {
+		return errors.New("no draw offer to decline")
+	}
+
+	outgoingEvent, err := NewOutgoingEvent(EventDrawDeclined, DrawOfferEvent{PlayerColor: pieces.String()})
+	if err != nil {
+		return err
+	}
+
+	m.drawOfferedBy = NoColor
+	m.MessagePlayers(outgoingEvent, OpponentPieceColor(pieces))
+
+	return nil
+}
+
+// a match can only be aborted before both sides have made a move
+func (m *Match) Abort(pieces PieceColor) error {
+	if m.State != Started {
+		return errors.New("match not in progress")
+	}
+
+	if len(m.Game.Moves()) >= 2 {
+		return errors.New("too late to abort")
+	}
+
+	m.aborted = true
+
+	return nil
+}
+
+func (m *Match) Berserk(pieces PieceColor) error {
+	if m.State != Started {
+		return errors.New("match not in progress")
+	}
+
+	if !m.BerserkAllowed {
+		return errors.New("berserk isn't allowed in this match")
+	}
+
+	p := m.player(pieces)
+	if p == nil || p.Berserked {
+		return errors.New("already berserked")
+	}
+
+	if m.hasMoved(pieces) {
+		return errors.New("too late to berserk")
+	}
+
+	p.Clock.Berserk()
+	p.Berserked = true
+
+	outgoingEvent, err := NewOutgoingEvent(EventBerserked, BerserkEvent{PlayerColor: pieces.String()})
+	if err != nil {
+		return err
+	}
+	m.Broadcast(outgoingEvent)
+
+	// the state carries both clocks so everyone sees the halved one straight away
+	if stateEvent, err := m.StateEvent(); err == nil {
+		m.Broadcast(stateEvent)
+	}
+
+	return nil
+}
+
+// hasMoved is whether pieces has made a move yet, the first to move isn't always light when starting from a fen
+func (m *Match) hasMoved(pieces PieceColor) bool {
+	moves := len(m.Game.Moves())
+	if pieceColorOf(m.Game.Positions()[0].Turn()) == pieces {
+		return moves >= 1
+	}
+
+	return moves >= 2
+}
+
 func (m *Match) swapRunningClock(pieces PieceColor) error {
 	if m.LightPlayer.Clock == nil || m.DarkPlayer.Clock == nil {
 		return fmt.Errorf("nil player clock")
@@ -311,6 +572,40 @@ func (pc PieceColor) String() string {
 	}
 }
 
+func pieceColorOf(color chess.Color) PieceColor {
+	switch color {
+	case chess.White:
+		return Light
+	case chess.Black:
+		return Dark
+	default:
+		return NoColor
+	}
+}
+
+func (pc PieceColor) ChessColor() chess.Color {
+	switch pc {
+	case Light:
+		return chess.White
+	case Dark:
+		return chess.Black
+	default:
+		return chess.NoColor
+	}
+}
+
+// outcomeMethod maps methods that end a game by player action to the names reported in MatchOutcome
+func outcomeMethod(method chess.Method) string {
+	switch method {
+	case chess.Resignation:
+		return MethodResignation
+	case chess.DrawOffer:
+		return MethodAgreement
+	default:
+		return method.String()
+	}
+}
+
 func PieceColorFromString(str string) (PieceColor, error) {
 	switch str {
 	case "light":
@@ -372,6 +667,14 @@ func (m *Match) OpponentPresent(pieces PieceColor) bool {
 	return false
 }
 
+func wonBy(pieces PieceColor) string {
+	if pieces == Dark {
+		return DarkWon
+	}
+
+	return LightWon
+}
+
 func OpponentPieceColor(pieces PieceColor) PieceColor {
 	switch pieces {
 	case Light:
@@ -393,68 +696,221 @@ func safePlayerClockChannel(p *Player) <-chan time.Time {
 
 func (m *Match) MessagePlayers(event Event, players ...PieceColor) {
 	for _, color := range players {
-		switch color {
-		case Light:
-			if m.LightPlayer != nil && m.LightPlayer.Client != nil {
-				m.LightPlayer.Client.egress <- event
-			}
-		case Dark:
-			if m.DarkPlayer != nil && m.DarkPlayer.Client != nil {
-				m.DarkPlayer.Client.egress <- event
-			}
+		p := m.player(color)
+		if p == nil {
+			continue
+		}
+
+		// the client is copied out since a disconnect can empty the seat underneath us
+		if c := p.Client; c != nil {
+			c.egress <- event
+		}
+	}
+}
+
+// UserPieceColor finds the seat held by a user whether or not they are currently connected
+func (m *Match) UserPieceColor(userId string) PieceColor {
+	if userId == "" {
+		return NoColor
+	}
+
+	if m.LightPlayer != nil && m.LightPlayer.UserId == userId {
+		return Light
+	}
+
+	if m.DarkPlayer != nil && m.DarkPlayer.UserId == userId {
+		return Dark
+	}
+
+	return NoColor
+}
+
+// Disconnect holds a dropped players seat open for ReconnectGracePeriod
+func (m *Match) Disconnect(pieces PieceColor) {
+	p := m.player(pieces)
+	if p == nil || p.Client == nil {
+		return
+	}
+
+	p.disconnect()
+
+	outgoingEvent, err := NewOutgoingEvent(EventOpponentDisconnected, OpponentConnectionEvent{
+		PlayerColor: pieces.String(),
+		GracePeriod: ReconnectGracePeriod.String(),
+	})
+	if err != nil {
+		m.Logger.Error("failed to create opponent disconnected event", "error", err)
+		return
+	}
+
+	m.MessagePlayers(outgoingEvent, OpponentPieceColor(pieces))
+	m.MessageSpectators(outgoingEvent)
+}
+
+// Reconnect seats a new connection from the same user back into their match
+func (m *Match) Reconnect(pieces PieceColor, c *Client) error {
+	p := m.player(pieces)
+	if p == nil || p.UserId == "" || p.UserId != c.UserId() {
+		return errors.New("seat belongs to another player")
+	}
+
+	p.reconnect(c)
+
+	outgoingEvent, err := NewOutgoingEvent(EventOpponentReconnected, OpponentConnectionEvent{
+		PlayerColor: pieces.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	m.MessagePlayers(outgoingEvent, OpponentPieceColor(pieces))
+	m.MessageSpectators(outgoingEvent)
+
+	return nil
+}
+
+// spectators are sent to without blocking so a slow spectator can never hold up the players egress
+func (m *Match) MessageSpectators(event Event) {
+	m.spectatorsMu.RLock()
+	defer m.spectatorsMu.RUnlock()
+
+	for c := range m.Spectators {
+		select {
+		case c.egress <- event:
+		default:
+			m.Logger.Debug("dropped event for slow spectator", "MatchId", m.ID, "event", event.Type)
 		}
 	}
 }
 
-const EngineMatchTimeControl = TimeControl(30 * time.Minute)
+// Broadcast sends the event to both players and any spectators
+func (m *Match) Broadcast(event Event) {
+	m.MessagePlayers(event, Light, Dark)
+	m.MessageSpectators(event)
+}
+
+func (m *Match) AddSpectator(c *Client) {
+	m.spectatorsMu.Lock()
+	defer m.spectatorsMu.Unlock()
+
+	m.Spectators[c] = true
+}
+
+func (m *Match) RemoveSpectator(c *Client) {
+	m.spectatorsMu.Lock()
+	defer m.spectatorsMu.Unlock()
+
+	delete(m.Spectators, c)
+}
+
+func (m *Match) SpectatorList() []*Client {
+	m.spectatorsMu.RLock()
+	defer m.spectatorsMu.RUnlock()
+
+	spectators := make([]*Client, 0, len(m.Spectators))
+	for c := range m.Spectators {
+		spectators = append(spectators, c)
+	}
+
+	return spectators
+}
+
+func (m *Match) JoinURL() string {
+	return fmt.Sprintf(PrivateMatchJoinPath, m.ID)
+}
+
+func (m *Match) StateEvent() (Event, error) {
+	return NewOutgoingEvent(EventMatchState, MatchStateEvent{
+		ID:          m.ID,
+		TimeControl: m.TimeControl,
+		Variant:     m.Game.Variant().String(),
+		FEN:         m.Game.FEN(),
+		Pockets:     newPockets(m.Game.Position()),
+		Moves:       sanMoves(m.Game),
+		Turn:        m.Turn,
+		LightClock:  playerTimeRemaining(m.LightPlayer, m.timeControl(Light)).String(),
+		DarkClock:   playerTimeRemaining(m.DarkPlayer, m.timeControl(Dark)).String(),
+	})
+}
+
+// before a match starts players have no clock so the full time control is remaining
+func playerTimeRemaining(p *Player, timeControl TimeControl) time.Duration {
+	if p == nil || p.Clock == nil {
+		return timeControl.ToDuration()
+	}
+
+	return p.Clock.TimeRemaining()
+}
+
+// EngineMatchTimeControl is used when an engine match request doesn't ask for a time control
+var EngineMatchTimeControl = TimeControl{Base: 30 * time.Minute}
+
+const (
+	EngineDrawEvaluationTime = 500 * time.Millisecond
+	EngineHintTime           = 500 * time.Millisecond
+	// EngineSearchGrace is how far past its time budget a search may run before it's stopped
+	EngineSearchGrace = 2 * time.Second
+)
 
 type EngineMatch struct {
 	ID           MatchId
 	ELO          ELO
-	Engine       *uci.Engine
+	HintELO      ELO
+	TimeControl  TimeControl
+	Limits       SearchLimits
+	Engines      *EnginePool
 	Player       *Player
 	PlayerPieces PieceColor
-	Game         *chess.Game
+	EngineClock  *Clock
+	Game         *Game
 	Turn         PieceColor
 	State        MatchState
+	StartedAt    time.Time
 	Logger       *slog.Logger
+
+	// mu guards the game, turn and clocks, the engines replies are played from their own goroutine
+	// so it's held by every exported method rather than relying on the managers matchesMu
+	mu         sync.Mutex
+	aborted    bool
+	assisted   bool
+	evaluation atomic.Bool
+	moveClocks []time.Duration
+	outcome    EngineMatchOutcome
 }
 
-func NewEngine(elo ELO) (*uci.Engine, error) {
-	engine, err := uci.New("stockfish")
-	if err != nil {
-		return nil, err
-	}
+// EngineMove searches on the engines own clock, if the search fails the clock keeps running and the engine flags.
+// the match isn't held while the engine thinks so the player can still resign or abort
+func (m *EngineMatch) EngineMove() error {
+	m.mu.Lock()
+	position, limits, budget := m.Game.Position(), m.searchLimits(), m.EngineClock.TimeRemaining()
+	m.mu.Unlock()
 
-	err = engine.Run(
-		uci.CmdUCI,
-		uci.CmdIsReady,
-		uci.CmdSetOption{Name: "UCI_LimitStrength", Value: "true"},
-		uci.CmdSetOption{Name: "UCI_Elo", Value: strconv.Itoa(elo)},
-		uci.CmdUCINewGame,
-	)
+	// past the engines own clock the search is pointless, it has lost on time
+	result, err := m.search(m.ELO, position, limits, budget)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return engine, nil
-}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-func (m *EngineMatch) EngineMove() error {
-	cmdPos := uci.CmdPosition{Position: m.Game.Position()}
-	cmdGo := uci.CmdGo{MoveTime: time.Second}
-	if err := m.Engine.Run(cmdPos, cmdGo); err != nil {
-		return err
+	// the game can end while the engine is thinking, e.g. the player resigns or the engine flags
+	if m.State == Over || m.aborted || m.Game.Outcome() != chess.NoOutcome || m.EngineClock.TimeRemaining() <= 0 || m.Game.Position() != position {
+		return nil
 	}
 
-	move := m.Engine.SearchResults().BestMove
-	if err := m.Game.Move(move); err != nil {
+	if err := m.Game.Move(result.BestMove); err != nil {
 		return err
 	}
 
+	m.EngineClock.Pause()
+	m.Player.Clock.Start()
+	m.moveClocks = append(m.moveClocks, m.EngineClock.TimeRemaining())
+
 	outgoingEvent, err := NewOutgoingEvent(EventPropagatePosition, PropagatePositionEvent{
 		PlayerColor: OpponentPieceColor(m.PlayerPieces).String(),
 		FEN:         m.Game.FEN(),
+		Pockets:     newPockets(m.Game.Position()),
 	})
 	if err != nil {
 		return err
@@ -463,10 +919,53 @@ func (m *EngineMatch) EngineMove() error {
 	m.Turn = m.PlayerPieces
 	m.messagePlayer(outgoingEvent)
 
+	if m.evaluation.Load() {
+		m.sendEvaluation(position, result)
+	}
+
 	return nil
 }
 
+// search gives up on the engine once it's EngineSearchGrace past budget, the pool stops it in the background
+func (m *EngineMatch) search(elo ELO, position *Position, limits SearchLimits, budget time.Duration) (SearchResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), budget+EngineSearchGrace)
+	defer cancel()
+
+	return m.Engines.Search(ctx, elo, position, limits)
+}
+
+// SetEvaluation turns the evaluation events sent after each engine move on or off
+func (m *EngineMatch) SetEvaluation(enabled bool) {
+	m.evaluation.Store(enabled)
+}
+
+func (m *EngineMatch) sendEvaluation(position *Position, result SearchResult) {
+	evt := EvaluationEvent{
+		PlayerColor: OpponentPieceColor(m.PlayerPieces).String(),
+		CP:          result.CP,
+		Mate:        result.Mate,
+		Depth:       result.Depth,
+		PV:          pvToSAN(position, result.PV),
+	}
+
+	// the engine scores from its own side so a dark engine is flipped to lights point of view
+	if m.PlayerPieces == Light {
+		evt.CP, evt.Mate = -evt.CP, -evt.Mate
+	}
+
+	outgoingEvent, err := NewOutgoingEvent(EventEvaluation, evt)
+	if err != nil {
+		m.Logger.Error("failed to create evaluation event", "MatchId", m.ID, "error", err)
+		return
+	}
+
+	m.messagePlayer(outgoingEvent)
+}
+
 func (m *EngineMatch) MakeMove(pieces PieceColor, move string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.Turn != pieces {
 		return errors.New("not players turn")
 	}
@@ -475,9 +974,14 @@ func (m *EngineMatch) MakeMove(pieces PieceColor, move string) error {
 		return fmt.Errorf("invalid move: %w", err)
 	}
 
+	m.Player.Clock.Pause()
+	m.EngineClock.Start()
+	m.moveClocks = append(m.moveClocks, m.Player.Clock.TimeRemaining())
+
 	outgoingEvent, err := NewOutgoingEvent(EventPropagatePosition, PropagatePositionEvent{
 		PlayerColor: m.PlayerPieces.String(),
 		FEN:         m.Game.FEN(),
+		Pockets:     newPockets(m.Game.Position()),
 	})
 	if err != nil {
 		return err
@@ -489,9 +993,221 @@ func (m *EngineMatch) MakeMove(pieces PieceColor, move string) error {
 	return nil
 }
 
+// searchLimits hands the engine both clocks so it manages its own time the way a player would
+func (m *EngineMatch) searchLimits() SearchLimits {
+	limits := m.Limits
+
+	light, dark := m.Player.Clock, m.EngineClock
+	if m.PlayerPieces == Dark {
+		light, dark = dark, light
+	}
+
+	// a go without any time left would be an infinite search
+	limits.WhiteTime = max(light.TimeRemaining(), time.Millisecond)
+	limits.BlackTime = max(dark.TimeRemaining(), time.Millisecond)
+
+	// uci has no way to describe a delay so the engine only hears about fischer increments
+	if m.TimeControl.Mode == FischerIncrement {
+		limits.WhiteIncrement = m.TimeControl.Increment
+		limits.BlackIncrement = m.TimeControl.Increment
+	}
+
+	return limits
+}
+
+func (m *EngineMatch) Resign(pieces PieceColor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.State != Started {
+		return errors.New("match not in progress")
+	}
+
+	m.Game.Resign(pieces.ChessColor())
+
+	return nil
+}
+
+// RequestHint asks the engine what it would play for the player at HintELO, the search runs in the
+// background like the engines own moves so the players clock keeps running meanwhile
+func (m *EngineMatch) RequestHint(pieces PieceColor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.State != Started || m.Game.Outcome() != chess.NoOutcome {
+		return errors.New("match not in progress")
+	}
+
+	if m.Turn != pieces {
+		return errors.New("not players turn")
+	}
+
+	m.assisted = true
+	go m.sendHint(m.Game.Position())
+
+	return nil
+}
+
+// sendHint drops the hint if the player has moved or taken back while the engine was thinking
+func (m *EngineMatch) sendHint(position *Position) {
+	result, err := m.search(m.HintELO, position, SearchLimits{MoveTime: EngineHintTime}, EngineHintTime)
+	if err != nil {
+		m.messageError(fmt.Errorf("engine failed to give a hint: %w", err))
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Game.Position() != position {
+		return
+	}
+
+	outgoingEvent, err := NewOutgoingEvent(EventHint, HintEvent{
+		Move: position.SAN(result.BestMove),
+		UCI:  position.UCI(result.BestMove),
+	})
+	if err != nil {
+		m.Logger.Error("failed to create hint event", "MatchId", m.ID, "error", err)
+		return
+	}
+
+	m.messagePlayer(outgoingEvent)
+}
+
+// Takeback undoes the players last move along with the engines reply, both clocks go back to what
+// they read before the undone moves
+func (m *EngineMatch) Takeback(pieces PieceColor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.State != Started || m.Game.Outcome() != chess.NoOutcome {
+		return errors.New("match not in progress")
+	}
+
+	if m.Turn != pieces {
+		return errors.New("not players turn")
+	}
+
+	// it's the players turn so the last move was the engines reply, when the engine
+	// opened the game its first move alone isn't the players to take back
+	moves := m.Game.Moves()
+	if len(moves) < 2 {
+		return errors.New("no move to take back")
+	}
+
+	game, err := replayGame(m.Game.Positions()[0], moves[:len(moves)-2])
+	if err != nil {
+		return err
+	}
+
+	// moveClocks holds the movers time after each move, so each side gets back its reading from
+	// its move before the undone ones or the full time control if it hadn't moved yet
+	playerClock, engineClock := m.TimeControl.Base, m.TimeControl.Base
+	if n := len(m.moveClocks); n >= 3 {
+		engineClock = m.moveClocks[n-3]
+		if n >= 4 {
+			playerClock = m.moveClocks[n-4]
+		}
+	}
+
+	game.custom = m.Game.custom
+	m.Game = game
+	m.moveClocks = m.moveClocks[:max(len(m.moveClocks)-2, 0)]
+	m.Player.Clock.SetTimeRemaining(playerClock)
+	m.EngineClock.SetTimeRemaining(engineClock)
+	m.assisted = true
+
+	stateEvent, err := m.stateEvent()
+	if err != nil {
+		return err
+	}
+
+	m.messagePlayer(stateEvent)
+
+	return nil
+}
+
+// OfferDraw has the engine weigh the offer in the background, it answers once it has searched the position
+func (m *EngineMatch) OfferDraw(pieces PieceColor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.State != Started || m.Game.Outcome() != chess.NoOutcome {
+		return errors.New("match not in progress")
+	}
+
+	go m.answerDrawOffer(pieces, m.Game.Position())
+
+	return nil
+}
+
+// answerDrawOffer accepts whenever the engine doesn't think it is winning, an offer the game moved past while
+// the engine was thinking is declined
+func (m *EngineMatch) answerDrawOffer(pieces PieceColor, position *Position) {
+	score, err := m.search(m.ELO, position, SearchLimits{MoveTime: EngineDrawEvaluationTime}, EngineDrawEvaluationTime)
+	if err != nil {
+		m.messageError(fmt.Errorf("engine failed to answer the draw offer: %w", err))
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.State != Started || m.Game.Outcome() != chess.NoOutcome {
+		return
+	}
+
+	// scores are reported from the perspective of the side to move
+	if pieceColorOf(position.Turn()) == m.PlayerPieces {
+		score.CP, score.Mate = -score.CP, -score.Mate
+	}
+
+	if m.Game.Position() == position && (score.Mate < 0 || (score.Mate == 0 && score.CP <= 0)) {
+		if err := m.Game.Draw(chess.DrawOffer); err != nil {
+			m.Logger.Error("failed to accept draw offer", "MatchId", m.ID, "error", err)
+		}
+		return
+	}
+
+	outgoingEvent, err := NewOutgoingEvent(EventDrawDeclined, DrawOfferEvent{PlayerColor: OpponentPieceColor(pieces).String()})
+	if err != nil {
+		m.Logger.Error("failed to create draw declined event", "MatchId", m.ID, "error", err)
+		return
+	}
+
+	m.messagePlayer(outgoingEvent)
+}
+
+// the engine never offers draws so there is never one to answer
+func (m *EngineMatch) AcceptDraw(pieces PieceColor) error {
+	return errors.New("no draw offer to accept")
+}
+
+func (m *EngineMatch) DeclineDraw(pieces PieceColor) error {
+	return errors.New("no draw offer to decline")
+}
+
+func (m *EngineMatch) Abort(pieces PieceColor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.State != Started {
+		return errors.New("match not in progress")
+	}
+
+	if len(m.Game.Moves()) >= 2 {
+		return errors.New("too late to abort")
+	}
+
+	m.aborted = true
+
+	return nil
+}
+
 func (m *EngineMatch) notifyIfStale(cleanupChan chan EngineMatchOutcome) {
 	ticker := time.NewTicker(500 * time.Millisecond)
-	startTime, waitTime := time.Now(), EngineMatchTimeControl.ToDuration()+time.Minute
+	startTime, waitTime := time.Now(), (m.TimeControl.EstimatedDuration()*2)+time.Minute // both clocks with a minute to spare
 
 	outcome := EngineMatchOutcome{
 		ID:      m.ID,
@@ -500,7 +1216,11 @@ func (m *EngineMatch) notifyIfStale(cleanupChan chan EngineMatchOutcome) {
 	}
 
 	for range ticker.C {
-		if time.Since(startTime) >= waitTime && m.State != Over {
+		m.mu.Lock()
+		stale := time.Since(startTime) >= waitTime && m.State != Over
+		m.mu.Unlock()
+
+		if stale {
 			cleanupChan <- outcome
 			return
 		}
@@ -515,73 +1235,211 @@ OUTER:
 	for {
 		select {
 		case <-ticker.C:
-			if m.Game.Outcome() != chess.NoOutcome {
-				outcome.Outcome = m.Game.Outcome().String()
-				outcome.Method = m.Game.Method().String()
-				break OUTER
-			}
-			if m.Player.Client == nil {
-				outcome.Outcome = "abandoned"
+			if m.checkOver(&outcome) {
 				break OUTER
 			}
 		case <-safePlayerClockChannel(m.Player):
-			outcome.Outcome = fmt.Sprintf("%s won", OpponentPieceColor(m.PlayerPieces))
-			outcome.Method = "flagged"
+			outcome.Outcome = wonBy(OpponentPieceColor(m.PlayerPieces))
+			outcome.Method = MethodFlagged
+			break OUTER
+		case <-m.EngineClock.Done:
+			outcome.Outcome = wonBy(m.PlayerPieces)
+			outcome.Method = MethodFlagged
 			break OUTER
 		}
 	}
 
+	m.mu.Lock()
+	m.outcome = outcome
 	m.State = Over
+	m.mu.Unlock()
+
 	cleanupChan <- outcome
 }
 
+// checkOver fills in outcome when the game is over on the board, aborted or abandoned by the player
+func (m *EngineMatch) checkOver(outcome *EngineMatchOutcome) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case m.Game.Outcome() != chess.NoOutcome:
+		outcome.Outcome = m.Game.Outcome().String()
+		outcome.Method = m.Game.OutcomeMethod()
+	case m.aborted:
+		outcome.Outcome = chess.NoOutcome.String()
+		outcome.Method = MethodAborted
+	case m.Player.abandoned():
+		outcome.Outcome = wonBy(OpponentPieceColor(m.PlayerPieces))
+		outcome.Method = MethodAbandonment
+	default:
+		return false
+	}
+
+	return true
+}
+
+// messagePlayer is called with mu held
 func (m *EngineMatch) messagePlayer(event Event) {
-	if m.Player != nil && m.Player.Client != nil {
-		m.Player.Client.egress <- event
+	if m.Player == nil {
+		return
+	}
+
+	if c := m.Player.Client; c != nil {
+		c.egress <- event
+	}
+}
+
+// messageError tells the player an action that ran in the background failed, the same way a failed handler would
+func (m *EngineMatch) messageError(err error) {
+	m.Logger.Error("engine match error", "MatchId", m.ID, "error", err)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messagePlayer(Event{
+		Payload: []byte(fmt.Sprintf(`{"error":"%v"}`, err)),
+		Type:    EventMatchError,
+	})
+}
+
+// Disconnect holds the players seat open for ReconnectGracePeriod, the engine doesn't mind waiting
+func (m *EngineMatch) Disconnect(c *Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.State == Over || m.Player == nil || m.Player.Client == nil || m.Player.Client != c {
+		return
+	}
+
+	m.Player.disconnect()
+}
+
+// resumable is true when the user left a match that is still being played
+func (m *EngineMatch) resumable(userId string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.State != Over && m.Player != nil && m.Player.Client == nil && m.Player.UserId == userId
+}
+
+func (m *EngineMatch) Reconnect(c *Client) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Player == nil || m.Player.UserId == "" || m.Player.UserId != c.UserId() {
+		return errors.New("seat belongs to another player")
+	}
+
+	m.Player.reconnect(c)
+
+	return nil
+}
+
+func (m *EngineMatch) StateEvent() (Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.stateEvent()
+}
+
+func (m *EngineMatch) stateEvent() (Event, error) {
+	playerClock := playerTimeRemaining(m.Player, m.TimeControl).String()
+	engineClock := m.TimeControl.ToDuration().String()
+	if m.EngineClock != nil {
+		engineClock = m.EngineClock.TimeRemaining().String()
+	}
+
+	evt := MatchStateEvent{
+		ID:          m.ID,
+		TimeControl: m.TimeControl,
+		Variant:     m.Game.Variant().String(),
+		FEN:         m.Game.FEN(),
+		Pockets:     newPockets(m.Game.Position()),
+		Moves:       sanMoves(m.Game),
+		Turn:        m.Turn,
+		LightClock:  playerClock,
+		DarkClock:   engineClock,
+	}
+
+	if m.PlayerPieces == Dark {
+		evt.LightClock, evt.DarkClock = engineClock, playerClock
 	}
+
+	return NewOutgoingEvent(EventMatchState, evt)
 }
 
 func (m *EngineMatch) Start(cleanupChan chan<- EngineMatchOutcome) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.State = Started
+	m.StartedAt = time.Now()
 	m.messagePlayer(Event{Type: EventMatchStarted})
 
-	m.Player.Clock.Start()
+	// only the side to move has its clock running
+	m.Player.Clock, m.EngineClock = NewPausedClock(m.TimeControl), NewPausedClock(m.TimeControl)
+	if m.Turn == m.PlayerPieces {
+		m.Player.Clock.Start()
+	} else {
+		m.EngineClock.Start()
+	}
+
 	go m.sendClockUpdates()
+
+	if startingFEN(m.Game) != "" {
+		if stateEvent, err := m.stateEvent(); err == nil {
+			m.messagePlayer(stateEvent)
+		}
+	}
 	go m.notifyWhenOver(cleanupChan)
 
 	return nil
 }
 
 func (m *EngineMatch) sendClockUpdates() {
-	for m.State != Over {
+	for {
 		time.Sleep(1 * time.Second)
-		if m.Player == nil {
+		if !m.sendClockUpdate() {
 			return
 		}
+	}
+}
 
-		if m.Turn != m.PlayerPieces {
-			continue
-		}
+// sendClockUpdate sends the running clock, it's false once there's nothing left to update
+func (m *EngineMatch) sendClockUpdate() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-		evt := ClockUpdateEvent{
-			ClockOwner:    m.PlayerPieces.String(),
-			TimeRemaining: m.Player.Clock.TimeRemaining().String(),
-		}
+	if m.State == Over || m.Player == nil {
+		return false
+	}
 
-		outgoingEvent, err := NewOutgoingEvent(EventClockUpdate, evt)
-		if err != nil {
-			return
-		}
+	evt := ClockUpdateEvent{
+		ClockOwner:    m.PlayerPieces.String(),
+		TimeRemaining: m.Player.Clock.TimeRemaining().String(),
+	}
+
+	if m.Turn != m.PlayerPieces {
+		evt.ClockOwner = OpponentPieceColor(m.PlayerPieces).String()
+		evt.TimeRemaining = m.EngineClock.TimeRemaining().String()
+	}
 
-		m.messagePlayer(outgoingEvent)
+	outgoingEvent, err := NewOutgoingEvent(EventClockUpdate, evt)
+	if err != nil {
+		return false
 	}
+
+	m.messagePlayer(outgoingEvent)
+
+	return true
 }
 
 type EngineMatchOutcome struct {
-	ID      MatchId
-	ELO     ELO
-	Outcome string
-	Method  string
+	ID      MatchId `json:"match_id"`
+	ELO     ELO     `json:"elo"`
+	Outcome string  `json:"outcome"`
+	Method  string  `json:"method"`
 }
 
 func assignPlayerPieces() PieceColor {
@@ -12,19 +12,28 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/notnil/chess"
+	"github.com/michaelgov-ctrl/bad-chess/internal/models"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 // TODO: Handle unsupported time controls & engine ELOs by returning error
 type MatchmakingManager struct {
-	clients   ClientList
-	clientsMu sync.RWMutex
+	clients    ClientList
+	spectators map[*Client]*Match
+	clientsMu  sync.RWMutex
 
 	matches          TimeControlMatchList
 	matchesMu        sync.RWMutex
 	matchCleanupChan chan MatchOutcome
 
+	// seeks are kept apart from matches so pairing never needs matchesMu
+	seeks   map[seekBucket]seekQueue
+	seekers map[*Client]*seek
+	seeksMu sync.Mutex
+
+	// lobby publishes seeks and matches as they come and go
+	lobby *lobby
+
 	handlers map[string]EventHandler
 
 	ManagerOptions
@@ -34,15 +43,20 @@ type MatchmakingManager struct {
 func NewMatchmakingManager(ctx context.Context, opts ...ManagerOption) *MatchmakingManager {
 	m := &MatchmakingManager{
 		clients:          make(ClientList),
+		spectators:       make(map[*Client]*Match),
 		matches:          make(TimeControlMatchList),
 		matchCleanupChan: make(chan MatchOutcome),
+		seeks:            make(map[seekBucket]seekQueue),
+		seekers:          make(map[*Client]*seek),
+		lobby:            newLobby(),
 		handlers:         make(map[string]EventHandler),
 		metrics:          &MatchmakingManagerMetrics{},
 	}
 
 	defaults := &ManagerOptions{
-		logger:   slog.New(slog.NewTextHandler(os.Stdout, nil)),
-		registry: prometheus.NewRegistry(),
+		logger:       slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		registry:     prometheus.NewRegistry(),
+		engineConfig: DefaultEngineConfig(),
 	}
 
 	for _, opt := range opts {
@@ -55,14 +69,25 @@ func NewMatchmakingManager(ctx context.Context, opts ...ManagerOption) *Matchmak
 	m.registerSupportedTimeControls()
 	m.registerEventHandlers()
 	go m.cleanupMatches()
+	go m.matchSeeks()
 
 	return m
 }
 
-// TODO: add a handler for joining with a valid match id
 func (m *MatchmakingManager) registerEventHandlers() {
 	m.handlers[EventJoinMatchRequest] = m.matchMakingHandler
+	m.handlers[EventCancelSeek] = m.cancelSeekHandler
+	m.handlers[EventAcceptSeek] = m.acceptSeekHandler
+	m.handlers[EventJoinMatchByIdRequest] = m.joinMatchByIdHandler
+	m.handlers[EventNewMatchRequest] = m.newPrivateMatchHandler
 	m.handlers[EventMakeMove] = m.makeMoveHandler
+	m.handlers[EventSpectateMatch] = m.spectateMatchHandler
+	m.handlers[EventResign] = m.matchActionHandler((*Match).Resign)
+	m.handlers[EventOfferDraw] = m.matchActionHandler((*Match).OfferDraw)
+	m.handlers[EventAcceptDraw] = m.matchActionHandler((*Match).AcceptDraw)
+	m.handlers[EventDeclineDraw] = m.matchActionHandler((*Match).DeclineDraw)
+	m.handlers[EventAbort] = m.matchActionHandler((*Match).Abort)
+	m.handlers[EventBerserk] = m.matchActionHandler((*Match).Berserk)
 }
 
 func (m *MatchmakingManager) registerSupportedTimeControls() {
@@ -86,6 +111,7 @@ func (m *MatchmakingManager) addClient(c *Client) {
 
 func (m *MatchmakingManager) removeClient(c *Client) {
 	m.logger.Debug("removed client", "client", c)
+	m.lobby.unsubscribe(c)
 
 	m.clientsMu.Lock()
 	defer m.clientsMu.Unlock()
@@ -96,6 +122,11 @@ func (m *MatchmakingManager) removeClient(c *Client) {
 		}
 		delete(m.clients, c)
 	}
+
+	if match, ok := m.spectators[c]; ok {
+		match.RemoveSpectator(c)
+		delete(m.spectators, c)
+	}
 }
 
 func (m *MatchmakingManager) ServeWS(w http.ResponseWriter, r *http.Request) {
@@ -107,14 +138,106 @@ func (m *MatchmakingManager) ServeWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := NewClient(conn, m)
+	client := NewClient(conn, m, UserIdFromContext(r.Context()))
 
 	m.addClient(client)
+	m.loadClient(client)
+
+	if err := m.resumeMatch(client); err != nil {
+		m.logger.Error("failed to resume match", "error", err)
+	}
 
 	go client.readEvents(m.logger)
 	go client.writeEvents(m.logger)
 }
 
+// disconnectClient keeps a dropped players seat open so they can come back to it
+func (m *MatchmakingManager) disconnectClient(c *Client) {
+	m.removeSeek(c)
+
+	// the client is removed under matchesMu so a seek being seated can't race it into a match
+	m.matchesMu.Lock()
+	defer m.matchesMu.Unlock()
+
+	if match, ok := m.matches[c.matchInfo().TimeControl][c.matchInfo().ID]; ok && !c.matchInfo().Spectating && match.State != Over {
+		if pieces := match.ClientPieceColor(c); pieces != NoColor {
+			match.Disconnect(pieces)
+		}
+	}
+
+	m.removeClient(c)
+}
+
+// resumeMatch re-seats a returning user into a match they dropped out of and catches them up on it
+func (m *MatchmakingManager) resumeMatch(c *Client) error {
+	if c.UserId() == "" {
+		return nil
+	}
+
+	m.matchesMu.Lock()
+	defer m.matchesMu.Unlock()
+
+	for _, matchList := range m.matches {
+		for _, match := range matchList {
+			if match.State == Over {
+				continue
+			}
+
+			pieces := match.UserPieceColor(c.UserId())
+			if pieces == NoColor || match.player(pieces).Client != nil {
+				continue
+			}
+
+			if err := match.Reconnect(pieces, c); err != nil {
+				return err
+			}
+
+			info := NewClientMatchInfo(match.ID, Matchmaking, match.TimeControl, 0, pieces)
+			info.Private = match.Private
+			info.BerserkAllowed = match.BerserkAllowed
+			c.setMatchInfo(info)
+
+			assignedEvent, err := NewOutgoingEvent(EventAssignedMatch, info)
+			if err != nil {
+				return err
+			}
+
+			stateEvent, err := match.StateEvent()
+			if err != nil {
+				return err
+			}
+
+			c.egress <- assignedEvent
+			c.egress <- stateEvent
+
+			// the link is still needed if nobody has joined yet
+			if match.Private && match.State == Waiting {
+				createdEvent, err := NewOutgoingEvent(EventPrivateMatchCreated, PrivateMatchCreatedEvent{
+					ID:      match.ID,
+					JoinURL: match.JoinURL(),
+				})
+				if err != nil {
+					return err
+				}
+
+				c.egress <- createdEvent
+			}
+
+			m.logger.Info("player reconnected", "MatchId", match.ID, "pieces", pieces)
+
+			// scheduled matches and accepted seeks start as soon as both players have turned up
+			if match.State == Waiting && match.LightPlayer.Client != nil && match.DarkPlayer.Client != nil {
+				m.metrics.totalMatches.Inc()
+				return m.startMatch(match)
+			}
+
+			return nil
+		}
+	}
+
+	return nil
+}
+
 func (m *MatchmakingManager) routeEvent(event Event, c *Client) error {
 	handler, ok := m.handlers[event.Type]
 	if !ok {
@@ -141,12 +264,42 @@ func (m *MatchmakingManager) cleanupMatches() {
 
 			finishedMatches = append(finishedMatches, matchInfo)
 		case <-cleanupTime.C:
+			var records []models.MatchRecord
+			var analyses []analysisJob
+			var results []scheduledResult
+
 			m.matchesMu.Lock()
 			for _, finishedMatch := range finishedMatches {
 				m.logger.Debug("removing match from Matchmaking Manager", "match info", finishedMatch)
 
 				if match, ok := m.matches[finishedMatch.TimeControl][finishedMatch.ID]; ok {
-					match.MessagePlayers(Event{Type: EventMatchOver}, Light, Dark)
+					finishedMatch.LightBerserked = match.LightPlayer != nil && match.LightPlayer.Berserked
+					finishedMatch.DarkBerserked = match.DarkPlayer != nil && match.DarkPlayer.Berserked
+
+					outgoingEvent, err := NewOutgoingEvent(EventMatchOver, finishedMatch)
+					if err != nil {
+						m.logger.Error("failed to create match over event", "error", err)
+						outgoingEvent = Event{Type: EventMatchOver}
+					}
+
+					match.Broadcast(outgoingEvent)
+					m.lobby.matchEnded(match.ID)
+
+					// matches that never filled up aren't worth keeping, scheduled matches have their seats filled before anyone shows
+					if match.LightPlayer != nil && match.DarkPlayer != nil && !match.StartedAt.IsZero() {
+						records = append(records, match.Record(finishedMatch))
+						if m.analyzable(match.Game.Variant()) {
+							analyses = append(analyses, newAnalysisJob(match.ID, match.Game))
+						}
+					}
+
+					if match.results != nil {
+						results = append(results, scheduledResult{results: match.results, outcome: finishedMatch})
+					}
+
+					for _, spectator := range match.SpectatorList() {
+						m.removeClient(spectator)
+					}
 
 					if match.LightPlayer != nil {
 						m.removeClient(match.LightPlayer.Client)
@@ -161,24 +314,33 @@ func (m *MatchmakingManager) cleanupMatches() {
 			}
 			m.matchesMu.Unlock()
 
+			m.storeMatchRecords(records)
+			m.updateRatings(records)
+			m.queueAnalyses(analyses)
+			reportScheduledResults(results)
+
 			finishedMatches = nil
 		}
 	}
 }
 
 func (m *MatchmakingManager) addClientToMatch(c *Client) error {
-	match := m.matches[c.currentMatch.TimeControl][c.currentMatch.ID]
-	outgoingEvent, err := NewOutgoingEvent(EventAssignedMatch, c.currentMatch)
+	info := c.matchInfo()
+	match := m.matches[info.TimeControl][info.ID]
+	outgoingEvent, err := NewOutgoingEvent(EventAssignedMatch, info)
 	if err != nil {
 		return err
 	}
 
-	switch c.currentMatch.Pieces {
+	player := NewPlayer(c)
+	player.Rating = c.Rating(RatingCategory(match.Game.Variant(), info.TimeControl))
+
+	switch info.Pieces {
 	case Light:
-		m.matches[c.currentMatch.TimeControl][c.currentMatch.ID].LightPlayer = &Player{Client: c}
+		match.LightPlayer = player
 		match.MessagePlayers(outgoingEvent, Light)
 	case Dark:
-		m.matches[c.currentMatch.TimeControl][c.currentMatch.ID].DarkPlayer = &Player{Client: c}
+		match.DarkPlayer = player
 		match.MessagePlayers(outgoingEvent, Dark)
 	}
 
@@ -186,7 +348,7 @@ func (m *MatchmakingManager) addClientToMatch(c *Client) error {
 }
 
 func (m *MatchmakingManager) matchMakingHandler(event Event, c *Client) error {
-	m.logger.Info("match making handler", "event", event, "client", *c)
+	m.logger.Info("match making handler", "event", event, "client", c)
 
 	var joinEvent JoinMatchEvent
 	if err := json.Unmarshal(event.Payload, &joinEvent); err != nil {
@@ -197,40 +359,154 @@ func (m *MatchmakingManager) matchMakingHandler(event Event, c *Client) error {
 		return fmt.Errorf("unsupported time control")
 	}
 
-	c.currentMatch.TimeControl = joinEvent.TimeControl
+	variant, err := ParseVariant(joinEvent.Variant)
+	if err != nil {
+		return err
+	}
+
+	if c.matchInfo().ID != "" {
+		return errors.New("already in a match")
+	}
+
+	color, err := seekColor(joinEvent.Color)
+	if err != nil {
+		return err
+	}
+
+	s := newSeek(c, variant, joinEvent.TimeControl, color)
+	opponent, err := m.addSeek(s)
+	if err != nil {
+		return err
+	}
+
+	if opponent != nil {
+		return m.startSeekMatch(newSeekPair(opponent, s))
+	}
+
+	outgoingEvent, err := NewOutgoingEvent(EventSeekCreated, s.event())
+	if err != nil {
+		return err
+	}
+
+	c.egress <- outgoingEvent
+
+	return nil
+}
+
+func (m *MatchmakingManager) cancelSeekHandler(event Event, c *Client) error {
+	m.logger.Info("cancel seek handler", "event", event, "client", c)
+
+	s, ok := m.removeSeek(c)
+	if !ok {
+		return ErrNoSeek
+	}
+
+	outgoingEvent, err := NewOutgoingEvent(EventSeekCancelled, s.event())
+	if err != nil {
+		return err
+	}
+
+	c.egress <- outgoingEvent
+
+	return nil
+}
+
+func (m *MatchmakingManager) newPrivateMatchHandler(event Event, c *Client) error {
+	m.logger.Info("new private match handler", "event", event, "client", c)
+
+	var newMatchEvent NewMatchEvent
+	if err := json.Unmarshal(event.Payload, &newMatchEvent); err != nil {
+		return fmt.Errorf("bad payload in request: %v", err)
+	}
+
+	if _, ok := SupportedTimeControls[newMatchEvent.TimeControl]; !ok {
+		return fmt.Errorf("unsupported time control")
+	}
+
+	variant, err := ParseVariant(newMatchEvent.Variant)
+	if err != nil {
+		return err
+	}
+
+	if c.matchInfo().ID != "" {
+		return errors.New("already in a match")
+	}
+
+	game, err := newGame(variant, newMatchEvent.FEN)
+	if err != nil {
+		return err
+	}
+
+	m.matchesMu.Lock()
+	defer m.matchesMu.Unlock()
+
+	matchId := m.newMatch(newMatchEvent.TimeControl, true, game)
+	info := NewClientMatchInfo(matchId, Matchmaking, newMatchEvent.TimeControl, 0, Light)
+	info.Private = true
+	c.setMatchInfo(info)
+
+	if err := m.addClientToMatch(c); err != nil {
+		return err
+	}
+
+	m.metrics.totalMatches.Inc()
+
+	match := m.matches[newMatchEvent.TimeControl][matchId]
+	outgoingEvent, err := NewOutgoingEvent(EventPrivateMatchCreated, PrivateMatchCreatedEvent{
+		ID:      matchId,
+		JoinURL: match.JoinURL(),
+	})
+	if err != nil {
+		return err
+	}
+
+	match.MessagePlayers(outgoingEvent, Light)
+
+	return nil
+}
+
+func (m *MatchmakingManager) joinMatchByIdHandler(event Event, c *Client) error {
+	m.logger.Info("join match by id handler", "event", event, "client", c)
+
+	var joinEvent JoinMatchByIdEvent
+	if err := json.Unmarshal(event.Payload, &joinEvent); err != nil {
+		return fmt.Errorf("bad payload in request: %v", err)
+	}
+
+	if c.matchInfo().ID != "" {
+		return errors.New("already in a match")
+	}
 
-	// this is probably slow
 	m.matchesMu.Lock()
 	defer m.matchesMu.Unlock()
-	for matchId, match := range m.matches[joinEvent.TimeControl] {
-		// no created match should ever be missing a light player since theyre added at creation
-		if match.DarkPlayer == nil {
-			m.matches[joinEvent.TimeControl][matchId].DarkPlayer = &Player{Client: c}
-			c.currentMatch.ID = matchId
-			c.currentMatch.Pieces = Dark
-			err := m.addClientToMatch(c)
-			if err != nil {
-				return err
-			}
 
-			// both players should now be present to start game
-			return match.Start(m.matchCleanupChan)
-		}
+	match, ok := m.findMatch(joinEvent.ID)
+	if !ok {
+		return errors.New("no match")
 	}
 
-	c.currentMatch.ID = m.newMatch(joinEvent.TimeControl)
-	c.currentMatch.Pieces = Light
+	if match.ClientPieceColor(c) != NoColor {
+		return errors.New("already seated in match")
+	}
 
-	err := m.addClientToMatch(c)
-	if err == nil {
-		m.metrics.totalMatches.Inc()
+	if match.State != Waiting || match.DarkPlayer != nil {
+		return errors.New("match is full")
+	}
+
+	info := NewClientMatchInfo(match.ID, Matchmaking, match.TimeControl, 0, Dark)
+	info.Private = match.Private
+	c.setMatchInfo(info)
+
+	if err := m.addClientToMatch(c); err != nil {
+		return err
 	}
 
-	return err
+	// both players should now be present to start game
+	return m.startMatch(match)
 }
 
 func (m *MatchmakingManager) makeMoveHandler(event Event, c *Client) error {
-	m.logger.Info("make move handler", "event", event, "client", *c)
+	m.logger.Info("make move handler", "event", event, "client", c)
 
 	var moveEvent MakeMoveEvent
 	if err := json.Unmarshal(event.Payload, &moveEvent); err != nil {
@@ -240,62 +516,181 @@ func (m *MatchmakingManager) makeMoveHandler(event Event, c *Client) error {
 	m.matchesMu.RLock()
 	defer m.matchesMu.RUnlock()
 
-	match, ok := m.matches[c.currentMatch.TimeControl][c.currentMatch.ID]
+	match, ok := m.matches[c.matchInfo().TimeControl][c.matchInfo().ID]
 	if !ok {
 		return errors.New("no match")
 	}
 
+	if c.matchInfo().Spectating {
+		return errors.New("spectators cannot make moves")
+	}
+
 	clientPlayerColor := match.ClientPieceColor(c)
 
 	if !match.OpponentPresent(clientPlayerColor) {
 		return fmt.Errorf("no opponent present")
 	}
 
-	if clientPlayerColor == NoColor || clientPlayerColor != c.currentMatch.Pieces {
+	if clientPlayerColor == NoColor || clientPlayerColor != c.matchInfo().Pieces {
 		return fmt.Errorf("player pieces are borked")
 	}
 
-	if err := match.MakeMove(c.currentMatch.Pieces, moveEvent.Move); err != nil {
+	if err := match.MakeMove(c.matchInfo().Pieces, moveEvent.Move); err != nil {
 		return err
 	}
 
 	outgoingEvent, err := NewOutgoingEvent(EventPropagatePosition, PropagatePositionEvent{
 		PlayerColor: clientPlayerColor.String(),
 		FEN:         match.Game.FEN(),
+		Pockets:     newPockets(match.Game.Position()),
 	})
 	if err != nil {
 		return err
 	}
 
 	// egress is handled in (c *Client) writeMessages()
-	match.MessagePlayers(outgoingEvent, Dark, Light)
+	match.Broadcast(outgoingEvent)
 
 	return nil
 }
 
-// TODO: this is probably fine for now, consider tossing an error on collision & retrying
-func (m *MatchmakingManager) newMatch(timeControl TimeControl) MatchId {
-	matchId := MatchId(uuid.NewString())
+// matchActionHandler wraps match actions like resigning that only need the acting players pieces
+func (m *MatchmakingManager) matchActionHandler(action func(*Match, PieceColor) error) EventHandler {
+	return func(event Event, c *Client) error {
+		m.logger.Info("match action handler", "event", event, "client", c)
 
-	// right now newMatch() is only called in matchmaking which locks m
-	if _, ok := m.matches[timeControl][matchId]; ok {
-		m.logger.Error("uuid collision", "MatchId", matchId)
-		matchId = m.newMatch(timeControl)
-	} else {
-		match := &Match{
-			ID:          matchId,
-			TimeControl: timeControl,
-			Game:        chess.NewGame(),
-			Turn:        Light,
-			State:       Waiting,
-			Logger:      m.logger,
+		if c.matchInfo().Spectating {
+			return errors.New("spectators cannot act in a match")
+		}
+
+		m.matchesMu.RLock()
+		defer m.matchesMu.RUnlock()
+
+		match, ok := m.matches[c.matchInfo().TimeControl][c.matchInfo().ID]
+		if !ok {
+			return errors.New("no match")
 		}
 
-		go match.notifyIfStale(m.matchCleanupChan)
+		clientPlayerColor := match.ClientPieceColor(c)
+		if clientPlayerColor == NoColor || clientPlayerColor != c.matchInfo().Pieces {
+			return fmt.Errorf("player pieces are borked")
+		}
+
+		return action(match, clientPlayerColor)
+	}
+}
+
+func (m *MatchmakingManager) spectateMatchHandler(event Event, c *Client) error {
+	m.logger.Info("spectate match handler", "event", event, "client", c)
+
+	var spectateEvent SpectateMatchEvent
+	if err := json.Unmarshal(event.Payload, &spectateEvent); err != nil {
+		return fmt.Errorf("bad payload in request: %v", err)
+	}
+
+	m.matchesMu.RLock()
+	defer m.matchesMu.RUnlock()
 
-		m.matches[timeControl][matchId] = match
+	match, ok := m.findMatch(spectateEvent.ID)
+	if !ok {
+		return errors.New("no match")
 	}
 
+	if match.State == Over {
+		return errors.New("match is over")
+	}
+
+	info := NewClientMatchInfo(match.ID, Matchmaking, match.TimeControl, 0, NoColor)
+	info.Spectating = true
+	c.setMatchInfo(info)
+
+	m.clientsMu.Lock()
+	m.spectators[c] = match
+	m.clientsMu.Unlock()
+
+	match.AddSpectator(c)
+
+	outgoingEvent, err := match.StateEvent()
+	if err != nil {
+		return err
+	}
+
+	c.egress <- outgoingEvent
+
+	return nil
+}
+
+// PGN returns the PGN of a match the manager is still holding on to
+func (m *MatchmakingManager) PGN(id MatchId) (string, bool) {
+	m.matchesMu.RLock()
+	defer m.matchesMu.RUnlock()
+
+	match, ok := m.findMatch(id)
+	if !ok {
+		return "", false
+	}
+
+	return match.PGN(), true
+}
+
+// findMatch looks up a match by id across all time controls, callers must hold matchesMu
+func (m *MatchmakingManager) findMatch(id MatchId) (*Match, bool) {
+	for _, matchList := range m.matches {
+		if match, ok := matchList[id]; ok {
+			return match, true
+		}
+	}
+
+	return nil, false
+}
+
+func (m *MatchmakingManager) newMatch(timeControl TimeControl, private bool, game *Game) MatchId {
+	match := m.buildMatch(timeControl, game)
+	match.Private = private
+
+	return m.addMatch(match)
+}
+
+func (m *MatchmakingManager) buildMatch(timeControl TimeControl, game *Game) *Match {
+	return &Match{
+		TimeControl: timeControl,
+		Spectators:  make(ClientList),
+		Game:        game,
+		Turn:        pieceColorOf(game.Position().Turn()),
+		State:       Waiting,
+		Logger:      m.logger,
+
+		drawOfferedBy: NoColor,
+	}
+}
+
+// startMatch starts a match with both players seated and lists it in the lobby, callers must hold matchesMu
+func (m *MatchmakingManager) startMatch(match *Match) error {
+	if err := match.Start(m.matchCleanupChan); err != nil {
+		return err
+	}
+
+	if !match.Private {
+		m.lobby.matchStarted(match)
+	}
+
+	return nil
+}
+
+// addMatch gives a match its id and starts watching it for going stale, callers must hold matchesMu
+func (m *MatchmakingManager) addMatch(match *Match) MatchId {
+	matchId := MatchId(uuid.NewString())
+
+	if _, ok := m.matches[match.TimeControl][matchId]; ok {
+		m.logger.Error("uuid collision", "MatchId", matchId)
+		return m.addMatch(match)
+	}
+
+	match.ID = matchId
+	go match.notifyIfStale(m.matchCleanupChan)
+
+	m.matches[match.TimeControl][matchId] = match
+
 	return matchId
 }
 
@@ -304,6 +699,7 @@ type MatchmakingManagerMetrics struct {
 	currentClients prometheus.Gauge
 	totalMatches   prometheus.Counter
 	currentMatches prometheus.Gauge
+	currentSeeks   prometheus.Gauge
 }
 
 func (m *MatchmakingManager) registerMatchmakingManagerMetrics() {
@@ -335,7 +731,14 @@ func (m *MatchmakingManager) registerMatchmakingManagerMetrics() {
 		},
 	)
 
-	m.registry.MustRegister(m.metrics.totalClients, m.metrics.currentClients, m.metrics.totalMatches, m.metrics.currentMatches)
+	m.metrics.currentSeeks = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "matchmaking_manager_seeks_current",
+			Help: "Current number of players waiting in the seek queue",
+		},
+	)
+
+	m.registry.MustRegister(m.metrics.totalClients, m.metrics.currentClients, m.metrics.totalMatches, m.metrics.currentMatches, m.metrics.currentSeeks)
 
 	go m.updateMetrics()
 }
@@ -345,9 +748,17 @@ func (m *MatchmakingManager) updateMetrics() {
 		time.Sleep(5 * time.Second)
 		go m.updateCurrentClientsMetric()
 		go m.updateCurrentMatchesMetric()
+		go m.updateCurrentSeeksMetric()
 	}
 }
 
+func (m *MatchmakingManager) updateCurrentSeeksMetric() {
+	m.seeksMu.Lock()
+	defer m.seeksMu.Unlock()
+
+	m.metrics.currentSeeks.Set(float64(len(m.seekers)))
+}
+
 func (m *MatchmakingManager) updateCurrentClientsMetric() {
 	m.clientsMu.RLock()
 	defer m.clientsMu.RUnlock()
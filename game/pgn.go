@@ -0,0 +1,217 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+var PGNSite = "https://bad-chess.com"
+
+// pgnLineLength keeps movetext within the 80 columns the PGN standard asks for
+const pgnLineLength = 79
+
+type pgnTags struct {
+	Event       string
+	Date        time.Time
+	White       string
+	Black       string
+	Result      string
+	TimeControl string
+	Termination string
+	Extra       [][2]string
+}
+
+func (m *Match) PGN() string {
+	if m.State == Over {
+		return m.encodePGN(m.outcome.Outcome, m.outcome.Method)
+	}
+
+	return m.encodePGN(chess.NoOutcome.String(), "")
+}
+
+func (m *Match) encodePGN(result, method string) string {
+	tags := pgnTags{
+		Event:       fmt.Sprintf("%s %s", Matchmaking, m.TimeControl),
+		Date:        m.StartedAt,
+		White:       playerUserId(m.LightPlayer),
+		Black:       playerUserId(m.DarkPlayer),
+		Result:      pgnResult(result),
+		TimeControl: m.TimeControl.PGNString(),
+		Termination: pgnTermination(result, method),
+	}
+
+	return encodePGN(tags, m.Game, m.moveClocks)
+}
+
+func (m *EngineMatch) PGN() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.State == Over {
+		return m.encodePGN(m.outcome.Outcome, m.outcome.Method)
+	}
+
+	return m.encodePGN(chess.NoOutcome.String(), "")
+}
+
+func (m *EngineMatch) encodePGN(result, method string) string {
+	tags := pgnTags{
+		Event:       fmt.Sprintf("%s %d", Engine, m.ELO),
+		Date:        m.StartedAt,
+		White:       EnginePlayerName,
+		Black:       EnginePlayerName,
+		Result:      pgnResult(result),
+		TimeControl: m.TimeControl.PGNString(),
+		Termination: pgnTermination(result, method),
+	}
+
+	engineElo := [2]string{"BlackElo", fmt.Sprint(m.ELO)}
+	switch m.PlayerPieces {
+	case Light:
+		tags.White = playerUserId(m.Player)
+	case Dark:
+		tags.Black = playerUserId(m.Player)
+		engineElo[0] = "WhiteElo"
+	}
+	tags.Extra = append(tags.Extra, engineElo)
+
+	return encodePGN(tags, m.Game, m.moveClocks)
+}
+
+func encodePGN(tags pgnTags, game *Game, clocks []time.Duration) string {
+	if tags.Date.IsZero() {
+		tags.Date = time.Now()
+	}
+
+	var sb strings.Builder
+	writeTag := func(key, value string) {
+		if value == "" {
+			value = "?"
+		}
+		fmt.Fprintf(&sb, "[%s %q]\n", key, value)
+	}
+
+	// the seven tag roster comes first and in this order
+	writeTag("Event", tags.Event)
+	writeTag("Site", PGNSite)
+	writeTag("Date", tags.Date.UTC().Format("2006.01.02"))
+	writeTag("Round", "-")
+	writeTag("White", tags.White)
+	writeTag("Black", tags.Black)
+	writeTag("Result", tags.Result)
+	writeTag("TimeControl", tags.TimeControl)
+	writeTag("Termination", tags.Termination)
+	if game.Variant() != Standard {
+		writeTag("Variant", game.Variant().PGNName())
+	}
+	// games set up from a position carry it so they can be replayed
+	if fen := startingFEN(game); fen != "" {
+		writeTag("SetUp", "1")
+		writeTag("FEN", fen)
+	}
+	for _, tag := range tags.Extra {
+		writeTag(tag[0], tag[1])
+	}
+	sb.WriteString("\n")
+
+	// move numbers carry on from the starting position, which may have dark to move
+	start := game.Positions()[0]
+	firstMove, darkFirst := fullMoveNumber(start), start.Turn() == chess.Black
+
+	var tokens []string
+	for i, move := range sanMoves(game) {
+		ply := i
+		if darkFirst {
+			ply++
+		}
+
+		switch {
+		case ply%2 == 0:
+			tokens = append(tokens, fmt.Sprintf("%d.", firstMove+ply/2))
+		case i == 0:
+			tokens = append(tokens, fmt.Sprintf("%d...", firstMove))
+		}
+
+		tokens = append(tokens, move)
+
+		if i < len(clocks) {
+			tokens = append(tokens, fmt.Sprintf("{ [%%clk %s] }", formatClock(clocks[i])))
+		}
+	}
+	tokens = append(tokens, tags.Result)
+
+	var lineLength int
+	for i, token := range tokens {
+		if i > 0 && lineLength+len(token)+1 > pgnLineLength {
+			sb.WriteString("\n")
+			lineLength = 0
+		} else if i > 0 {
+			sb.WriteString(" ")
+			lineLength++
+		}
+
+		sb.WriteString(token)
+		lineLength += len(token)
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+func sanMoves(game *Game) []string {
+	positions, moves := game.Positions(), game.Moves()
+
+	san := make([]string, len(moves))
+	for i, move := range moves {
+		san[i] = positions[i].SAN(move)
+	}
+
+	return san
+}
+
+// pvToSAN writes out an engines principal variation from position
+func pvToSAN(position *Position, pv []*Move) []string {
+	san := make([]string, 0, len(pv))
+	for _, move := range pv {
+		san = append(san, position.SAN(move))
+		position = position.Update(move)
+	}
+
+	return san
+}
+
+// formatClock writes clock times as H:MM:SS as used by the %clk command
+func formatClock(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+// pgnResult maps outcomes like abandoned, which aren't valid results, to an unknown result
+func pgnResult(outcome string) string {
+	switch outcome {
+	case LightWon, DarkWon, Draw:
+		return outcome
+	default:
+		return chess.NoOutcome.String()
+	}
+}
+
+func pgnTermination(outcome, method string) string {
+	switch {
+	case method == MethodFlagged:
+		return "time forfeit"
+	case method == MethodAborted, method == MethodAbandonment, outcome == "abandoned":
+		return "abandoned"
+	case method == "" && pgnResult(outcome) == chess.NoOutcome.String():
+		return "unterminated"
+	default:
+		return "normal"
+	}
+}
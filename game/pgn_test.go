@@ -0,0 +1,114 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func playMoves(t *testing.T, variant Variant, fen string, moves ...string) *Game {
+	t.Helper()
+
+	game, err := newGame(variant, fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, move := range moves {
+		if err := game.MoveStr(move); err != nil {
+			t.Fatalf("%s: %v", move, err)
+		}
+	}
+
+	return game
+}
+
+func TestEncodePGN(t *testing.T) {
+	// the date is written in UTC, which is still the day before here
+	date := time.Date(2024, time.March, 10, 1, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	tests := []struct {
+		name   string
+		tags   pgnTags
+		game   *Game
+		clocks []time.Duration
+		want   string
+	}{
+		{
+			name: "clocks wrap the movetext",
+			tags: pgnTags{Event: "matchmaking 3+2", Date: date, White: "alice", Black: "bob", Result: LightWon, TimeControl: "180+2", Termination: "normal", Extra: [][2]string{{"WhiteElo", "1500"}}},
+			game: playMoves(t, Standard, "", "e4", "e5", "Qh5", "Nc6", "Bc4", "Nf6", "Qxf7#"),
+			clocks: []time.Duration{
+				180 * time.Second, 181 * time.Second, 175 * time.Second, 179 * time.Second,
+				170 * time.Second, 61 * time.Second, 3*time.Hour + 5*time.Second + 900*time.Millisecond,
+			},
+			want: `[Event "matchmaking 3+2"]
+[Site "https://bad-chess.com"]
+[Date "2024.03.09"]
+[Round "-"]
+[White "alice"]
+[Black "bob"]
+[Result "1-0"]
+[TimeControl "180+2"]
+[Termination "normal"]
+[WhiteElo "1500"]
+
+1. e4 { [%clk 0:03:00] } e5 { [%clk 0:03:01] } 2. Qh5 { [%clk 0:02:55] } Nc6
+{ [%clk 0:02:59] } 3. Bc4 { [%clk 0:02:50] } Nf6 { [%clk 0:01:01] } 4. Qxf7#
+{ [%clk 3:00:05] } 1-0
+`,
+		},
+		{
+			name: "dark moves first from a set up position",
+			tags: pgnTags{Event: "engine 1400", Date: date, White: "engine", Result: "*", Termination: "unterminated"},
+			game: playMoves(t, Standard, "4k3/8/8/8/8/8/4P3/4K3 b - - 3 5", "Kd7", "e4", "Kc6"),
+			want: `[Event "engine 1400"]
+[Site "https://bad-chess.com"]
+[Date "2024.03.09"]
+[Round "-"]
+[White "engine"]
+[Black "?"]
+[Result "*"]
+[TimeControl "?"]
+[Termination "unterminated"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 3 5"]
+
+5... Kd7 6. e4 Kc6 *
+`,
+		},
+		{
+			name: "variants are tagged",
+			tags: pgnTags{Event: "matchmaking 5+0", Date: date, Result: Draw, TimeControl: "300", Termination: "normal"},
+			game: playMoves(t, KingOfTheHill, "", "e4"),
+			want: `[Event "matchmaking 5+0"]
+[Site "https://bad-chess.com"]
+[Date "2024.03.09"]
+[Round "-"]
+[White "?"]
+[Black "?"]
+[Result "1/2-1/2"]
+[TimeControl "300"]
+[Termination "normal"]
+[Variant "King of the Hill"]
+
+1. e4 1/2-1/2
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodePGN(tt.tags, tt.game, tt.clocks)
+			if got != tt.want {
+				t.Errorf("encodePGN() =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			for _, line := range strings.Split(got, "\n") {
+				if len(line) > pgnLineLength {
+					t.Errorf("line is %d long: %s", len(line), line)
+				}
+			}
+		})
+	}
+}
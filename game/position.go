@@ -0,0 +1,636 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+var ErrIllegalMove = errors.New("illegal move")
+
+// castleRights holds the rook each color may still castle with on each side, NoSquare once the right is gone
+type castleRights [2][2]chess.Square
+
+func noCastleRights() castleRights {
+	return castleRights{
+		{chess.NoSquare, chess.NoSquare},
+		{chess.NoSquare, chess.NoSquare},
+	}
+}
+
+func (cr castleRights) rook(color chess.Color, side chess.Side) chess.Square {
+	return cr[color-1][side-1]
+}
+
+func (cr *castleRights) set(color chess.Color, side chess.Side, rook chess.Square) {
+	cr[color-1][side-1] = rook
+}
+
+func (cr *castleRights) clear(color chess.Color) {
+	cr[color-1] = [2]chess.Square{chess.NoSquare, chess.NoSquare}
+}
+
+// remove drops any right to castle with a rook on sq, i.e. the rook moved or was captured
+func (cr *castleRights) remove(sq chess.Square) {
+	for c := range cr {
+		for s := range cr[c] {
+			if cr[c][s] == sq {
+				cr[c][s] = chess.NoSquare
+			}
+		}
+	}
+}
+
+var castleSides = []chess.Side{chess.KingSide, chess.QueenSide}
+
+// Move is a legal move in a Position, castles go from the king to the rook it castles with
+// since in chess960 the king can already be standing on its castled square, drops come from NoSquare
+type Move struct {
+	from   chess.Square
+	to     chess.Square
+	castle chess.Side
+	drop   chess.PieceType
+	move   *chess.Move
+}
+
+func (m *Move) isCastle() bool {
+	return m.castle == chess.KingSide || m.castle == chess.QueenSide
+}
+
+func (m *Move) isDrop() bool {
+	return m.drop != chess.NoPieceType
+}
+
+// Position wraps a notnil position that never has any castling rights, notnil only knows how to castle
+// from the standard squares so castling is generated and played here for every variant
+type Position struct {
+	pos     *chess.Position
+	castles castleRights
+	variant Variant
+	// checks each color has given, only three-check cares
+	checks [2]int
+	// pockets and promoted pieces are only used in crazyhouse, promoted is a bitboard of squares
+	pockets  [2]pocket
+	promoted uint64
+}
+
+// parsePosition reads a fen, castling rights may be KQkq or the rooks files as in X-FEN and Shredder-FEN
+// and three-check fens carry the checks left before the clocks
+func parsePosition(fen string, variant Variant) (*Position, error) {
+	fields := strings.Fields(fen)
+
+	var checks [2]int
+	if variant == ThreeCheck && len(fields) == 7 {
+		var err error
+		if checks, err = parseChecksLeft(fields[4]); err != nil {
+			return nil, err
+		}
+
+		fields = slices.Delete(fields, 4, 5)
+	}
+
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("fen %q must have 6 fields", fen)
+	}
+
+	var pockets [2]pocket
+	var promoted uint64
+	if variant == Crazyhouse {
+		var err error
+		if fields[0], pockets, promoted, err = parseCrazyhouseBoard(fields[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	castling := fields[2]
+	fields[2] = "-"
+
+	pos, err := decodePosition(strings.Join(fields, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	castles, err := parseCastleRights(castling, pos.Board(), variant.Chess960())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Position{pos: pos, castles: castles, variant: variant, checks: checks, pockets: pockets, promoted: promoted}, nil
+}
+
+func decodePosition(fen string) (*chess.Position, error) {
+	pos := &chess.Position{}
+	if err := pos.UnmarshalText([]byte(fen)); err != nil {
+		return nil, err
+	}
+
+	return pos, nil
+}
+
+func parseCastleRights(field string, board *chess.Board, chess960 bool) (castleRights, error) {
+	castles := noCastleRights()
+	if field == "-" {
+		return castles, nil
+	}
+
+	for _, r := range field {
+		color, rank := chess.White, chess.Rank1
+		if 'a' <= r && r <= 'z' {
+			color, rank = chess.Black, chess.Rank8
+			r -= 'a' - 'A'
+		}
+
+		king := kingSquare(board, color)
+		if king == chess.NoSquare || king.Rank() != rank {
+			return castles, fmt.Errorf("castling rights %q without a king on the back rank", field)
+		}
+
+		var rook chess.Square
+		switch {
+		case r == 'K':
+			rook = outermostRook(board, color, king, chess.KingSide)
+		case r == 'Q':
+			rook = outermostRook(board, color, king, chess.QueenSide)
+		case 'A' <= r && r <= 'H':
+			rook = chess.NewSquare(chess.File(r-'A'), rank)
+			if board.Piece(rook) != chess.NewPiece(chess.Rook, color) {
+				rook = chess.NoSquare
+			}
+		default:
+			return castles, fmt.Errorf("invalid castling rights %q", field)
+		}
+
+		if rook == chess.NoSquare || rook.File() == king.File() {
+			return castles, fmt.Errorf("castling rights %q without a rook to castle with", field)
+		}
+
+		side := chess.KingSide
+		if rook.File() < king.File() {
+			side = chess.QueenSide
+		}
+
+		// engines in standard mode can only castle from the usual squares
+		standardRook := (side == chess.KingSide && rook.File() == chess.FileH) || (side == chess.QueenSide && rook.File() == chess.FileA)
+		if !chess960 && (king.File() != chess.FileE || !standardRook) {
+			return castles, fmt.Errorf("castling rights %q need chess960", field)
+		}
+
+		castles.set(color, side, rook)
+	}
+
+	return castles, nil
+}
+
+// String is the positions fen, rooks that aren't the outermost on their side are written by file as in X-FEN
+func (p *Position) String() string {
+	fields := strings.Fields(p.pos.String())
+	fields[2] = p.castlingString()
+
+	if p.variant == ThreeCheck {
+		fields = slices.Insert(fields, 4, checksLeftString(p.checks))
+	}
+
+	if p.variant == Crazyhouse {
+		fields[0] = p.crazyhouseBoard()
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func (p *Position) castlingString() string {
+	board := p.pos.Board()
+
+	var sb strings.Builder
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		king := kingSquare(board, color)
+		for _, side := range castleSides {
+			rook := p.castles.rook(color, side)
+			if rook == chess.NoSquare {
+				continue
+			}
+
+			c := 'K'
+			switch {
+			case rook != outermostRook(board, color, king, side):
+				c = 'A' + rune(rook.File())
+			case side == chess.QueenSide:
+				c = 'Q'
+			}
+
+			if color == chess.Black {
+				c += 'a' - 'A'
+			}
+
+			sb.WriteRune(c)
+		}
+	}
+
+	if sb.Len() == 0 {
+		return "-"
+	}
+
+	return sb.String()
+}
+
+func (p *Position) Turn() chess.Color {
+	return p.pos.Turn()
+}
+
+func (p *Position) Board() *chess.Board {
+	return p.pos.Board()
+}
+
+func (p *Position) HalfMoveClock() int {
+	return p.pos.HalfMoveClock()
+}
+
+func (p *Position) Variant() Variant {
+	return p.variant
+}
+
+func (p *Position) Chess960() bool {
+	return p.variant.Chess960()
+}
+
+// key identifies a position for repetitions, the clocks don't count
+func (p *Position) key() string {
+	fields := strings.Fields(p.String())
+	return strings.Join(fields[:len(fields)-2], " ")
+}
+
+// LegalMoves is every move notnil finds plus the castles and drops it can't, less anything the variant forbids
+func (p *Position) LegalMoves() []*Move {
+	valid := p.pos.ValidMoves()
+
+	moves := make([]*Move, 0, len(valid)+2)
+	for _, m := range valid {
+		moves = append(moves, &Move{from: m.S1(), to: m.S2(), move: m})
+	}
+	moves = append(moves, p.castlingMoves()...)
+	moves = append(moves, p.dropMoves()...)
+
+	return slices.DeleteFunc(moves, func(m *Move) bool {
+		return !p.variant.Legal(p, m)
+	})
+}
+
+func (p *Position) castlingMoves() []*Move {
+	turn, board := p.Turn(), p.pos.Board()
+
+	king := kingSquare(board, turn)
+	if king == chess.NoSquare || p.InCheck() {
+		return nil
+	}
+
+	var moves []*Move
+	for _, side := range castleSides {
+		rook := p.castles.rook(turn, side)
+		if rook == chess.NoSquare {
+			continue
+		}
+
+		if p.canCastle(king, rook, side) {
+			moves = append(moves, &Move{from: king, to: rook, castle: side})
+		}
+	}
+
+	return moves
+}
+
+// canCastle checks everything between the king, rook and where they end up is empty
+// and that the king doesn't pass through or land on an attacked square
+func (p *Position) canCastle(king, rook chess.Square, side chess.Side) bool {
+	turn := p.Turn()
+	kingTo, rookTo := castleSquares(turn, side)
+
+	squares := p.pos.Board().SquareMap()
+	delete(squares, king)
+	delete(squares, rook)
+
+	for _, path := range [][2]chess.Square{{king, kingTo}, {rook, rookTo}} {
+		for _, sq := range rankSquares(path[0], path[1]) {
+			if _, ok := squares[sq]; ok {
+				return false
+			}
+		}
+	}
+
+	// the castling rook is taken off too, it may have been the only thing shielding the kings path
+	board := chess.NewBoard(squares)
+	for _, sq := range rankSquares(king, kingTo) {
+		if attacked(board, sq, turn.Other()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// castleSquares is where the king and rook end up, the same as standard chess whatever the start
+func castleSquares(color chess.Color, side chess.Side) (kingTo, rookTo chess.Square) {
+	rank := chess.Rank1
+	if color == chess.Black {
+		rank = chess.Rank8
+	}
+
+	if side == chess.QueenSide {
+		return chess.NewSquare(chess.FileC, rank), chess.NewSquare(chess.FileD, rank)
+	}
+
+	return chess.NewSquare(chess.FileG, rank), chess.NewSquare(chess.FileF, rank)
+}
+
+// rankSquares lists the squares from a to b inclusive, both on the same rank
+func rankSquares(a, b chess.Square) []chess.Square {
+	lo, hi := min(a.File(), b.File()), max(a.File(), b.File())
+
+	squares := make([]chess.Square, 0, hi-lo+1)
+	for f := lo; f <= hi; f++ {
+		squares = append(squares, chess.NewSquare(f, a.Rank()))
+	}
+
+	return squares
+}
+
+// Update plays a move from LegalMoves
+func (p *Position) Update(m *Move) *Position {
+	next := p.update(m)
+	if next.InCheck() {
+		next.checks[p.Turn()-1]++
+	}
+
+	return next
+}
+
+func (p *Position) update(m *Move) *Position {
+	next := &Position{castles: p.castles, variant: p.variant, checks: p.checks, pockets: p.pockets, promoted: p.promoted}
+
+	if m.isDrop() {
+		squares := p.pos.Board().SquareMap()
+		squares[m.to] = chess.NewPiece(m.drop, p.Turn())
+
+		// dropping a pawn resets the fifty move count the same as moving one
+		halfMoveClock := p.pos.HalfMoveClock() + 1
+		if m.drop == chess.Pawn {
+			halfMoveClock = 0
+		}
+
+		next.pos = p.withBoard(squares, halfMoveClock)
+		next.pockets[p.Turn()-1][m.drop]--
+
+		return next
+	}
+
+	if !m.isCastle() {
+		next.pos = p.pos.Update(m.move)
+		if p.variant == Crazyhouse {
+			p.pocketCapture(next, m)
+		}
+
+		if piece := p.pos.Board().Piece(m.from); piece.Type() == chess.King {
+			next.castles.clear(piece.Color())
+		}
+		next.castles.remove(m.from)
+		next.castles.remove(m.to)
+
+		return next
+	}
+
+	turn := p.Turn()
+	kingTo, rookTo := castleSquares(turn, m.castle)
+
+	squares := p.pos.Board().SquareMap()
+	king, rook := squares[m.from], squares[m.to]
+	delete(squares, m.from)
+	delete(squares, m.to)
+	squares[kingTo], squares[rookTo] = king, rook
+
+	next.pos = p.withBoard(squares, p.pos.HalfMoveClock()+1)
+	next.castles.clear(turn)
+
+	return next
+}
+
+// withBoard is the position after a move notnil can't play itself, with squares as the new board and the other side to move
+func (p *Position) withBoard(squares map[chess.Square]chess.Piece, halfMoveClock int) *chess.Position {
+	turn := p.Turn()
+
+	moveNumber := fullMoveNumber(p)
+	if turn == chess.Black {
+		moveNumber++
+	}
+
+	fen := fmt.Sprintf("%s %s - - %d %d", chess.NewBoard(squares), turn.Other(), halfMoveClock, moveNumber)
+	pos, err := decodePosition(fen)
+	if err != nil {
+		// the board came from a legal position so this can't happen
+		panic(fmt.Sprintf("move produced an invalid fen %s: %v", fen, err))
+	}
+
+	return pos
+}
+
+func (p *Position) InCheck() bool {
+	board := p.pos.Board()
+
+	king := kingSquare(board, p.Turn())
+	return king != chess.NoSquare && attacked(board, king, p.Turn().Other())
+}
+
+// Status is Checkmate or Stalemate once the side to move has no moves left, NoMethod otherwise
+func (p *Position) Status() chess.Method {
+	if len(p.LegalMoves()) > 0 {
+		return chess.NoMethod
+	}
+
+	if p.InCheck() {
+		return chess.Checkmate
+	}
+
+	return chess.Stalemate
+}
+
+// UCI encodes castles as king takes rook in chess960 and as the kings two square move otherwise, drops as P@e4
+func (p *Position) UCI(m *Move) string {
+	if m.isDrop() {
+		return dropString(m)
+	}
+
+	if !m.isCastle() {
+		return m.move.String()
+	}
+
+	to := m.to
+	if !p.Chess960() {
+		to, _ = castleSquares(p.Turn(), m.castle)
+	}
+
+	return m.from.String() + to.String()
+}
+
+func (p *Position) SAN(m *Move) string {
+	san := p.sanWithoutCheck(m)
+
+	if next := p.Update(m); next.InCheck() {
+		if next.Status() == chess.Checkmate {
+			return san + "#"
+		}
+
+		return san + "+"
+	}
+
+	return san
+}
+
+func (p *Position) sanWithoutCheck(m *Move) string {
+	if m.isDrop() {
+		return dropString(m)
+	}
+
+	switch m.castle {
+	case chess.KingSide:
+		return "O-O"
+	case chess.QueenSide:
+		return "O-O-O"
+	default:
+		return strings.TrimRight(chess.AlgebraicNotation{}.Encode(p.pos, m.move), "+#")
+	}
+}
+
+// ParseMove accepts SAN, with or without check marks, or UCI, castles may also be given as king takes rook
+func (p *Position) ParseMove(s string) (*Move, error) {
+	s = strings.TrimSpace(s)
+	san := normalizeSAN(s)
+
+	for _, m := range p.LegalMoves() {
+		if p.UCI(m) == s || p.sanWithoutCheck(m) == san {
+			return m, nil
+		}
+
+		if m.isCastle() && m.from.String()+m.to.String() == s {
+			return m, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w %s for position %s", ErrIllegalMove, s, p)
+}
+
+func dropString(m *Move) string {
+	return strings.ToUpper(m.drop.String()) + "@" + m.to.String()
+}
+
+func normalizeSAN(s string) string {
+	s = strings.TrimRight(s, "+#!?")
+	return strings.ReplaceAll(s, "0", "O")
+}
+
+func kingSquare(board *chess.Board, color chess.Color) chess.Square {
+	for sq, piece := range board.SquareMap() {
+		if piece == chess.NewPiece(chess.King, color) {
+			return sq
+		}
+	}
+
+	return chess.NoSquare
+}
+
+// outermostRook finds the rook furthest from the king on side, which is what K and Q castling rights refer to
+func outermostRook(board *chess.Board, color chess.Color, king chess.Square, side chess.Side) chess.Square {
+	if king == chess.NoSquare {
+		return chess.NoSquare
+	}
+
+	rook := chess.NewPiece(chess.Rook, color)
+	if side == chess.KingSide {
+		for f := chess.FileH; f > king.File(); f-- {
+			if sq := chess.NewSquare(f, king.Rank()); board.Piece(sq) == rook {
+				return sq
+			}
+		}
+	} else {
+		for f := chess.FileA; f < king.File(); f++ {
+			if sq := chess.NewSquare(f, king.Rank()); board.Piece(sq) == rook {
+				return sq
+			}
+		}
+	}
+
+	return chess.NoSquare
+}
+
+// attacked checks if any of by's pieces attack sq
+func attacked(board *chess.Board, sq chess.Square, by chess.Color) bool {
+	for from, piece := range board.SquareMap() {
+		if piece.Color() == by && attacks(board, piece, from, sq) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func attacks(board *chess.Board, piece chess.Piece, from, to chess.Square) bool {
+	df, dr := int(to.File())-int(from.File()), int(to.Rank())-int(from.Rank())
+	if df == 0 && dr == 0 {
+		return false
+	}
+
+	diagonal, straight := abs(df) == abs(dr), df == 0 || dr == 0
+
+	switch piece.Type() {
+	case chess.Pawn:
+		forward := 1
+		if piece.Color() == chess.Black {
+			forward = -1
+		}
+		return dr == forward && abs(df) == 1
+	case chess.Knight:
+		return abs(df)*abs(dr) == 2
+	case chess.King:
+		return abs(df) <= 1 && abs(dr) <= 1
+	case chess.Bishop:
+		return diagonal && clearLine(board, from, df, dr)
+	case chess.Rook:
+		return straight && clearLine(board, from, df, dr)
+	case chess.Queen:
+		return (diagonal || straight) && clearLine(board, from, df, dr)
+	default:
+		return false
+	}
+}
+
+// clearLine checks the squares strictly between from and from+(df, dr) are empty
+func clearLine(board *chess.Board, from chess.Square, df, dr int) bool {
+	stepF, stepR := sign(df), sign(dr)
+	f, r := int(from.File())+stepF, int(from.Rank())+stepR
+	for f != int(from.File())+df || r != int(from.Rank())+dr {
+		if board.Piece(chess.NewSquare(chess.File(f), chess.Rank(r))) != chess.NoPiece {
+			return false
+		}
+		f, r = f+stepF, r+stepR
+	}
+
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	default:
+		return 0
+	}
+}
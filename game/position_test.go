@@ -0,0 +1,232 @@
+package game
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func perft(p *Position, depth int) int {
+	moves := p.LegalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+
+	nodes := 0
+	for _, m := range moves {
+		nodes += perft(p.Update(m), depth-1)
+	}
+
+	return nodes
+}
+
+func TestPerft(t *testing.T) {
+	// node counts from the standard perft suites, the chess960 positions are from Reinhard Scharnagl's
+	// and the third castles with the king and rook side by side straight away
+	tests := []struct {
+		name    string
+		variant Variant
+		fen     string
+		nodes   []int
+	}{
+		{"start", Standard, standardFEN, []int{20, 400, 8902, 197281}},
+		{"kiwipete", Standard, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []int{48, 2039, 97862}},
+		{"chess960 1", Chess960, "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []int{21, 528, 12189}},
+		{"chess960 2", Chess960, "2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []int{21, 807, 18002}},
+		{"chess960 3", Chess960, "b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []int{20, 479, 10471}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position, err := parsePosition(tt.fen, tt.variant)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, want := range tt.nodes {
+				if got := perft(position, i+1); got != want {
+					t.Errorf("perft(%d) = %d, want %d", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCastling(t *testing.T) {
+	// want is the castles on offer by their UCI, for chess960 that's the king taking its own rook
+	tests := []struct {
+		name    string
+		variant Variant
+		fen     string
+		want    []string
+		// after is the board once the first wanted castle is played
+		after string
+	}{
+		{"king and rook side by side", Chess960, "4k3/8/8/8/8/8/8/5KR1 w G - 0 1", []string{"f1g1"}, "4k3/8/8/8/8/8/8/5RK1"},
+		{"king already on its square", Chess960, "4k3/8/8/8/8/8/8/6KR w H - 0 1", []string{"g1h1"}, "4k3/8/8/8/8/8/8/5RK1"},
+		{"rook already on its square", Chess960, "4k3/8/8/8/8/8/8/4KR2 w F - 0 1", []string{"e1f1"}, "4k3/8/8/8/8/8/8/5RK1"},
+		{"queenside from b1 and d1", Chess960, "4k3/8/8/8/8/8/8/1R1K4 w B - 0 1", []string{"d1b1"}, "4k3/8/8/8/8/8/8/2KR4"},
+		{"through an attacked square", Standard, "4kr2/8/8/8/8/8/8/R3K2R w KQ - 0 1", []string{"e1c1"}, "4kr2/8/8/8/8/8/8/2KR3R"},
+		{"onto an attacked square", Standard, "4k1r1/8/8/8/8/8/8/4K2R w K - 0 1", nil, ""},
+		{"out of check", Standard, "4k3/8/8/8/8/8/8/r3K2R w K - 0 1", nil, ""},
+		{"rook shields the kings path", Chess960, "k7/8/8/8/8/8/8/qR1K4 w B - 0 1", nil, ""},
+		{"a piece on the rooks square", Chess960, "4k3/8/8/8/8/8/8/RK1N4 w A - 0 1", nil, ""},
+		{"a piece on the kings square", Chess960, "4k3/8/8/8/8/8/8/1RNK4 w B - 0 1", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position, err := parsePosition(tt.fen, tt.variant)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, m := range position.castlingMoves() {
+				got = append(got, position.UCI(m))
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("castles = %v, want %v", got, tt.want)
+			}
+
+			if len(tt.want) == 0 {
+				return
+			}
+
+			move, err := position.ParseMove(tt.want[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			next := position.Update(move)
+			if board := strings.Fields(next.String())[0]; board != tt.after {
+				t.Fatalf("board after %s = %s, want %s", tt.want[0], board, tt.after)
+			}
+
+			if next.castles.rook(chess.White, chess.KingSide) != chess.NoSquare || next.castles.rook(chess.White, chess.QueenSide) != chess.NoSquare {
+				t.Fatalf("white can still castle after castling: %s", next)
+			}
+		})
+	}
+}
+
+// drops are the squares the side to move can drop on, sorted by their UCI
+func drops(p *Position) []string {
+	var uci []string
+	for _, m := range p.LegalMoves() {
+		if m.isDrop() {
+			uci = append(uci, p.UCI(m))
+		}
+	}
+	slices.Sort(uci)
+
+	return uci
+}
+
+func TestCrazyhouseDrops(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want []string
+	}{
+		// a rook on a1 checks along the back rank, only the squares between can block it
+		{"blocking a check", "4k3/8/8/8/8/8/8/r3K3[N] w - - 0 1", []string{"N@b1", "N@c1", "N@d1"}},
+		// a knight check can't be blocked
+		{"knight check", "4k3/8/8/8/8/3n4/8/4K3[Q] w - - 0 1", nil},
+		{"double check", "4k3/8/8/8/8/3n4/8/r3K3[QN] w - - 0 1", nil},
+		// with nothing in pocket the other sides pieces can't be dropped
+		{"empty pocket", "4k3/8/8/8/8/8/8/r3K3[n] w - - 0 1", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position, err := parsePosition(tt.fen, Crazyhouse)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := drops(position); !slices.Equal(got, tt.want) {
+				t.Fatalf("drops = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrazyhousePawnDrops(t *testing.T) {
+	position, err := parsePosition("4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", Crazyhouse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := drops(position)
+	if len(got) != 48 {
+		t.Fatalf("got %d pawn drops, want every empty square on ranks 2 to 7", len(got))
+	}
+
+	for _, drop := range got {
+		if rank := drop[len(drop)-1]; rank == '1' || rank == '8' {
+			t.Fatalf("pawn dropped on the back rank: %s", drop)
+		}
+	}
+
+	if _, err := position.ParseMove("P@a8"); err == nil {
+		t.Fatal("P@a8 was allowed")
+	}
+}
+
+func TestCrazyhousePromotedCaptures(t *testing.T) {
+	game := playMoves(t, Crazyhouse, "1r6/P3k3/8/8/8/8/8/4K3[] w - - 0 1", "a8=Q")
+
+	// the promoted queen is marked so it goes back to being a pawn when taken
+	if want := "Q~r6/4k3/8/8/8/8/8/4K3[] b - - 0 1"; game.FEN() != want {
+		t.Fatalf("after a8=Q FEN = %s, want %s", game.FEN(), want)
+	}
+
+	if err := game.MoveStr("Rxa8"); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "r7/4k3/8/8/8/8/8/4K3[p] w - - 0 2"; game.FEN() != want {
+		t.Fatalf("after Rxa8 FEN = %s, want %s", game.FEN(), want)
+	}
+
+	// a promoted piece keeps its mark when it moves
+	game = playMoves(t, Crazyhouse, "4k3/8/8/8/8/8/8/Q~3K3[] w - - 0 1", "Qa2", "Kd7")
+	if want := "8/3k4/8/8/8/8/Q~7/4K3[] w - - 2 2"; game.FEN() != want {
+		t.Fatalf("after Qa2 FEN = %s, want %s", game.FEN(), want)
+	}
+}
+
+func TestCrazyhouseFEN(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1",
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R[Pp] w KQkq - 0 4",
+		"4k3/8/8/8/8/8/4q~3/4K3[QRRBNPPqbnnp] w - - 0 1",
+		"Q~3k3/8/8/8/8/8/8/4K2n~[] b - - 0 1",
+	}
+
+	for _, fen := range fens {
+		position, err := parsePosition(fen, Crazyhouse)
+		if err != nil {
+			t.Fatalf("parsePosition(%s): %v", fen, err)
+		}
+
+		if got := position.String(); got != fen {
+			t.Errorf("round trip of %s = %s", fen, got)
+		}
+	}
+
+	for _, field := range []string{
+		"4k3/8/8/8/8/8/8/4K3[K]",
+		"4k3/8/8/8/8/8/8/4K3[Q",
+		"~4k3/8/8/8/8/8/8/4K3[]",
+		"4k3/8/8/8/8/8/8/4K3[X]",
+	} {
+		if _, _, _, err := parseCrazyhouseBoard(field); err == nil {
+			t.Errorf("parseCrazyhouseBoard(%s) = nil error", field)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package game
+
+import (
+	"errors"
+	"time"
+
+	"github.com/michaelgov-ctrl/bad-chess/internal/models"
+	"github.com/michaelgov-ctrl/bad-chess/internal/rating"
+)
+
+func (tc TimeControl) Category() rating.Category {
+	return rating.CategoryFor(tc.EstimatedDuration())
+}
+
+// RatingCategory rates every variant on its own so variant games never move a players standard ratings,
+// standard chess keeps the plain time control categories
+func RatingCategory(variant Variant, timeControl TimeControl) rating.Category {
+	if variant == nil || variant == Standard {
+		return timeControl.Category()
+	}
+
+	return rating.Category(variant.String() + "-" + string(timeControl.Category()))
+}
+
+// Rating is the clients rating in a category, or the starting rating if they haven't played in it
+func (c *Client) Rating(category rating.Category) rating.Rating {
+	if r, ok := c.ratings[category]; ok {
+		return r
+	}
+
+	return rating.Default()
+}
+
+// loadRatings is done as a client connects so seating them never waits on the store
+func (o *ManagerOptions) loadRatings(c *Client) {
+	if o.ratingStore == nil || c.UserId() == "" {
+		return
+	}
+
+	records, err := o.ratingStore.All(c.UserId())
+	if err != nil {
+		o.logger.Error("failed to load ratings", "error", err)
+		return
+	}
+
+	c.ratings = make(map[rating.Category]rating.Rating, len(records))
+	for _, record := range records {
+		c.ratings[rating.Category(record.Category)] = ratingFromRecord(record)
+	}
+}
+
+// loadClient looks up everything shown about a client as they connect, their ratings and username
+func (o *ManagerOptions) loadClient(c *Client) {
+	o.loadRatings(c)
+	c.name = o.username(c.UserId())
+}
+
+// username is empty for anonymous users or when there's no user store
+func (o *ManagerOptions) username(userId string) string {
+	if o.userStore == nil || userId == "" {
+		return ""
+	}
+
+	user, err := o.userStore.Get(userId)
+	if err != nil {
+		o.logger.Error("failed to load user", "error", err)
+		return ""
+	}
+
+	return user.Username
+}
+
+// userRating reads a rating straight from the store for players who may not be connected
+func (o *ManagerOptions) userRating(userId string, category rating.Category) rating.Rating {
+	if o.ratingStore == nil {
+		return rating.Default()
+	}
+
+	record, err := o.storedRating(userId, string(category))
+	if err != nil {
+		o.logger.Error("failed to load rating", "error", err)
+		return rating.Default()
+	}
+
+	return ratingFromRecord(record)
+}
+
+// updateRatings is called outside of any manager locks alongside storing the match records
+func (o *ManagerOptions) updateRatings(records []models.MatchRecord) {
+	if o.ratingStore == nil {
+		return
+	}
+
+	for _, record := range records {
+		if err := o.updateRating(record); err != nil {
+			o.logger.Error("failed to update ratings", "MatchId", record.ID, "error", err)
+		}
+	}
+}
+
+func (o *ManagerOptions) updateRating(record models.MatchRecord) error {
+	// only games paired by rating from the usual starting position say anything about a players strength
+	if record.Assisted || record.CustomStart || record.Private {
+		return nil
+	}
+
+	var lightScore float64
+	switch record.Outcome {
+	case LightWon:
+		lightScore = 1
+	case DarkWon:
+		lightScore = 0
+	case Draw:
+		lightScore = 0.5
+	default:
+		// aborted and abandoned matches aren't rated
+		return nil
+	}
+
+	if record.LightPlayer == "" || record.DarkPlayer == "" || record.LightPlayer == record.DarkPlayer {
+		return nil
+	}
+
+	timeControl, err := ParseTimeControl(record.TimeControl)
+	if err != nil {
+		return err
+	}
+
+	variant, err := ParseVariant(record.Variant)
+	if err != nil {
+		return err
+	}
+	category := string(RatingCategory(variant, timeControl))
+
+	light, err := o.storedRating(record.LightPlayer, category)
+	if err != nil {
+		return err
+	}
+
+	dark, err := o.storedRating(record.DarkPlayer, category)
+	if err != nil {
+		return err
+	}
+
+	lightRating, darkRating := ratingFromRecord(light), ratingFromRecord(dark)
+	newLight := rating.Update(lightRating, []rating.Result{{Opponent: darkRating, Score: lightScore}})
+	newDark := rating.Update(darkRating, []rating.Result{{Opponent: lightRating, Score: 1 - lightScore}})
+
+	if err := o.ratingStore.Upsert(recordFromRating(light, newLight)); err != nil {
+		return err
+	}
+
+	return o.ratingStore.Upsert(recordFromRating(dark, newDark))
+}
+
+func (o *ManagerOptions) storedRating(userId, category string) (models.RatingRecord, error) {
+	record, err := o.ratingStore.Get(userId, category)
+	if errors.Is(err, models.ErrNoRecord) {
+		return models.RatingRecord{
+			UserID:     userId,
+			Category:   category,
+			Rating:     rating.DefaultRating,
+			Deviation:  rating.DefaultDeviation,
+			Volatility: rating.DefaultVolatility,
+		}, nil
+	}
+
+	return record, err
+}
+
+func ratingFromRecord(record models.RatingRecord) rating.Rating {
+	return rating.Rating{
+		Rating:     record.Rating,
+		Deviation:  record.Deviation,
+		Volatility: record.Volatility,
+	}
+}
+
+// recordFromRating counts the game that produced r towards the players record
+func recordFromRating(record models.RatingRecord, r rating.Rating) models.RatingRecord {
+	record.Rating = r.Rating
+	record.Deviation = r.Deviation
+	record.Volatility = r.Volatility
+	record.Games++
+	record.UpdatedAt = time.Now()
+
+	return record
+}
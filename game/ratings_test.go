@@ -0,0 +1,72 @@
+package game
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/michaelgov-ctrl/bad-chess/internal/models"
+	"github.com/michaelgov-ctrl/bad-chess/internal/rating"
+)
+
+func TestUpdateRating(t *testing.T) {
+	rated := models.MatchRecord{
+		ID:          "match",
+		MatchType:   Matchmaking.String(),
+		LightPlayer: "alice",
+		DarkPlayer:  "bob",
+		TimeControl: "5+0",
+		Variant:     Standard.String(),
+		Outcome:     LightWon,
+	}
+
+	// wantCategory is empty when the match shouldn't be rated
+	tests := []struct {
+		name         string
+		edit         func(*models.MatchRecord)
+		wantCategory string
+	}{
+		{"paired from the starting position", func(*models.MatchRecord) {}, "blitz"},
+		{"stored before variants were recorded", func(r *models.MatchRecord) { r.Variant = "" }, "blitz"},
+		{"chess960", func(r *models.MatchRecord) { r.Variant = Chess960.String() }, "chess960-blitz"},
+		{"three-check", func(r *models.MatchRecord) { r.Variant = ThreeCheck.String() }, ThreeCheck.String() + "-blitz"},
+		{"assisted", func(r *models.MatchRecord) { r.Assisted = true }, ""},
+		{"custom starting position", func(r *models.MatchRecord) { r.CustomStart = true }, ""},
+		{"private", func(r *models.MatchRecord) { r.Private = true }, ""},
+		{"aborted", func(r *models.MatchRecord) { r.Outcome = "*" }, ""},
+		{"against themselves", func(r *models.MatchRecord) { r.DarkPlayer = "alice" }, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := models.NewInMemoryRatingModel()
+			o := &ManagerOptions{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), ratingStore: store}
+
+			record := rated
+			tt.edit(&record)
+			if err := o.updateRating(record); err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := store.All("alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantCategory == "" {
+				if len(records) != 0 {
+					t.Fatalf("unrated match gave alice ratings %+v", records)
+				}
+				return
+			}
+
+			if len(records) != 1 || records[0].Category != tt.wantCategory {
+				t.Fatalf("alice has ratings %+v, want one in %s", records, tt.wantCategory)
+			}
+
+			if records[0].Rating <= rating.DefaultRating {
+				t.Fatalf("alice won but was rated %v", records[0].Rating)
+			}
+		})
+	}
+}
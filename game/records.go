@@ -0,0 +1,80 @@
+package game
+
+import (
+	"time"
+
+	"github.com/michaelgov-ctrl/bad-chess/internal/models"
+)
+
+// EnginePlayerName stands in for a user id on the engines side of a match record
+const EnginePlayerName = "engine"
+
+func (m *Match) Record(outcome MatchOutcome) models.MatchRecord {
+	return models.MatchRecord{
+		ID:          string(m.ID),
+		MatchType:   Matchmaking.String(),
+		LightPlayer: playerUserId(m.LightPlayer),
+		DarkPlayer:  playerUserId(m.DarkPlayer),
+		TimeControl: m.TimeControl.String(),
+		Variant:     m.Game.Variant().String(),
+		PGN:         m.encodePGN(outcome.Outcome, outcome.Method),
+		Outcome:     outcome.Outcome,
+		Method:      outcome.Method,
+		CustomStart: m.Game.CustomStart(),
+		Private:     m.Private,
+		StartedAt:   m.StartedAt,
+		EndedAt:     time.Now(),
+	}
+}
+
+func (m *EngineMatch) Record(outcome EngineMatchOutcome) models.MatchRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record := models.MatchRecord{
+		ID:          string(m.ID),
+		MatchType:   Engine.String(),
+		LightPlayer: EnginePlayerName,
+		DarkPlayer:  EnginePlayerName,
+		TimeControl: m.TimeControl.String(),
+		Variant:     m.Game.Variant().String(),
+		EngineELO:   m.ELO,
+		PGN:         m.encodePGN(outcome.Outcome, outcome.Method),
+		Outcome:     outcome.Outcome,
+		Method:      outcome.Method,
+		Assisted:    m.assisted,
+		CustomStart: m.Game.CustomStart(),
+		StartedAt:   m.StartedAt,
+		EndedAt:     time.Now(),
+	}
+
+	switch m.PlayerPieces {
+	case Light:
+		record.LightPlayer = playerUserId(m.Player)
+	case Dark:
+		record.DarkPlayer = playerUserId(m.Player)
+	}
+
+	return record
+}
+
+func playerUserId(p *Player) string {
+	if p == nil {
+		return ""
+	}
+
+	return p.UserId
+}
+
+// storeMatchRecords is called outside of any manager locks since it may be writing to disk
+func (o *ManagerOptions) storeMatchRecords(records []models.MatchRecord) {
+	if o.matchStore == nil {
+		return
+	}
+
+	for _, record := range records {
+		if err := o.matchStore.Insert(record); err != nil {
+			o.logger.Error("failed to store match record", "MatchId", record.ID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"errors"
+	"time"
+
+	"github.com/michaelgov-ctrl/bad-chess/internal/rating"
+)
+
+// ScheduledMatchWaitTime is how long both players of a scheduled match have to turn up
+var ScheduledMatchWaitTime = 5 * time.Minute
+
+// ScheduledMatch seats two users in a match up front, e.g. for a tournament round. Players take their
+// seats by connecting to the matchmaking websocket and the match starts once both have, the outcome
+// is sent on Results once the match has been cleaned up
+type ScheduledMatch struct {
+	LightUserId string
+	DarkUserId  string
+	TimeControl TimeControl
+	Variant     Variant
+	Results     chan<- MatchOutcome
+	// BerserkAllowed lets either player halve their own clock before their first move
+	BerserkAllowed bool
+	// DarkTime gives dark less (or more) time than the time control, for armageddon games
+	DarkTime time.Duration
+}
+
+type scheduledResult struct {
+	results chan<- MatchOutcome
+	outcome MatchOutcome
+}
+
+// ScheduleMatch creates a match for two users who are sent to it by whatever scheduled it. If only one of
+// them turns up in time they win by forfeit
+func (m *MatchmakingManager) ScheduleMatch(req ScheduledMatch) (MatchId, error) {
+	if _, ok := SupportedTimeControls[req.TimeControl]; !ok {
+		return "", errors.New("unsupported time control")
+	}
+
+	if req.LightUserId == "" || req.DarkUserId == "" || req.LightUserId == req.DarkUserId {
+		return "", errors.New("a scheduled match needs two different players")
+	}
+
+	if req.DarkTime < 0 {
+		return "", errors.New("a scheduled match can't give a player negative time")
+	}
+
+	if req.Variant == nil {
+		req.Variant = Standard
+	}
+
+	game, err := newGame(req.Variant, "")
+	if err != nil {
+		return "", err
+	}
+
+	category := RatingCategory(req.Variant, req.TimeControl)
+	light := &Player{UserId: req.LightUserId, Name: m.username(req.LightUserId), Rating: m.userRating(req.LightUserId, category)}
+	dark := &Player{UserId: req.DarkUserId, Name: m.username(req.DarkUserId), Rating: m.userRating(req.DarkUserId, category)}
+
+	m.matchesMu.Lock()
+	defer m.matchesMu.Unlock()
+
+	match := m.buildMatch(req.TimeControl, game)
+	match.Scheduled = true
+	match.BerserkAllowed = req.BerserkAllowed
+	match.darkBase = req.DarkTime
+	match.LightPlayer, match.DarkPlayer = light, dark
+	match.results = req.Results
+
+	return m.addMatch(match), nil
+}
+
+// UserRating is a users rating for a variant and time control whether or not they're connected
+func (m *MatchmakingManager) UserRating(userId string, variant Variant, timeControl TimeControl) rating.Rating {
+	return m.userRating(userId, RatingCategory(variant, timeControl))
+}
+
+// reportScheduledResults doesn't wait on whoever scheduled the match so cleanup is never held up
+func reportScheduledResults(results []scheduledResult) {
+	for _, result := range results {
+		go func() {
+			result.results <- result.outcome
+		}()
+	}
+}
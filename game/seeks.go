@@ -0,0 +1,368 @@
+package game
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/michaelgov-ctrl/bad-chess/internal/rating"
+)
+
+var (
+	// a seek starts out only accepting opponents within InitialSeekWindow rating points
+	// and widens by SeekWindowStep every SeekWindowInterval it goes unanswered
+	InitialSeekWindow  = 100.0
+	SeekWindowStep     = 50.0
+	SeekWindowInterval = 5 * time.Second
+
+	// SeekLifetime is how long a seek stays open before it expires, by then its window takes in everyone
+	SeekLifetime = 10 * time.Minute
+
+	ErrAlreadySeeking = errors.New("already seeking a match")
+	ErrNoSeek         = errors.New("no open seek")
+)
+
+type seek struct {
+	id          string
+	client      *Client
+	variant     Variant
+	timeControl TimeControl
+	// color is the pieces the seeker wants, NoColor if they don't mind
+	color     PieceColor
+	rating    rating.Rating
+	createdAt time.Time
+}
+
+func newSeek(c *Client, variant Variant, timeControl TimeControl, color PieceColor) *seek {
+	return &seek{
+		id:          uuid.NewString(),
+		client:      c,
+		variant:     variant,
+		timeControl: timeControl,
+		color:       color,
+		rating:      c.Rating(RatingCategory(variant, timeControl)),
+		createdAt:   time.Now(),
+	}
+}
+
+// seekColor reads the pieces a seeker asked for, empty or random means either
+func seekColor(color string) (PieceColor, error) {
+	if color == "" || color == "random" {
+		return NoColor, nil
+	}
+
+	return PieceColorFromString(color)
+}
+
+func (s *seek) lobbySeek() LobbySeek {
+	color := "random"
+	if s.color != NoColor {
+		color = s.color.String()
+	}
+
+	return LobbySeek{
+		ID:          s.id,
+		Creator:     s.client.displayName(),
+		Rating:      s.rating.String(),
+		TimeControl: s.timeControl,
+		Variant:     s.variant.String(),
+		Color:       color,
+		CreatedAt:   s.createdAt,
+	}
+}
+
+func (s *seek) event() SeekEvent {
+	return SeekEvent{
+		TimeControl: s.timeControl,
+		Variant:     s.variant.String(),
+		Rating:      s.rating.String(),
+	}
+}
+
+// seekBucket is what a seek wants to play, seeks are only ever paired within the same bucket
+type seekBucket struct {
+	variant     Variant
+	timeControl TimeControl
+}
+
+func (s *seek) bucket() seekBucket {
+	return seekBucket{variant: s.variant, timeControl: s.timeControl}
+}
+
+// window is how far apart in rating an opponent may be, it widens the longer the seek waits
+func (s *seek) window(now time.Time) float64 {
+	steps := math.Floor(float64(now.Sub(s.createdAt)) / float64(SeekWindowInterval))
+	return InitialSeekWindow + steps*SeekWindowStep
+}
+
+// accepts only pairs seeks that are both willing to play each other and don't want the same pieces. seeks
+// are held per connection so the same user seeking from two tabs mustn't be paired with themselves
+func (s *seek) accepts(other *seek, now time.Time) bool {
+	if s.client.UserId() != "" && s.client.UserId() == other.client.UserId() {
+		return false
+	}
+
+	if s.color != NoColor && s.color == other.color {
+		return false
+	}
+
+	diff := math.Abs(s.rating.Rating - other.rating.Rating)
+	return diff <= s.window(now) && diff <= other.window(now)
+}
+
+// seekQueue holds the open seeks for a bucket oldest first, so the longest waiting player is always offered an opponent first
+type seekQueue []*seek
+
+func (q seekQueue) remove(s *seek) seekQueue {
+	return slices.DeleteFunc(q, func(queued *seek) bool {
+		return queued == s
+	})
+}
+
+// insert keeps the queue ordered by when each seek was made, used to put a seek back in its old place
+func (q seekQueue) insert(s *seek) seekQueue {
+	i, _ := slices.BinarySearchFunc(q, s, func(queued, target *seek) int {
+		return queued.createdAt.Compare(target.createdAt)
+	})
+
+	return slices.Insert(q, i, s)
+}
+
+type seekPair struct {
+	light *seek
+	dark  *seek
+}
+
+// newSeekPair gives the older seek light unless either seeker asked for the other side
+func newSeekPair(older, newer *seek) seekPair {
+	if older.color == Dark || newer.color == Light {
+		return seekPair{light: newer, dark: older}
+	}
+
+	return seekPair{light: older, dark: newer}
+}
+
+// addSeek answers the oldest compatible seek or joins the back of the queue, it returns the opponents seek if one was found
+func (m *MatchmakingManager) addSeek(s *seek) (*seek, error) {
+	m.seeksMu.Lock()
+	defer m.seeksMu.Unlock()
+
+	if _, ok := m.seekers[s.client]; ok {
+		return nil, ErrAlreadySeeking
+	}
+
+	now, bucket := time.Now(), s.bucket()
+	for _, queued := range m.seeks[bucket] {
+		if queued.accepts(s, now) {
+			m.seeks[bucket] = m.seeks[bucket].remove(queued)
+			delete(m.seekers, queued.client)
+			m.lobby.seekClosed(queued, SeekAccepted)
+
+			return queued, nil
+		}
+	}
+
+	m.seeks[bucket] = append(m.seeks[bucket], s)
+	m.seekers[s.client] = s
+	m.lobby.seekOpened(s)
+
+	return nil, nil
+}
+
+func (m *MatchmakingManager) requeueSeek(s *seek) {
+	m.seeksMu.Lock()
+	defer m.seeksMu.Unlock()
+
+	if _, ok := m.seekers[s.client]; ok {
+		return
+	}
+
+	m.seeks[s.bucket()] = m.seeks[s.bucket()].insert(s)
+	m.seekers[s.client] = s
+	m.lobby.seekOpened(s)
+}
+
+func (m *MatchmakingManager) removeSeek(c *Client) (*seek, bool) {
+	m.seeksMu.Lock()
+	defer m.seeksMu.Unlock()
+
+	s, ok := m.seekers[c]
+	if !ok {
+		return nil, false
+	}
+
+	m.seeks[s.bucket()] = m.seeks[s.bucket()].remove(s)
+	delete(m.seekers, c)
+	m.lobby.seekClosed(s, SeekCancelled)
+
+	return s, true
+}
+
+// takeSeek removes a seek picked from the lobby so nobody else can pair with it
+func (m *MatchmakingManager) takeSeek(id, userId string) (*seek, error) {
+	m.seeksMu.Lock()
+	defer m.seeksMu.Unlock()
+
+	for _, s := range m.seekers {
+		if s.id != id {
+			continue
+		}
+
+		if s.client.UserId() == userId {
+			return nil, ErrOwnSeek
+		}
+
+		m.seeks[s.bucket()] = m.seeks[s.bucket()].remove(s)
+		delete(m.seekers, s.client)
+		m.lobby.seekClosed(s, SeekAccepted)
+
+		return s, nil
+	}
+
+	return nil, ErrNoSeek
+}
+
+// expireSeeks closes every seek that has been open longer than SeekLifetime
+func (m *MatchmakingManager) expireSeeks() []*seek {
+	m.seeksMu.Lock()
+	defer m.seeksMu.Unlock()
+
+	var expired []*seek
+	now := time.Now()
+	for c, s := range m.seekers {
+		if now.Sub(s.createdAt) < SeekLifetime {
+			continue
+		}
+
+		m.seeks[s.bucket()] = m.seeks[s.bucket()].remove(s)
+		delete(m.seekers, c)
+		m.lobby.seekClosed(s, SeekExpired)
+		expired = append(expired, s)
+	}
+
+	return expired
+}
+
+// collectSeekPairs pairs up waiting seeks whose windows have grown enough to accept each other
+func (m *MatchmakingManager) collectSeekPairs() []seekPair {
+	m.seeksMu.Lock()
+	defer m.seeksMu.Unlock()
+
+	var pairs []seekPair
+	now := time.Now()
+	for bucket, queue := range m.seeks {
+		paired := make(map[*seek]bool)
+		for i, older := range queue {
+			if paired[older] {
+				continue
+			}
+
+			for _, newer := range queue[i+1:] {
+				if paired[newer] || !older.accepts(newer, now) {
+					continue
+				}
+
+				paired[older], paired[newer] = true, true
+				pairs = append(pairs, newSeekPair(older, newer))
+				break
+			}
+		}
+
+		if len(paired) == 0 {
+			continue
+		}
+
+		m.seeks[bucket] = slices.DeleteFunc(queue, func(s *seek) bool {
+			if paired[s] {
+				delete(m.seekers, s.client)
+				m.lobby.seekClosed(s, SeekAccepted)
+				return true
+			}
+			return false
+		})
+	}
+
+	return pairs
+}
+
+// matchSeeks periodically retries pairing since windows keep widening while seeks wait, seeks nobody
+// answers in time are expired
+func (m *MatchmakingManager) matchSeeks() {
+	ticker := time.NewTicker(SeekWindowInterval)
+	for range ticker.C {
+		for _, pair := range m.collectSeekPairs() {
+			if err := m.startSeekMatch(pair); err != nil {
+				m.logger.Error("failed to start match from seeks", "error", err)
+			}
+		}
+
+		for _, s := range m.expireSeeks() {
+			outgoingEvent, err := NewOutgoingEvent(EventSeekExpired, s.event())
+			if err != nil {
+				m.logger.Error("failed to create seek expired event", "error", err)
+				continue
+			}
+
+			select {
+			case s.client.egress <- outgoingEvent:
+			default:
+			}
+		}
+	}
+}
+
+// startSeekMatch seats a pair of seeks in a new match, if either player has left in the meantime
+// the other keeps their place in the queue
+func (m *MatchmakingManager) startSeekMatch(pair seekPair) error {
+	m.matchesMu.Lock()
+
+	m.clientsMu.RLock()
+	_, lightConnected := m.clients[pair.light.client]
+	_, darkConnected := m.clients[pair.dark.client]
+	m.clientsMu.RUnlock()
+
+	if !lightConnected || !darkConnected {
+		m.matchesMu.Unlock()
+
+		if lightConnected {
+			m.requeueSeek(pair.light)
+		}
+
+		if darkConnected {
+			m.requeueSeek(pair.dark)
+		}
+
+		return nil
+	}
+
+	defer m.matchesMu.Unlock()
+
+	game, err := newGame(pair.light.variant, "")
+	if err != nil {
+		return err
+	}
+
+	timeControl := pair.light.timeControl
+	matchId := m.newMatch(timeControl, false, game)
+
+	seats := []struct {
+		pieces PieceColor
+		seek   *seek
+	}{
+		{Light, pair.light},
+		{Dark, pair.dark},
+	}
+
+	for _, seat := range seats {
+		seat.seek.client.setMatchInfo(NewClientMatchInfo(matchId, Matchmaking, timeControl, 0, seat.pieces))
+		if err := m.addClientToMatch(seat.seek.client); err != nil {
+			return err
+		}
+	}
+
+	m.metrics.totalMatches.Inc()
+
+	return m.startMatch(m.matches[timeControl][matchId])
+}
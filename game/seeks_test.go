@@ -0,0 +1,48 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/michaelgov-ctrl/bad-chess/internal/rating"
+)
+
+func testSeek(userId string, rated float64, color PieceColor, createdAt time.Time) *seek {
+	r := rating.Default()
+	r.Rating = rated
+
+	return &seek{
+		client:    &Client{userId: userId},
+		variant:   Standard,
+		color:     color,
+		rating:    r,
+		createdAt: createdAt,
+	}
+}
+
+func TestSeekAccepts(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		older *seek
+		newer *seek
+		want  bool
+	}{
+		{"close ratings", testSeek("alice", 1500, NoColor, now), testSeek("bob", 1550, NoColor, now), true},
+		{"same user from another connection", testSeek("alice", 1500, NoColor, now), testSeek("alice", 1500, NoColor, now), false},
+		{"both want light", testSeek("alice", 1500, Light, now), testSeek("bob", 1500, Light, now), false},
+		{"opposite colors", testSeek("alice", 1500, Light, now), testSeek("bob", 1500, Dark, now), true},
+		{"outside the window", testSeek("alice", 1500, NoColor, now), testSeek("bob", 1700, NoColor, now), false},
+		{"both windows have widened", testSeek("alice", 1500, NoColor, now.Add(-3*SeekWindowInterval)), testSeek("bob", 1700, NoColor, now.Add(-2*SeekWindowInterval)), true},
+		{"only one window has widened", testSeek("alice", 1500, NoColor, now.Add(-3*SeekWindowInterval)), testSeek("bob", 1700, NoColor, now), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.older.accepts(tt.newer, now); got != tt.want {
+				t.Errorf("accepts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
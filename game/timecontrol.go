@@ -0,0 +1,168 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidTimeControl = errors.New("invalid time control")
+
+type DelayMode int
+
+const (
+	NoDelay DelayMode = iota
+	// the increment is added to the clock after every move
+	FischerIncrement
+	// the time used on a move is given back after it, up to the increment
+	BronsteinDelay
+	// the clock does not start counting down until the increment has passed each move
+	SimpleDelay
+)
+
+// the separators used in the string form of a time control, e.g. 3+2, 5d3 or 5b3
+var delayModeSeparators = map[DelayMode]string{
+	NoDelay:          "+",
+	FischerIncrement: "+",
+	BronsteinDelay:   "b",
+	SimpleDelay:      "d",
+}
+
+// movesPerGameEstimate is used to fold the increment into how long a game is expected to last
+const movesPerGameEstimate = 40
+
+type TimeControl struct {
+	Base      time.Duration
+	Increment time.Duration
+	Mode      DelayMode
+}
+
+func NewTimeControl(base, increment time.Duration, mode DelayMode) TimeControl {
+	// without any increment every mode plays the same
+	if increment == 0 {
+		mode = NoDelay
+	}
+
+	return TimeControl{Base: base, Increment: increment, Mode: mode}
+}
+
+// ParseTimeControl reads the string form of a time control, base minutes followed by
+// the delay mode separator and increment seconds, e.g. 3+2, 5d3 or 5b3
+func ParseTimeControl(s string) (TimeControl, error) {
+	// a + from a query string may have been decoded into a space
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "+")
+
+	i := strings.IndexAny(s, "+bd")
+	if i < 0 {
+		minutes, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			// fall back to the older duration form, e.g. 3m0s
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return TimeControl{}, ErrInvalidTimeControl
+			}
+
+			return NewTimeControl(d, 0, NoDelay), nil
+		}
+
+		return NewTimeControl(minutesToDuration(minutes), 0, NoDelay), nil
+	}
+
+	minutes, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || minutes <= 0 {
+		return TimeControl{}, ErrInvalidTimeControl
+	}
+
+	seconds, err := strconv.ParseFloat(s[i+1:], 64)
+	if err != nil || seconds < 0 {
+		return TimeControl{}, ErrInvalidTimeControl
+	}
+
+	var mode DelayMode
+	switch s[i] {
+	case '+':
+		mode = FischerIncrement
+	case 'b':
+		mode = BronsteinDelay
+	case 'd':
+		mode = SimpleDelay
+	}
+
+	return NewTimeControl(minutesToDuration(minutes), time.Duration(seconds*float64(time.Second)), mode), nil
+}
+
+func minutesToDuration(minutes float64) time.Duration {
+	return time.Duration(minutes * float64(time.Minute))
+}
+
+func (tc TimeControl) String() string {
+	return fmt.Sprintf("%s%s%s",
+		strconv.FormatFloat(tc.Base.Minutes(), 'f', -1, 64),
+		delayModeSeparators[tc.Mode],
+		strconv.FormatFloat(tc.Increment.Seconds(), 'f', -1, 64),
+	)
+}
+
+func (tc TimeControl) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tc.String())
+}
+
+func (tc *TimeControl) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch value := v.(type) {
+	case string:
+		tmp, err := ParseTimeControl(value)
+		if err != nil {
+			return err
+		}
+
+		if !SupportedTimeControls[tmp] {
+			return errors.New("unsupported time control")
+		}
+
+		*tc = tmp
+		return nil
+	default:
+		return ErrInvalidTimeControl
+	}
+}
+
+// PGNString is the TimeControl tag form of a time control, base and increment in seconds, e.g. 180+2
+func (tc TimeControl) PGNString() string {
+	if tc.Increment == 0 {
+		return strconv.FormatFloat(tc.Base.Seconds(), 'f', -1, 64)
+	}
+
+	return fmt.Sprintf("%s+%s",
+		strconv.FormatFloat(tc.Base.Seconds(), 'f', -1, 64),
+		strconv.FormatFloat(tc.Increment.Seconds(), 'f', -1, 64),
+	)
+}
+
+func (tc TimeControl) ToDuration() time.Duration {
+	return tc.Base
+}
+
+// EstimatedDuration is how long one players clock is expected to last over a typical game
+func (tc TimeControl) EstimatedDuration() time.Duration {
+	return tc.Base + movesPerGameEstimate*tc.Increment
+}
+
+func (tc TimeControl) Less(other TimeControl) bool {
+	if tc.EstimatedDuration() != other.EstimatedDuration() {
+		return tc.EstimatedDuration() < other.EstimatedDuration()
+	}
+
+	if tc.Base != other.Base {
+		return tc.Base < other.Base
+	}
+
+	return tc.Mode < other.Mode
+}
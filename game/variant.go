@@ -0,0 +1,273 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+var ErrUnsupportedVariant = errors.New("unsupported variant")
+
+// MatchOutcome methods for games won or drawn by a variants own rules
+const (
+	MethodKingInCenter = "king in the center"
+	MethodThreeChecks  = "three checks"
+	MethodRaceWon      = "race won"
+	MethodRaceTied     = "race tied"
+)
+
+// Variant is a set of rules layered on top of standard chess
+type Variant interface {
+	// String is the variants name in requests and events
+	String() string
+	// PGNName is the value of the PGN Variant tag
+	PGNName() string
+	// UCIVariant is the UCI_Variant value engines know the variant by, chess for anything a standard engine can play
+	UCIVariant() string
+	Chess960() bool
+	// StartingFEN is where a new game of the variant begins
+	StartingFEN() string
+	// Legal filters out moves the variant forbids on top of the usual rules
+	Legal(position *Position, move *Move) bool
+	// Outcome ends the game by the variants own rules, method is what gets reported in MatchOutcome
+	Outcome(position *Position) (outcome chess.Outcome, method string)
+}
+
+var (
+	Standard      Variant = standard{}
+	Chess960      Variant = chess960{}
+	KingOfTheHill Variant = kingOfTheHill{}
+	ThreeCheck    Variant = threeCheck{}
+	RacingKings   Variant = racingKings{}
+	Crazyhouse    Variant = crazyhouse{}
+)
+
+// SupportedVariants is every variant that can be played, in the order they're offered to players
+var SupportedVariants = []Variant{Standard, Chess960, KingOfTheHill, ThreeCheck, RacingKings, Crazyhouse}
+
+// ParseVariant looks a variant up by name, an empty name is standard chess so older clients don't need to send one
+func ParseVariant(name string) (Variant, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return Standard, nil
+	}
+
+	for _, variant := range SupportedVariants {
+		if variant.String() == name {
+			return variant, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedVariant, name)
+}
+
+const standardFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+type standard struct{}
+
+func (standard) String() string              { return "standard" }
+func (standard) PGNName() string             { return "Standard" }
+func (standard) UCIVariant() string          { return "chess" }
+func (standard) Chess960() bool              { return false }
+func (standard) StartingFEN() string         { return standardFEN }
+func (standard) Legal(*Position, *Move) bool { return true }
+
+func (standard) Outcome(*Position) (chess.Outcome, string) {
+	return chess.NoOutcome, ""
+}
+
+// chess960 starts from one of 960 shuffled back ranks, castling puts the king and rook on the usual squares
+type chess960 struct {
+	standard
+}
+
+func (chess960) String() string      { return "chess960" }
+func (chess960) PGNName() string     { return "Chess960" }
+func (chess960) Chess960() bool      { return true }
+func (chess960) StartingFEN() string { return chess960FEN(rand.Intn(960)) }
+
+// kingOfTheHill is also won by getting your king to one of the four center squares
+type kingOfTheHill struct {
+	standard
+}
+
+func (kingOfTheHill) String() string     { return "kingofthehill" }
+func (kingOfTheHill) PGNName() string    { return "King of the Hill" }
+func (kingOfTheHill) UCIVariant() string { return "kingofthehill" }
+
+func (kingOfTheHill) Outcome(position *Position) (chess.Outcome, string) {
+	mover := position.Turn().Other()
+
+	switch kingSquare(position.Board(), mover) {
+	case chess.D4, chess.E4, chess.D5, chess.E5:
+		return winFor(mover), MethodKingInCenter
+	default:
+		return chess.NoOutcome, ""
+	}
+}
+
+// ThreeCheckLimit is how many checks win a three-check game
+const ThreeCheckLimit = 3
+
+// threeCheck is also won by checking the other king three times
+type threeCheck struct {
+	standard
+}
+
+func (threeCheck) String() string     { return "threecheck" }
+func (threeCheck) PGNName() string    { return "Three-check" }
+func (threeCheck) UCIVariant() string { return "3check" }
+
+func (threeCheck) StartingFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 3+3 0 1"
+}
+
+func (threeCheck) Outcome(position *Position) (chess.Outcome, string) {
+	mover := position.Turn().Other()
+	if position.checks[mover-1] >= ThreeCheckLimit {
+		return winFor(mover), MethodThreeChecks
+	}
+
+	return chess.NoOutcome, ""
+}
+
+// parseChecksLeft reads how many checks each side still needs to give, written like 3+3 after the en passant square
+func parseChecksLeft(field string) ([2]int, error) {
+	light, dark, ok := strings.Cut(field, "+")
+	if !ok {
+		return [2]int{}, fmt.Errorf("invalid checks left %q", field)
+	}
+
+	var checks [2]int
+	for i, s := range []string{light, dark} {
+		left, err := strconv.Atoi(s)
+		if err != nil || left < 0 || ThreeCheckLimit < left {
+			return [2]int{}, fmt.Errorf("invalid checks left %q", field)
+		}
+
+		checks[i] = ThreeCheckLimit - left
+	}
+
+	return checks, nil
+}
+
+func checksLeftString(checks [2]int) string {
+	return fmt.Sprintf("%d+%d", max(ThreeCheckLimit-checks[0], 0), max(ThreeCheckLimit-checks[1], 0))
+}
+
+// racingKings is a race to the eighth rank where nobody may give check, when light gets
+// there first dark still has one move to get there too and tie
+type racingKings struct {
+	standard
+}
+
+func (racingKings) String() string      { return "racingkings" }
+func (racingKings) PGNName() string     { return "Racing Kings" }
+func (racingKings) UCIVariant() string  { return "racingkings" }
+func (racingKings) StartingFEN() string { return "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1" }
+
+func (racingKings) Legal(position *Position, move *Move) bool {
+	return !position.Update(move).InCheck()
+}
+
+func (racingKings) Outcome(position *Position) (chess.Outcome, string) {
+	board := position.Board()
+	lightHome := kingSquare(board, chess.White).Rank() == chess.Rank8
+	darkHome := kingSquare(board, chess.Black).Rank() == chess.Rank8
+
+	switch {
+	case lightHome && darkHome:
+		return chess.Draw, MethodRaceTied
+	case darkHome:
+		return chess.BlackWon, MethodRaceWon
+	case lightHome && position.Turn() == chess.Black && kingCanReachRank8(position):
+		return chess.NoOutcome, ""
+	case lightHome:
+		return chess.WhiteWon, MethodRaceWon
+	default:
+		return chess.NoOutcome, ""
+	}
+}
+
+func kingCanReachRank8(position *Position) bool {
+	for _, move := range position.LegalMoves() {
+		if position.Board().Piece(move.from).Type() == chess.King && move.to.Rank() == chess.Rank8 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// crazyhouse lets captured pieces be dropped back on the board by whoever took them, the moves themselves live in Position
+type crazyhouse struct {
+	standard
+}
+
+func (crazyhouse) String() string     { return "crazyhouse" }
+func (crazyhouse) PGNName() string    { return "Crazyhouse" }
+func (crazyhouse) UCIVariant() string { return "crazyhouse" }
+
+func (crazyhouse) StartingFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1"
+}
+
+func winFor(color chess.Color) chess.Outcome {
+	if color == chess.Black {
+		return chess.BlackWon
+	}
+
+	return chess.WhiteWon
+}
+
+// the knights go on two of the five squares left after the bishops and queen, indexed as in the 960 numbering
+var chess960Knights = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2},
+	{1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// chess960FEN is the starting position numbered n in the standard 960 numbering, 518 is the usual setup
+func chess960FEN(n int) string {
+	var rank [8]byte
+
+	n, lightBishop := n/4, n%4
+	rank[2*lightBishop+1] = 'B'
+
+	n, darkBishop := n/4, n%4
+	rank[2*darkBishop] = 'B'
+
+	n, queen := n/6, n%6
+	placeOnEmpty(&rank, queen, 'Q')
+
+	// the second knight is placed first so the first knights index isn't shifted
+	knights := chess960Knights[n]
+	placeOnEmpty(&rank, knights[1], 'N')
+	placeOnEmpty(&rank, knights[0], 'N')
+
+	// the three squares left are always rook, king, rook
+	for _, piece := range []byte{'R', 'K', 'R'} {
+		placeOnEmpty(&rank, 0, piece)
+	}
+
+	back := string(rank[:])
+	return strings.ToLower(back) + "/pppppppp/8/8/8/8/PPPPPPPP/" + back + " w KQkq - 0 1"
+}
+
+// placeOnEmpty puts piece on the i-th empty square counting from the a file
+func placeOnEmpty(rank *[8]byte, i int, piece byte) {
+	for f := range rank {
+		if rank[f] != 0 {
+			continue
+		}
+
+		if i == 0 {
+			rank[f] = piece
+			return
+		}
+		i--
+	}
+}
@@ -0,0 +1,65 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestVariantOutcomes(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		fen     string
+		moves   []string
+		outcome chess.Outcome
+		method  string
+	}{
+		{"light king reaches the hill", KingOfTheHill, "4k3/8/8/8/8/4K3/8/8 w - - 0 1", []string{"Kd4"}, chess.WhiteWon, MethodKingInCenter},
+		{"dark king reaches the hill", KingOfTheHill, "8/8/3k4/8/8/8/8/4K3 b - - 0 1", []string{"Ke5"}, chess.BlackWon, MethodKingInCenter},
+		{"king next to the hill", KingOfTheHill, "4k3/8/8/8/8/4K3/8/8 w - - 0 1", []string{"Kf4"}, chess.NoOutcome, ""},
+		{"light gives the third check", ThreeCheck, "4k3/8/8/8/8/8/8/R3K3 w - - 1+3 0 1", []string{"Ra8+"}, chess.WhiteWon, MethodThreeChecks},
+		{"dark gives the third check", ThreeCheck, "r3k3/8/8/8/8/8/8/4K3 b - - 3+1 0 1", []string{"Ra1+"}, chess.BlackWon, MethodThreeChecks},
+		{"second check plays on", ThreeCheck, "4k3/8/8/8/8/8/8/R3K3 w - - 2+3 0 1", []string{"Ra8+"}, chess.NoOutcome, ""},
+		{"dark wins the race", RacingKings, "8/1k6/8/8/8/8/8/7K b - - 0 1", []string{"Kb8"}, chess.BlackWon, MethodRaceWon},
+		{"dark gets a move to catch up", RacingKings, "8/1K4k1/8/8/8/8/8/8 w - - 0 1", []string{"Kb8"}, chess.NoOutcome, ""},
+		{"dark catches up", RacingKings, "8/1K4k1/8/8/8/8/8/8 w - - 0 1", []string{"Kb8", "Kg8"}, chess.Draw, MethodRaceTied},
+		{"dark can't catch up", RacingKings, "8/1K6/8/8/6k1/8/8/8 w - - 0 1", []string{"Kb8"}, chess.WhiteWon, MethodRaceWon},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := playMoves(t, tt.variant, tt.fen, tt.moves...)
+
+			if game.Outcome() != tt.outcome {
+				t.Fatalf("outcome = %s, want %s", game.Outcome(), tt.outcome)
+			}
+
+			if tt.method != "" && game.OutcomeMethod() != tt.method {
+				t.Fatalf("method = %q, want %q", game.OutcomeMethod(), tt.method)
+			}
+		})
+	}
+}
+
+func TestThreeCheckCountsChecks(t *testing.T) {
+	game := playMoves(t, ThreeCheck, "4k3/8/8/8/8/8/8/R3K3 w - - 2+3 0 1", "Ra8+")
+
+	if want := "R3k3/8/8/8/8/8/8/4K3 b - - 1+3 1 1"; game.FEN() != want {
+		t.Fatalf("FEN = %s, want %s", game.FEN(), want)
+	}
+}
+
+func TestRacingKingsForbidsCheck(t *testing.T) {
+	game := playMoves(t, RacingKings, "8/8/8/8/8/8/k7/1R5K w - - 0 1")
+
+	for _, move := range []string{"Rb2", "Ra1"} {
+		if err := game.MoveStr(move); err == nil {
+			t.Fatalf("%s gives check but was allowed", move)
+		}
+	}
+
+	if err := game.MoveStr("Rb3"); err != nil {
+		t.Fatalf("Rb3: %v", err)
+	}
+}
@@ -8,7 +8,7 @@ import (
 
 var (
 	websocketUpgrader = websocket.Upgrader{
-		CheckOrigin:     checkOrigin,
+		CheckOrigin:     CheckOrigin,
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
 	}
@@ -23,7 +23,8 @@ var (
 )
 
 // TODO: update this for proxy
-func checkOrigin(r *http.Request) bool {
+// CheckOrigin is shared with the tournament websockets
+func CheckOrigin(r *http.Request) bool {
 	return true
 	/*
 		origin := r.Header.Get("Origin")
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// AnalysisRecord holds a finished matches engine analysis, Analysis is the json served to clients
+type AnalysisRecord struct {
+	MatchID   string
+	Status    string
+	Analysis  string
+	UpdatedAt time.Time
+}
+
+type AnalysisStore interface {
+	Get(matchId string) (AnalysisRecord, error)
+	Upsert(record AnalysisRecord) error
+}
+
+type AnalysisModel struct {
+	DB *sql.DB
+}
+
+func (m *AnalysisModel) Get(matchId string) (AnalysisRecord, error) {
+	stmt := `SELECT match_id, status, analysis, updated_at FROM analyses WHERE match_id = ?`
+
+	var record AnalysisRecord
+	err := m.DB.QueryRow(stmt, matchId).Scan(&record.MatchID, &record.Status, &record.Analysis, &record.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return AnalysisRecord{}, ErrNoRecord
+		}
+
+		return AnalysisRecord{}, err
+	}
+
+	return record, nil
+}
+
+func (m *AnalysisModel) Upsert(record AnalysisRecord) error {
+	stmt := `INSERT INTO analyses (match_id, status, analysis, updated_at)
+	VALUES (?, ?, ?, ?)
+	ON CONFLICT (match_id) DO UPDATE SET
+		status = excluded.status,
+		analysis = excluded.analysis,
+		updated_at = excluded.updated_at`
+
+	_, err := m.DB.Exec(stmt, record.MatchID, record.Status, record.Analysis, record.UpdatedAt.UTC())
+
+	return err
+}
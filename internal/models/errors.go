@@ -0,0 +1,7 @@
+package models
+
+import "errors"
+
+var (
+	ErrNoRecord = errors.New("models: no matching record found")
+)
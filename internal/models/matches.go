@@ -0,0 +1,130 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type MatchRecord struct {
+	ID          string
+	MatchType   string
+	LightPlayer string
+	DarkPlayer  string
+	TimeControl string
+	Variant     string
+	EngineELO   int
+	PGN         string
+	Outcome     string
+	Method      string
+	Assisted    bool // hints or takebacks were used, assisted matches are never rated
+	CustomStart bool // the players set up the starting position, like assisted these are never rated
+	Private     bool // played over a shared link rather than paired by rating, never rated
+	StartedAt   time.Time
+	EndedAt     time.Time
+}
+
+type MatchStore interface {
+	Insert(record MatchRecord) error
+	Get(id string) (MatchRecord, error)
+	Latest(limit int) ([]MatchRecord, error)
+}
+
+type MatchModel struct {
+	DB *sql.DB
+}
+
+func (m *MatchModel) Insert(record MatchRecord) error {
+	stmt := `INSERT INTO matches (id, match_type, light_player, dark_player, time_control, engine_elo, pgn, outcome, method, variant, assisted, custom_start, private, started_at, ended_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := m.DB.Exec(stmt,
+		record.ID,
+		record.MatchType,
+		record.LightPlayer,
+		record.DarkPlayer,
+		record.TimeControl,
+		record.EngineELO,
+		record.PGN,
+		record.Outcome,
+		record.Method,
+		record.Variant,
+		record.Assisted,
+		record.CustomStart,
+		record.Private,
+		record.StartedAt.UTC(),
+		record.EndedAt.UTC(),
+	)
+
+	return err
+}
+
+func (m *MatchModel) Get(id string) (MatchRecord, error) {
+	stmt := `SELECT id, match_type, light_player, dark_player, time_control, engine_elo, pgn, outcome, method, variant, assisted, custom_start, private, started_at, ended_at
+	FROM matches WHERE id = ?`
+
+	record, err := scanMatchRecord(m.DB.QueryRow(stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return MatchRecord{}, ErrNoRecord
+		}
+
+		return MatchRecord{}, err
+	}
+
+	return record, nil
+}
+
+func (m *MatchModel) Latest(limit int) ([]MatchRecord, error) {
+	stmt := `SELECT id, match_type, light_player, dark_player, time_control, engine_elo, pgn, outcome, method, variant, assisted, custom_start, private, started_at, ended_at
+	FROM matches ORDER BY ended_at DESC LIMIT ?`
+
+	rows, err := m.DB.Query(stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []MatchRecord
+	for rows.Next() {
+		record, err := scanMatchRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMatchRecord(row rowScanner) (MatchRecord, error) {
+	var record MatchRecord
+	err := row.Scan(
+		&record.ID,
+		&record.MatchType,
+		&record.LightPlayer,
+		&record.DarkPlayer,
+		&record.TimeControl,
+		&record.EngineELO,
+		&record.PGN,
+		&record.Outcome,
+		&record.Method,
+		&record.Variant,
+		&record.Assisted,
+		&record.CustomStart,
+		&record.Private,
+		&record.StartedAt,
+		&record.EndedAt,
+	)
+
+	return record, err
+}
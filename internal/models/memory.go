@@ -0,0 +1,258 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InMemoryMatchModel keeps match records in a slice in the order they finished
+type InMemoryMatchModel struct {
+	records []MatchRecord
+	sync.RWMutex
+}
+
+func NewInMemoryMatchModel() *InMemoryMatchModel {
+	return &InMemoryMatchModel{}
+}
+
+func (m *InMemoryMatchModel) Insert(record MatchRecord) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.records = append(m.records, record)
+
+	return nil
+}
+
+func (m *InMemoryMatchModel) Get(id string) (MatchRecord, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	for _, record := range m.records {
+		if record.ID == id {
+			return record, nil
+		}
+	}
+
+	return MatchRecord{}, ErrNoRecord
+}
+
+func (m *InMemoryMatchModel) Latest(limit int) ([]MatchRecord, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	records := slices.Clone(m.records)
+	slices.SortStableFunc(records, func(a, b MatchRecord) int {
+		return b.EndedAt.Compare(a.EndedAt)
+	})
+
+	if len(records) > limit {
+		records = records[:limit]
+	}
+
+	return records, nil
+}
+
+type ratingKey struct {
+	userId   string
+	category string
+}
+
+// InMemoryRatingModel holds one rating per user and category like the ratings table's primary key
+type InMemoryRatingModel struct {
+	records map[ratingKey]RatingRecord
+	sync.RWMutex
+}
+
+func NewInMemoryRatingModel() *InMemoryRatingModel {
+	return &InMemoryRatingModel{
+		records: make(map[ratingKey]RatingRecord),
+	}
+}
+
+func (m *InMemoryRatingModel) Get(userId, category string) (RatingRecord, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	record, ok := m.records[ratingKey{userId, category}]
+	if !ok {
+		return RatingRecord{}, ErrNoRecord
+	}
+
+	return record, nil
+}
+
+func (m *InMemoryRatingModel) All(userId string) ([]RatingRecord, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	var records []RatingRecord
+	for key, record := range m.records {
+		if key.userId == userId {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+func (m *InMemoryRatingModel) Upsert(record RatingRecord) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.records[ratingKey{record.UserID, record.Category}] = record
+
+	return nil
+}
+
+// InMemoryAnalysisModel replaces a matches analysis on every upsert
+type InMemoryAnalysisModel struct {
+	records map[string]AnalysisRecord
+	sync.RWMutex
+}
+
+func NewInMemoryAnalysisModel() *InMemoryAnalysisModel {
+	return &InMemoryAnalysisModel{
+		records: make(map[string]AnalysisRecord),
+	}
+}
+
+func (m *InMemoryAnalysisModel) Get(matchId string) (AnalysisRecord, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	record, ok := m.records[matchId]
+	if !ok {
+		return AnalysisRecord{}, ErrNoRecord
+	}
+
+	return record, nil
+}
+
+func (m *InMemoryAnalysisModel) Upsert(record AnalysisRecord) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.records[record.MatchID] = record
+
+	return nil
+}
+
+// InMemoryUserModel hashes passwords the same as UserModel so credentials behave alike
+type InMemoryUserModel struct {
+	users map[string]User
+	sync.RWMutex
+}
+
+func NewInMemoryUserModel() *InMemoryUserModel {
+	return &InMemoryUserModel{
+		users: make(map[string]User),
+	}
+}
+
+func (m *InMemoryUserModel) Insert(username, password string) (string, error) {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return "", err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.findByUsername(username); ok {
+		return "", ErrDuplicateUsername
+	}
+
+	user := User{
+		ID:             uuid.NewString(),
+		Username:       username,
+		HashedPassword: hashedPassword,
+		Created:        time.Now(),
+	}
+	m.users[user.ID] = user
+
+	return user.ID, nil
+}
+
+func (m *InMemoryUserModel) Authenticate(username, password string) (string, error) {
+	m.RLock()
+	user, ok := m.findByUsername(username)
+	m.RUnlock()
+
+	if !ok {
+		return "", ErrInvalidCredentials
+	}
+
+	if err := checkPassword(user.HashedPassword, password); err != nil {
+		return "", err
+	}
+
+	return user.ID, nil
+}
+
+func (m *InMemoryUserModel) Exists(id string) (bool, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	_, ok := m.users[id]
+	return ok, nil
+}
+
+func (m *InMemoryUserModel) Get(id string) (User, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return User{}, ErrNoRecord
+	}
+
+	return user, nil
+}
+
+func (m *InMemoryUserModel) UsernameTaken(username string) (bool, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	_, ok := m.findByUsername(username)
+	return ok, nil
+}
+
+func (m *InMemoryUserModel) PasswordUpdate(id, currentPassword, newPassword string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return ErrNoRecord
+	}
+
+	if err := checkPassword(user.HashedPassword, currentPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	user.HashedPassword = hashedPassword
+	m.users[id] = user
+
+	return nil
+}
+
+// usernames are unique regardless of case to match the sqlite schema, callers must hold the lock
+func (m *InMemoryUserModel) findByUsername(username string) (User, bool) {
+	for _, user := range m.users {
+		if strings.EqualFold(user.Username, username) {
+			return user, true
+		}
+	}
+
+	return User{}, false
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInMemoryMatchModel(t *testing.T) {
+	m := NewInMemoryMatchModel()
+	now := time.Now()
+
+	for i, id := range []string{"first", "second", "third"} {
+		if err := m.Insert(MatchRecord{ID: id, EndedAt: now.Add(time.Duration(i) * time.Minute)}); err != nil {
+			t.Fatalf("Insert(%s): %v", id, err)
+		}
+	}
+
+	record, err := m.Get("second")
+	if err != nil || record.ID != "second" {
+		t.Fatalf("Get(second) = %v, %v", record.ID, err)
+	}
+
+	if _, err := m.Get("missing"); !errors.Is(err, ErrNoRecord) {
+		t.Fatalf("Get(missing) error = %v, want ErrNoRecord", err)
+	}
+
+	latest, err := m.Latest(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(latest) != 2 || latest[0].ID != "third" || latest[1].ID != "second" {
+		t.Fatalf("Latest(2) = %v, want third then second", latest)
+	}
+}
+
+func TestInMemoryRatingModel(t *testing.T) {
+	m := NewInMemoryRatingModel()
+
+	if _, err := m.Get("alice", "blitz"); !errors.Is(err, ErrNoRecord) {
+		t.Fatalf("Get before upsert error = %v, want ErrNoRecord", err)
+	}
+
+	records := []RatingRecord{
+		{UserID: "alice", Category: "blitz", Rating: 1500, Games: 1},
+		{UserID: "alice", Category: "blitz", Rating: 1520, Games: 2},
+		{UserID: "alice", Category: "rapid", Rating: 1480, Games: 1},
+		{UserID: "bob", Category: "blitz", Rating: 1600, Games: 1},
+	}
+	for _, record := range records {
+		if err := m.Upsert(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	record, err := m.Get("alice", "blitz")
+	if err != nil || record.Rating != 1520 || record.Games != 2 {
+		t.Fatalf("Get(alice, blitz) = %+v, %v, want the second upsert", record, err)
+	}
+
+	all, err := m.All("alice")
+	if err != nil || len(all) != 2 {
+		t.Fatalf("All(alice) = %v, %v, want 2 categories", all, err)
+	}
+}
+
+func TestInMemoryAnalysisModel(t *testing.T) {
+	m := NewInMemoryAnalysisModel()
+
+	if _, err := m.Get("match"); !errors.Is(err, ErrNoRecord) {
+		t.Fatalf("Get before upsert error = %v, want ErrNoRecord", err)
+	}
+
+	for _, status := range []string{"pending", "done"} {
+		if err := m.Upsert(AnalysisRecord{MatchID: "match", Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	record, err := m.Get("match")
+	if err != nil || record.Status != "done" {
+		t.Fatalf("Get(match) = %+v, %v, want status done", record, err)
+	}
+}
+
+func TestInMemoryUserModel(t *testing.T) {
+	m := NewInMemoryUserModel()
+
+	id, err := m.Insert("Alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantErr  error
+	}{
+		{"correct credentials", "Alice", "correct horse", nil},
+		{"username ignores case", "alice", "correct horse", nil},
+		{"wrong password", "Alice", "battery staple", ErrInvalidCredentials},
+		{"unknown user", "bob", "correct horse", ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Authenticate(tt.username, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && got != id {
+				t.Fatalf("Authenticate() = %q, want %q", got, id)
+			}
+		})
+	}
+
+	if _, err := m.Insert("ALICE", "another password"); !errors.Is(err, ErrDuplicateUsername) {
+		t.Fatalf("Insert duplicate error = %v, want ErrDuplicateUsername", err)
+	}
+
+	if taken, _ := m.UsernameTaken("aLiCe"); !taken {
+		t.Fatal("UsernameTaken(aLiCe) = false, want true")
+	}
+
+	if err := m.PasswordUpdate(id, "wrong", "new password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("PasswordUpdate with wrong password error = %v, want ErrInvalidCredentials", err)
+	}
+
+	if err := m.PasswordUpdate(id, "correct horse", "new password"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Authenticate("Alice", "new password"); err != nil {
+		t.Fatalf("Authenticate after update: %v", err)
+	}
+
+	if exists, _ := m.Exists(id); !exists {
+		t.Fatal("Exists() = false, want true")
+	}
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type RatingRecord struct {
+	UserID     string
+	Category   string
+	Rating     float64
+	Deviation  float64
+	Volatility float64
+	Games      int
+	UpdatedAt  time.Time
+}
+
+type RatingStore interface {
+	Get(userId, category string) (RatingRecord, error)
+	All(userId string) ([]RatingRecord, error)
+	Upsert(record RatingRecord) error
+}
+
+type RatingModel struct {
+	DB *sql.DB
+}
+
+func (m *RatingModel) Get(userId, category string) (RatingRecord, error) {
+	stmt := `SELECT user_id, category, rating, deviation, volatility, games, updated_at
+	FROM ratings WHERE user_id = ? AND category = ?`
+
+	record, err := scanRatingRecord(m.DB.QueryRow(stmt, userId, category))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RatingRecord{}, ErrNoRecord
+		}
+
+		return RatingRecord{}, err
+	}
+
+	return record, nil
+}
+
+func (m *RatingModel) All(userId string) ([]RatingRecord, error) {
+	stmt := `SELECT user_id, category, rating, deviation, volatility, games, updated_at
+	FROM ratings WHERE user_id = ?`
+
+	rows, err := m.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []RatingRecord
+	for rows.Next() {
+		record, err := scanRatingRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (m *RatingModel) Upsert(record RatingRecord) error {
+	stmt := `INSERT INTO ratings (user_id, category, rating, deviation, volatility, games, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (user_id, category) DO UPDATE SET
+		rating = excluded.rating,
+		deviation = excluded.deviation,
+		volatility = excluded.volatility,
+		games = excluded.games,
+		updated_at = excluded.updated_at`
+
+	_, err := m.DB.Exec(stmt,
+		record.UserID,
+		record.Category,
+		record.Rating,
+		record.Deviation,
+		record.Volatility,
+		record.Games,
+		record.UpdatedAt.UTC(),
+	)
+
+	return err
+}
+
+func scanRatingRecord(row rowScanner) (RatingRecord, error) {
+	var record RatingRecord
+	err := row.Scan(
+		&record.UserID,
+		&record.Category,
+		&record.Rating,
+		&record.Deviation,
+		&record.Volatility,
+		&record.Games,
+		&record.UpdatedAt,
+	)
+
+	return record, err
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS users (
+	id TEXT NOT NULL PRIMARY KEY,
+	username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+	hashed_password BLOB NOT NULL,
+	created DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS matches (
+	id TEXT NOT NULL PRIMARY KEY,
+	match_type TEXT NOT NULL,
+	light_player TEXT NOT NULL,
+	dark_player TEXT NOT NULL,
+	time_control TEXT NOT NULL,
+	engine_elo INTEGER NOT NULL DEFAULT 0,
+	pgn TEXT NOT NULL,
+	outcome TEXT NOT NULL,
+	method TEXT NOT NULL,
+	variant TEXT NOT NULL DEFAULT 'standard',
+	assisted INTEGER NOT NULL DEFAULT 0,
+	custom_start INTEGER NOT NULL DEFAULT 0,
+	private INTEGER NOT NULL DEFAULT 0,
+	started_at DATETIME NOT NULL,
+	ended_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_matches_ended_at ON matches(ended_at);
+
+CREATE TABLE IF NOT EXISTS ratings (
+	user_id TEXT NOT NULL,
+	category TEXT NOT NULL,
+	rating REAL NOT NULL,
+	deviation REAL NOT NULL,
+	volatility REAL NOT NULL,
+	games INTEGER NOT NULL DEFAULT 0,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (user_id, category)
+);
+
+CREATE TABLE IF NOT EXISTS analyses (
+	match_id TEXT NOT NULL PRIMARY KEY,
+	status TEXT NOT NULL,
+	analysis TEXT NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+`
+
+// columns added to tables after they were first created, sqlite has no ADD COLUMN IF NOT EXISTS
+// so each one is tried on startup and a duplicate column means it's already there
+var addedColumns = []string{
+	`ALTER TABLE matches ADD COLUMN assisted INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE matches ADD COLUMN custom_start INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE matches ADD COLUMN private INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE matches ADD COLUMN variant TEXT NOT NULL DEFAULT 'standard'`,
+}
+
+// Migrate creates any missing tables and columns, it is safe to run on every startup
+func Migrate(db *sql.DB) error {
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+
+	for _, stmt := range addedColumns {
+		if _, err := db.Exec(stmt); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const MaxSessionAge = 3 * time.Hour
+
+// bcrypt only looks at the first 72 bytes of a password
+const MaxPasswordBytes = 72
+
+var (
+	ErrInvalidCredentials = errors.New("models: invalid credentials")
+	ErrDuplicateUsername  = errors.New("models: duplicate username")
+)
+
+type User struct {
+	ID             string
+	Username       string
+	HashedPassword []byte
+	Created        time.Time
+}
+
+type UserStore interface {
+	Insert(username, password string) (string, error)
+	Authenticate(username, password string) (string, error)
+	Exists(id string) (bool, error)
+	Get(id string) (User, error)
+	UsernameTaken(username string) (bool, error)
+	PasswordUpdate(id, currentPassword, newPassword string) error
+}
+
+func hashPassword(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), 12)
+}
+
+// checkPassword maps a mismatched password to ErrInvalidCredentials
+func checkPassword(hashedPassword []byte, password string) error {
+	err := bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrInvalidCredentials
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+type UserModel struct {
+	DB *sql.DB
+}
+
+func (m *UserModel) Insert(username, password string) (string, error) {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return "", err
+	}
+
+	id := uuid.NewString()
+	stmt := `INSERT INTO users (id, username, hashed_password, created) VALUES (?, ?, ?, ?)`
+
+	if _, err := m.DB.Exec(stmt, id, username, hashedPassword, time.Now().UTC()); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: users.username") {
+			return "", ErrDuplicateUsername
+		}
+
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (m *UserModel) Authenticate(username, password string) (string, error) {
+	var id string
+	var hashedPassword []byte
+
+	stmt := `SELECT id, hashed_password FROM users WHERE username = ?`
+
+	if err := m.DB.QueryRow(stmt, username).Scan(&id, &hashedPassword); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrInvalidCredentials
+		}
+
+		return "", err
+	}
+
+	if err := checkPassword(hashedPassword, password); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (m *UserModel) Exists(id string) (bool, error) {
+	var exists bool
+
+	stmt := `SELECT EXISTS(SELECT true FROM users WHERE id = ?)`
+
+	err := m.DB.QueryRow(stmt, id).Scan(&exists)
+	return exists, err
+}
+
+func (m *UserModel) Get(id string) (User, error) {
+	var user User
+
+	stmt := `SELECT id, username, hashed_password, created FROM users WHERE id = ?`
+
+	err := m.DB.QueryRow(stmt, id).Scan(&user.ID, &user.Username, &user.HashedPassword, &user.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, ErrNoRecord
+		}
+
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+func (m *UserModel) UsernameTaken(username string) (bool, error) {
+	var taken bool
+
+	stmt := `SELECT EXISTS(SELECT true FROM users WHERE username = ?)`
+
+	err := m.DB.QueryRow(stmt, username).Scan(&taken)
+	return taken, err
+}
+
+func (m *UserModel) PasswordUpdate(id, currentPassword, newPassword string) error {
+	var hashedPassword []byte
+
+	stmt := `SELECT hashed_password FROM users WHERE id = ?`
+
+	if err := m.DB.QueryRow(stmt, id).Scan(&hashedPassword); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+
+		return err
+	}
+
+	if err := checkPassword(hashedPassword, currentPassword); err != nil {
+		return err
+	}
+
+	newHashedPassword, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	stmt = `UPDATE users SET hashed_password = ? WHERE id = ?`
+
+	_, err = m.DB.Exec(stmt, newHashedPassword, id)
+	return err
+}
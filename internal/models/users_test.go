@@ -0,0 +1,109 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: is its own database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	// twice, migrating has to be safe on every startup
+	for range 2 {
+		if err := Migrate(db); err != nil {
+			t.Fatalf("Migrate: %v", err)
+		}
+	}
+
+	return db
+}
+
+func TestUserStores(t *testing.T) {
+	t.Run("sqlite", func(t *testing.T) { testUserStore(t, &UserModel{DB: newTestDB(t)}) })
+	t.Run("memory", func(t *testing.T) { testUserStore(t, NewInMemoryUserModel()) })
+}
+
+// testUserStore runs the same checks against both stores so the in memory one keeps behaving like sqlite
+func testUserStore(t *testing.T, store UserStore) {
+	id, err := store.Insert("alice", "correct horse")
+	if err != nil {
+		t.Fatalf("Insert(alice): %v", err)
+	}
+
+	if _, err := store.Insert("ALICE", "battery staple"); !errors.Is(err, ErrDuplicateUsername) {
+		t.Fatalf("Insert(ALICE) error = %v, want ErrDuplicateUsername", err)
+	}
+
+	if taken, err := store.UsernameTaken("Alice"); err != nil || !taken {
+		t.Fatalf("UsernameTaken(Alice) = %v, %v, want true", taken, err)
+	}
+
+	if taken, err := store.UsernameTaken("bob"); err != nil || taken {
+		t.Fatalf("UsernameTaken(bob) = %v, %v, want false", taken, err)
+	}
+
+	if exists, err := store.Exists(id); err != nil || !exists {
+		t.Fatalf("Exists(%s) = %v, %v, want true", id, exists, err)
+	}
+
+	if exists, err := store.Exists("missing"); err != nil || exists {
+		t.Fatalf("Exists(missing) = %v, %v, want false", exists, err)
+	}
+
+	user, err := store.Get(id)
+	if err != nil || user.ID != id || user.Username != "alice" || user.Created.IsZero() {
+		t.Fatalf("Get(%s) = %+v, %v", id, user, err)
+	}
+
+	if string(user.HashedPassword) == "correct horse" {
+		t.Fatal("password was stored unhashed")
+	}
+
+	if _, err := store.Get("missing"); !errors.Is(err, ErrNoRecord) {
+		t.Fatalf("Get(missing) error = %v, want ErrNoRecord", err)
+	}
+
+	if got, err := store.Authenticate("alice", "correct horse"); err != nil || got != id {
+		t.Fatalf("Authenticate = %s, %v, want %s", got, err, id)
+	}
+
+	for _, tt := range []struct{ username, password string }{
+		{"alice", "wrong"},
+		{"bob", "correct horse"},
+	} {
+		if _, err := store.Authenticate(tt.username, tt.password); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Authenticate(%s, %s) error = %v, want ErrInvalidCredentials", tt.username, tt.password, err)
+		}
+	}
+
+	if err := store.PasswordUpdate(id, "wrong", "new password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("PasswordUpdate with the wrong password error = %v, want ErrInvalidCredentials", err)
+	}
+
+	if err := store.PasswordUpdate("missing", "correct horse", "new password"); !errors.Is(err, ErrNoRecord) {
+		t.Fatalf("PasswordUpdate(missing) error = %v, want ErrNoRecord", err)
+	}
+
+	if err := store.PasswordUpdate(id, "correct horse", "new password"); err != nil {
+		t.Fatalf("PasswordUpdate: %v", err)
+	}
+
+	if _, err := store.Authenticate("alice", "correct horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Authenticate with the old password error = %v, want ErrInvalidCredentials", err)
+	}
+
+	if got, err := store.Authenticate("alice", "new password"); err != nil || got != id {
+		t.Fatalf("Authenticate with the new password = %s, %v, want %s", got, err, id)
+	}
+}
@@ -0,0 +1,194 @@
+// Package rating implements the Glicko-2 rating system as described in
+// http://www.glicko.net/glicko/glicko2.pdf, with every game treated as its own rating period
+package rating
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	DefaultRating     = 1500.0
+	DefaultDeviation  = 350.0
+	DefaultVolatility = 0.06
+
+	// MinDeviation keeps very active players ratings from freezing in place
+	MinDeviation = 45.0
+	MaxDeviation = DefaultDeviation
+
+	// ratings with a deviation above this are still settling and shown with a ?
+	ProvisionalDeviation = 110.0
+
+	// Tau constrains how much volatility can change, the paper suggests somewhere between 0.3 and 1.2
+	Tau = 0.5
+
+	// glicko2Scale converts between the glicko and glicko-2 scales
+	glicko2Scale = 173.7178
+	// convergenceTolerance is the epsilon used when iterating on the new volatility
+	convergenceTolerance = 0.000001
+)
+
+type Category string
+
+const (
+	Bullet Category = "bullet"
+	Blitz  Category = "blitz"
+	Rapid  Category = "rapid"
+)
+
+// CategoryFor buckets a game by how long one players clock is expected to last
+func CategoryFor(estimated time.Duration) Category {
+	switch {
+	case estimated < 3*time.Minute:
+		return Bullet
+	case estimated < 8*time.Minute:
+		return Blitz
+	default:
+		return Rapid
+	}
+}
+
+type Rating struct {
+	Rating     float64
+	Deviation  float64
+	Volatility float64
+}
+
+func Default() Rating {
+	return Rating{
+		Rating:     DefaultRating,
+		Deviation:  DefaultDeviation,
+		Volatility: DefaultVolatility,
+	}
+}
+
+func (r Rating) Provisional() bool {
+	return r.Deviation > ProvisionalDeviation
+}
+
+func (r Rating) String() string {
+	if r.Provisional() {
+		return fmt.Sprintf("%d?", int(math.Round(r.Rating)))
+	}
+
+	return fmt.Sprintf("%d", int(math.Round(r.Rating)))
+}
+
+// Result is one game from a players point of view, Score is 1 for a win, 0.5 for a draw and 0 for a loss
+type Result struct {
+	Opponent Rating
+	Score    float64
+}
+
+func Win(opponent Rating) Result {
+	return Result{Opponent: opponent, Score: 1}
+}
+
+func Draw(opponent Rating) Result {
+	return Result{Opponent: opponent, Score: 0.5}
+}
+
+func Loss(opponent Rating) Result {
+	return Result{Opponent: opponent, Score: 0}
+}
+
+// Update rates a player over a single rating period, with no results only the deviation grows
+func Update(player Rating, results []Result) Rating {
+	mu, phi := toGlicko2(player)
+	sigma := player.Volatility
+
+	if len(results) == 0 {
+		return fromGlicko2(mu, math.Sqrt(phi*phi+sigma*sigma), sigma)
+	}
+
+	// step 3 & 4: the estimated variance and improvement from the game outcomes
+	var vInv, deltaSum float64
+	for _, result := range results {
+		muJ, phiJ := toGlicko2(result.Opponent)
+		g := gPhi(phiJ)
+		e := expectedScore(mu, muJ, g)
+
+		vInv += g * g * e * (1 - e)
+		deltaSum += g * (result.Score - e)
+	}
+
+	v := 1 / vInv
+	delta := v * deltaSum
+
+	// step 5: the new volatility
+	sigma = newVolatility(sigma, phi, v, delta)
+
+	// step 6 & 7: the new deviation and rating
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*deltaSum
+
+	return fromGlicko2(newMu, newPhi, sigma)
+}
+
+// ExpectedScore is the chance of player beating opponent, counting a draw as half a win
+func ExpectedScore(player, opponent Rating) float64 {
+	mu, _ := toGlicko2(player)
+	muJ, phiJ := toGlicko2(opponent)
+
+	return expectedScore(mu, muJ, gPhi(phiJ))
+}
+
+func toGlicko2(r Rating) (mu, phi float64) {
+	return (r.Rating - DefaultRating) / glicko2Scale, r.Deviation / glicko2Scale
+}
+
+func fromGlicko2(mu, phi, sigma float64) Rating {
+	return Rating{
+		Rating:     mu*glicko2Scale + DefaultRating,
+		Deviation:  math.Min(math.Max(phi*glicko2Scale, MinDeviation), MaxDeviation),
+		Volatility: sigma,
+	}
+}
+
+func gPhi(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expectedScore(mu, muJ, g float64) float64 {
+	return 1 / (1 + math.Exp(-g*(mu-muJ)))
+}
+
+// newVolatility solves for the new volatility with the Illinois algorithm from step 5 of the paper
+func newVolatility(sigma, phi, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(Tau*Tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*Tau) < 0 {
+			k++
+		}
+		B = a - k*Tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > convergenceTolerance {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA = fA / 2
+		}
+
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
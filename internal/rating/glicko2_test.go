@@ -0,0 +1,106 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+// the worked example from section 3 of Glickman's paper
+func TestUpdateGlickmanExample(t *testing.T) {
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	results := []Result{
+		Win(Rating{Rating: 1400, Deviation: 30, Volatility: DefaultVolatility}),
+		Loss(Rating{Rating: 1550, Deviation: 100, Volatility: DefaultVolatility}),
+		Loss(Rating{Rating: 1700, Deviation: 300, Volatility: DefaultVolatility}),
+	}
+
+	got := Update(player, results)
+
+	tests := []struct {
+		name      string
+		got, want float64
+		tolerance float64
+	}{
+		{"rating", got.Rating, 1464.06, 0.01},
+		{"deviation", got.Deviation, 151.52, 0.01},
+		{"volatility", got.Volatility, 0.05999, 0.00001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !near(tt.got, tt.want, tt.tolerance) {
+				t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateWithoutGames(t *testing.T) {
+	tests := []struct {
+		name          string
+		player        Rating
+		wantDeviation float64
+	}{
+		{"deviation grows", Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}, 200.27},
+		{"deviation grows from the floor", Rating{Rating: 1800, Deviation: MinDeviation, Volatility: 0.06}, 46.19},
+		{"deviation is capped", Default(), MaxDeviation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Update(tt.player, nil)
+
+			if got.Rating != tt.player.Rating || got.Volatility != tt.player.Volatility {
+				t.Errorf("Update() = %+v, only the deviation should change from %+v", got, tt.player)
+			}
+
+			if !near(got.Deviation, tt.wantDeviation, 0.01) {
+				t.Errorf("deviation = %v, want %v", got.Deviation, tt.wantDeviation)
+			}
+		})
+	}
+}
+
+func TestNewVolatilityConverges(t *testing.T) {
+	tests := []struct {
+		name                 string
+		sigma, phi, v, delta float64
+		want, wantTolerance  float64
+		rootOnly             bool
+	}{
+		// the intermediate values from step 5 of the paper's example
+		{name: "paper example", sigma: 0.06, phi: 1.1513, v: 1.7785, delta: -0.4834, want: 0.05999, wantTolerance: 0.00001},
+		// delta squared larger than phi squared plus v takes the other branch for the starting bracket
+		{name: "big upset", sigma: 0.06, phi: 0.3, v: 0.5, delta: 2.5, rootOnly: true},
+		// a result close to expectations has to step down from ln(sigma^2) to bracket the root
+		{name: "settled player", sigma: 0.2, phi: 0.26, v: 4, delta: 0.1, rootOnly: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newVolatility(tt.sigma, tt.phi, tt.v, tt.delta)
+
+			if math.IsNaN(got) || math.IsInf(got, 0) || got <= 0 {
+				t.Fatalf("newVolatility() = %v, want a positive finite volatility", got)
+			}
+
+			if !tt.rootOnly && !near(got, tt.want, tt.wantTolerance) {
+				t.Errorf("newVolatility() = %v, want %v", got, tt.want)
+			}
+
+			// the result should be a root of f from step 5
+			a := math.Log(tt.sigma * tt.sigma)
+			x := math.Log(got * got)
+			ex := math.Exp(x)
+			d := tt.phi*tt.phi + tt.v + ex
+			f := ex*(tt.delta*tt.delta-tt.phi*tt.phi-tt.v-ex)/(2*d*d) - (x-a)/(Tau*Tau)
+			if !near(f, 0, 0.0001) {
+				t.Errorf("f(ln(sigma'^2)) = %v, want 0", f)
+			}
+		})
+	}
+}
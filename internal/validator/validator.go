@@ -1,9 +1,18 @@
 package validator
 
 import (
+	"regexp"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
+// UsernameRX allows letters, digits, underscores and hyphens
+var UsernameRX = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+// UniqueChecker reports whether a value is already taken, e.g. a username
+type UniqueChecker func(value string) (bool, error)
+
 type Validator struct {
 	NonFieldErrors []string
 	FieldErrors    map[string]string
@@ -33,6 +42,38 @@ func (v *Validator) CheckField(ok bool, key, msg string) {
 	}
 }
 
+// CheckUnique adds a field error if value is taken, lookup errors are returned for the caller to handle
+func (v *Validator) CheckUnique(value string, taken UniqueChecker, key, msg string) error {
+	isTaken, err := taken(value)
+	if err != nil {
+		return err
+	}
+
+	v.CheckField(!isTaken, key, msg)
+
+	return nil
+}
+
 func NotBlank(value string) bool {
 	return strings.TrimSpace(value) != ""
 }
+
+func MinChars(value string, n int) bool {
+	return utf8.RuneCountInString(value) >= n
+}
+
+func MaxChars(value string, n int) bool {
+	return utf8.RuneCountInString(value) <= n
+}
+
+func MaxBytes(value string, n int) bool {
+	return len(value) <= n
+}
+
+func Matches(value string, rx *regexp.Regexp) bool {
+	return rx.MatchString(value)
+}
+
+func PermittedValue[T comparable](value T, permittedValues ...T) bool {
+	return slices.Contains(permittedValues, value)
+}
@@ -0,0 +1,88 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	var v Validator
+	if !v.Valid() {
+		t.Fatal("a new validator should be valid")
+	}
+
+	v.CheckField(true, "username", "fine")
+	if !v.Valid() {
+		t.Fatal("a passing check added an error")
+	}
+
+	v.CheckField(false, "username", "first")
+	v.CheckField(false, "username", "second")
+	if v.Valid() || v.FieldErrors["username"] != "first" {
+		t.Fatalf("FieldErrors = %v, want the first message kept", v.FieldErrors)
+	}
+
+	v = Validator{}
+	v.AddNonFieldError("bad credentials")
+	if v.Valid() || len(v.NonFieldErrors) != 1 {
+		t.Fatalf("NonFieldErrors = %v, want one error", v.NonFieldErrors)
+	}
+}
+
+func TestCheckUnique(t *testing.T) {
+	taken := func(value string) (bool, error) {
+		switch value {
+		case "broken":
+			return false, errors.New("lookup failed")
+		case "alice":
+			return true, nil
+		}
+
+		return false, nil
+	}
+
+	var v Validator
+	if err := v.CheckUnique("bob", taken, "username", "taken"); err != nil || !v.Valid() {
+		t.Fatalf("CheckUnique(bob) = %v, errors %v", err, v.FieldErrors)
+	}
+
+	if err := v.CheckUnique("alice", taken, "username", "taken"); err != nil || v.FieldErrors["username"] != "taken" {
+		t.Fatalf("CheckUnique(alice) = %v, errors %v", err, v.FieldErrors)
+	}
+
+	v = Validator{}
+	if err := v.CheckUnique("broken", taken, "username", "taken"); err == nil || !v.Valid() {
+		t.Fatalf("CheckUnique(broken) = %v, errors %v, want the lookup error and no field error", err, v.FieldErrors)
+	}
+}
+
+func TestChecks(t *testing.T) {
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"NotBlank", NotBlank("a"), true},
+		{"NotBlank whitespace", NotBlank(" \t\n"), false},
+		{"MinChars", MinChars("abc", 3), true},
+		{"MinChars short", MinChars("ab", 3), false},
+		{"MinChars counts runes", MinChars("é", 2), false},
+		{"MaxChars", MaxChars("abc", 3), true},
+		{"MaxChars long", MaxChars("abcd", 3), false},
+		{"MaxChars counts runes", MaxChars("ééé", 3), true},
+		{"MaxBytes counts bytes", MaxBytes("ééé", 3), false},
+		{"MaxBytes", MaxBytes(strings.Repeat("a", 72), 72), true},
+		{"Matches", Matches("bad_chess-1", UsernameRX), true},
+		{"Matches space", Matches("bad chess", UsernameRX), false},
+		{"Matches empty", Matches("", UsernameRX), false},
+		{"PermittedValue", PermittedValue("blitz", "bullet", "blitz"), true},
+		{"PermittedValue missing", PermittedValue(3, 1, 2), false},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
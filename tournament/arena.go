@@ -0,0 +1,439 @@
+package tournament
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michaelgov-ctrl/bad-chess/game"
+)
+
+const ArenaFormat = "arena"
+
+// arena scoring, once a player has won two in a row their wins and draws count double until they stop winning
+const (
+	arenaWinPoints     = 2
+	arenaDrawPoints    = 1
+	arenaBerserkPoints = 1
+	arenaFireStreak    = 2
+)
+
+// ArenaPairingInterval is how often free players are paired, waiting a moment gives more players a chance to be free
+var ArenaPairingInterval = 5 * time.Second
+
+type ArenaConfig struct {
+	Name        string
+	Organizer   string
+	Duration    time.Duration
+	TimeControl game.TimeControl
+	Variant     game.Variant
+}
+
+// ArenaTournament runs for a fixed time, players in the pool are paired with whoever else is free as soon
+// as they have the tournament open and aren't in a game. Games still being played when the time is up count
+// and the arena finishes once they have all come back
+type ArenaTournament struct {
+	id        string
+	config    ArenaConfig
+	scheduler MatchScheduler
+	logger    *slog.Logger
+	watching  *broadcaster
+
+	mu       sync.Mutex
+	state    State
+	endsAt   time.Time
+	entrants []*Entrant
+	players  map[string]*arenaPlayer
+	// games are the pairings still being played, keyed by their match
+	games map[game.MatchId]*Pairing
+	board int
+
+	results chan game.MatchOutcome
+	done    chan struct{}
+}
+
+type arenaPlayer struct {
+	entrant *Entrant
+	score   int
+	// sheet is the points from each game in the order they were played
+	sheet        []int
+	streak       int
+	playing      bool
+	paused       bool
+	lastOpponent string
+	lightGames   int
+	darkGames    int
+}
+
+func (p *arenaPlayer) onFire() bool {
+	return p.streak >= arenaFireStreak
+}
+
+// record scores a finished game, berserking only pays off with a win
+func (p *arenaPlayer) record(score float64, berserked bool) {
+	points := 0
+	switch score {
+	case 1:
+		points = arenaWinPoints
+	case 0.5:
+		points = arenaDrawPoints
+	}
+
+	if p.onFire() {
+		points *= 2
+	}
+
+	if score == 1 {
+		p.streak++
+		if berserked {
+			points += arenaBerserkPoints
+		}
+	} else {
+		p.streak = 0
+	}
+
+	p.score += points
+	p.sheet = append(p.sheet, points)
+}
+
+func newArenaTournament(id string, config ArenaConfig, scheduler MatchScheduler, logger *slog.Logger) *ArenaTournament {
+	return &ArenaTournament{
+		id:        id,
+		config:    config,
+		scheduler: scheduler,
+		logger:    logger,
+		watching:  newBroadcaster(logger),
+		state:     Registering,
+		players:   make(map[string]*arenaPlayer),
+		games:     make(map[game.MatchId]*Pairing),
+		results:   make(chan game.MatchOutcome),
+		done:      make(chan struct{}),
+	}
+}
+
+func (a *ArenaTournament) watchers() *broadcaster {
+	return a.watching
+}
+
+func (a *ArenaTournament) Info() Info {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.info()
+}
+
+func (a *ArenaTournament) info() Info {
+	info := Info{
+		ID:          a.id,
+		Name:        a.config.Name,
+		Format:      ArenaFormat,
+		Organizer:   a.config.Organizer,
+		State:       a.state,
+		TimeControl: a.config.TimeControl,
+		Variant:     a.config.Variant.String(),
+		Players:     len(a.entrants),
+	}
+
+	if !a.endsAt.IsZero() {
+		endsAt := a.endsAt
+		info.EndsAt = &endsAt
+	}
+
+	return info
+}
+
+func (a *ArenaTournament) Snapshot() Snapshot {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.snapshot()
+}
+
+func (a *ArenaTournament) snapshot() Snapshot {
+	snapshot := Snapshot{
+		Info:      a.info(),
+		Standings: a.leaderboard(),
+	}
+
+	for _, pairing := range a.games {
+		snapshot.Pairings = append(snapshot.Pairings, *pairing)
+	}
+	slices.SortFunc(snapshot.Pairings, func(x, y Pairing) int {
+		return cmp.Compare(x.Board, y.Board)
+	})
+
+	return snapshot
+}
+
+// leaderboard ranks players by score, then by the rating they joined with, callers must hold mu
+func (a *ArenaTournament) leaderboard() []Standing {
+	ranked := slices.Clone(a.entrants)
+	slices.SortStableFunc(ranked, func(x, y *Entrant) int {
+		if c := cmp.Compare(a.players[y.UserId].score, a.players[x.UserId].score); c != 0 {
+			return c
+		}
+		return cmp.Compare(y.Rating.Rating, x.Rating.Rating)
+	})
+
+	table := make([]Standing, len(ranked))
+	for i, entrant := range ranked {
+		player := a.players[entrant.UserId]
+
+		sheet := make([]string, len(player.sheet))
+		for j, points := range player.sheet {
+			sheet[j] = strconv.Itoa(points)
+		}
+
+		table[i] = Standing{
+			Rank:   i + 1,
+			UserId: entrant.UserId,
+			Name:   entrant.Name,
+			Rating: entrant.Rating.String(),
+			Points: float64(player.score),
+			Games:  len(player.sheet),
+			Sheet:  strings.Join(sheet, " "),
+			OnFire: player.onFire(),
+			Paused: player.paused,
+		}
+	}
+
+	return table
+}
+
+// Register adds a player to the pool, players can join late and those who withdrew can come back
+func (a *ArenaTournament) Register(userId, name string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.state == Finished || a.closed() {
+		return ErrRegistrationClosed
+	}
+
+	if player, ok := a.players[userId]; ok {
+		if !player.paused {
+			return ErrAlreadyRegistered
+		}
+
+		player.paused = false
+		a.publishStandings()
+		return nil
+	}
+
+	entrant := &Entrant{
+		UserId: userId,
+		Name:   name,
+		Rating: a.scheduler.UserRating(userId, a.config.Variant, a.config.TimeControl),
+		Seed:   len(a.entrants) + 1,
+	}
+
+	a.entrants = append(a.entrants, entrant)
+	a.players[userId] = &arenaPlayer{entrant: entrant}
+	a.publishStandings()
+
+	return nil
+}
+
+// Withdraw takes a player out of the pool without losing their score, any game they're in still counts
+func (a *ArenaTournament) Withdraw(userId string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	player, ok := a.players[userId]
+	if !ok || player.paused {
+		return ErrNotRegistered
+	}
+
+	player.paused = true
+	a.publishStandings()
+
+	return nil
+}
+
+// closed is whether the arena has stopped pairing, callers must hold mu
+func (a *ArenaTournament) closed() bool {
+	return !a.endsAt.IsZero() && !time.Now().Before(a.endsAt)
+}
+
+func (a *ArenaTournament) Start() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.state != Registering {
+		return ErrAlreadyStarted
+	}
+
+	a.state = Running
+	a.endsAt = time.Now().Add(a.config.Duration)
+	a.publishStandings()
+
+	go a.run()
+
+	return nil
+}
+
+func (a *ArenaTournament) run() {
+	ticker := time.NewTicker(ArenaPairingInterval)
+	defer ticker.Stop()
+
+	end := time.NewTimer(a.config.Duration)
+	defer end.Stop()
+
+	for {
+		select {
+		case outcome := <-a.results:
+			a.recordResult(outcome)
+		case <-ticker.C:
+			a.pairFreePlayers()
+		case <-end.C:
+			a.timeUp()
+		case <-a.done:
+			return
+		}
+	}
+}
+
+func (a *ArenaTournament) timeUp() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.logger.Info("arena time is up", "tournament", a.id, "games", len(a.games))
+
+	if len(a.games) == 0 {
+		a.finish()
+		return
+	}
+
+	a.publishStandings()
+}
+
+// finish is called once the time is up and every game has come back, callers must hold mu
+func (a *ArenaTournament) finish() {
+	a.state = Finished
+	close(a.done)
+	a.publishStandings()
+}
+
+func (a *ArenaTournament) recordResult(outcome game.MatchOutcome) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	pairing, ok := a.games[outcome.ID]
+	if !ok {
+		a.logger.Error("result for unknown tournament match", "tournament", a.id, "MatchId", outcome.ID)
+		return
+	}
+	delete(a.games, outcome.ID)
+
+	pairing.Result = pairingResult(outcome)
+	a.logger.Info("tournament result", "tournament", a.id, "MatchId", outcome.ID, "result", pairing.Result)
+
+	light, dark := a.players[pairing.Light], a.players[pairing.Dark]
+	light.playing, dark.playing = false, false
+
+	// games nobody finished don't count for either player
+	if pairing.Result != DoubleForfeit {
+		lightScore, darkScore := pairing.scores()
+		light.record(lightScore, outcome.LightBerserked)
+		dark.record(darkScore, outcome.DarkBerserked)
+	}
+
+	if a.closed() && len(a.games) == 0 {
+		a.finish()
+		return
+	}
+
+	a.publishStandings()
+}
+
+// pairFreePlayers pairs everyone waiting in the pool from the top of the leaderboard down, avoiding
+// a rematch of their last game unless there's nobody else
+func (a *ArenaTournament) pairFreePlayers() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.state != Running || a.closed() {
+		return
+	}
+
+	var free []*arenaPlayer
+	for _, entrant := range a.entrants {
+		player := a.players[entrant.UserId]
+		if !player.playing && !player.paused && a.watching.present(entrant.UserId) {
+			free = append(free, player)
+		}
+	}
+
+	slices.SortStableFunc(free, func(x, y *arenaPlayer) int {
+		if c := cmp.Compare(y.score, x.score); c != 0 {
+			return c
+		}
+		return cmp.Compare(y.entrant.Rating.Rating, x.entrant.Rating.Rating)
+	})
+
+	paired := false
+	for len(free) >= 2 {
+		player := free[0]
+
+		i := slices.IndexFunc(free[1:], func(opponent *arenaPlayer) bool {
+			return opponent.entrant.UserId != player.lastOpponent && opponent.lastOpponent != player.entrant.UserId
+		}) + 1
+		if i == 0 {
+			i = 1
+		}
+
+		a.pair(player, free[i])
+		free = slices.Delete(free, i, i+1)[1:]
+		paired = true
+	}
+
+	if paired {
+		a.publishStandings()
+	}
+}
+
+// pair schedules a game between two free players, light goes to whoever has had it less. callers must hold mu
+func (a *ArenaTournament) pair(x, y *arenaPlayer) {
+	light, dark := x, y
+	if x.lightGames-x.darkGames > y.lightGames-y.darkGames {
+		light, dark = y, x
+	}
+
+	matchId, err := a.scheduler.ScheduleMatch(game.ScheduledMatch{
+		LightUserId:    light.entrant.UserId,
+		DarkUserId:     dark.entrant.UserId,
+		TimeControl:    a.config.TimeControl,
+		Variant:        a.config.Variant,
+		Results:        a.results,
+		BerserkAllowed: true,
+	})
+	if err != nil {
+		a.logger.Error("failed to schedule tournament match", "tournament", a.id, "error", err)
+		return
+	}
+
+	a.board++
+	pairing := &Pairing{
+		Board:     a.board,
+		MatchID:   matchId,
+		Light:     light.entrant.UserId,
+		LightName: light.entrant.Name,
+		Dark:      dark.entrant.UserId,
+		DarkName:  dark.entrant.Name,
+	}
+	a.games[matchId] = pairing
+
+	light.playing, dark.playing = true, true
+	light.lastOpponent, dark.lastOpponent = dark.entrant.UserId, light.entrant.UserId
+	light.lightGames++
+	dark.darkGames++
+
+	publishPairings(a.watching, a.logger, []*Pairing{pairing})
+}
+
+func (a *ArenaTournament) publishStandings() {
+	publishStandings(a.watching, a.logger, a.snapshot())
+}
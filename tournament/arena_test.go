@@ -0,0 +1,227 @@
+package tournament
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/michaelgov-ctrl/bad-chess/game"
+	"github.com/michaelgov-ctrl/bad-chess/internal/rating"
+)
+
+// fakeScheduler hands out match ids in order and keeps every match it was asked for
+type fakeScheduler struct {
+	mu        sync.Mutex
+	ratings   map[string]float64
+	scheduled []game.ScheduledMatch
+}
+
+func newFakeScheduler(ratings map[string]float64) *fakeScheduler {
+	return &fakeScheduler{ratings: ratings}
+}
+
+func (s *fakeScheduler) ScheduleMatch(req game.ScheduledMatch) (game.MatchId, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scheduled = append(s.scheduled, req)
+	return game.MatchId(fmt.Sprintf("m%d", len(s.scheduled))), nil
+}
+
+func (s *fakeScheduler) UserRating(userId string, variant game.Variant, timeControl game.TimeControl) rating.Rating {
+	r := rating.Default()
+	if value, ok := s.ratings[userId]; ok {
+		r.Rating = value
+	}
+
+	return r
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// watch opens the tournament page for each user, only players watching get paired
+func watch(b *broadcaster, userIds ...string) {
+	for _, userId := range userIds {
+		b.add(&watcher{userId: userId, egress: make(chan game.Event, 64)})
+	}
+}
+
+func TestArenaPlayerRecord(t *testing.T) {
+	results := []struct {
+		score     float64
+		berserked bool
+		points    int
+		onFire    bool
+	}{
+		{1, false, 2, false},
+		{1, false, 2, true},
+		// a draw on fire is doubled but puts the fire out
+		{0.5, false, 2, false},
+		{1, false, 2, false},
+		{1, true, 3, true},
+		// the berserk point isn't doubled
+		{1, true, 5, true},
+		// berserking only pays off with a win
+		{0.5, true, 2, false},
+		{0, true, 0, false},
+	}
+
+	var p arenaPlayer
+	total := 0
+	for i, r := range results {
+		p.record(r.score, r.berserked)
+		total += r.points
+
+		if got := p.sheet[len(p.sheet)-1]; got != r.points || p.onFire() != r.onFire {
+			t.Fatalf("game %d scored %d, on fire %v, want %d and %v", i+1, got, p.onFire(), r.points, r.onFire)
+		}
+	}
+
+	if p.score != total {
+		t.Fatalf("score = %d, want %d", p.score, total)
+	}
+}
+
+// newRunningArena is an arena that has started without its timers, so the test decides when pairing and results happen
+func newRunningArena(t *testing.T, ratings map[string]float64, players ...string) (*ArenaTournament, *fakeScheduler) {
+	t.Helper()
+
+	scheduler := newFakeScheduler(ratings)
+	a := newArenaTournament("arena", ArenaConfig{Duration: time.Hour, Variant: game.Standard}, scheduler, testLogger())
+
+	for _, player := range players {
+		if err := a.Register(player, player); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a.state, a.endsAt = Running, time.Now().Add(time.Hour)
+	watch(a.watching, players...)
+
+	return a, scheduler
+}
+
+// arenaGames are the games in play written light-dark, sorted
+func arenaGames(a *ArenaTournament) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var games []string
+	for _, pairing := range a.games {
+		games = append(games, pairing.Light+"-"+pairing.Dark)
+	}
+	slices.Sort(games)
+
+	return games
+}
+
+func finishArenaGame(t *testing.T, a *ArenaTournament, light, dark, outcome string, lightBerserked bool) {
+	t.Helper()
+
+	a.mu.Lock()
+	var id game.MatchId
+	for matchId, pairing := range a.games {
+		if pairing.Light == light && pairing.Dark == dark {
+			id = matchId
+		}
+	}
+	a.mu.Unlock()
+
+	if id == "" {
+		t.Fatalf("no game %s-%s in %v", light, dark, arenaGames(a))
+	}
+
+	a.recordResult(game.MatchOutcome{ID: id, Outcome: outcome, LightBerserked: lightBerserked})
+}
+
+func TestArenaPairing(t *testing.T) {
+	ratings := map[string]float64{"a": 2000, "b": 1900, "c": 1800, "d": 1700}
+	a, scheduler := newRunningArena(t, ratings, "a", "b", "c", "d")
+
+	a.pairFreePlayers()
+	if got, want := arenaGames(a), []string{"a-b", "c-d"}; !slices.Equal(got, want) {
+		t.Fatalf("first pairing = %v, want %v", got, want)
+	}
+
+	for _, req := range scheduler.scheduled {
+		if !req.BerserkAllowed {
+			t.Fatalf("arena match %+v doesn't allow berserking", req)
+		}
+	}
+
+	finishArenaGame(t, a, "a", "b", game.LightWon, true)
+
+	// with nobody else free a and b play again, and b gets light having had dark
+	a.pairFreePlayers()
+	if got, want := arenaGames(a), []string{"b-a", "c-d"}; !slices.Equal(got, want) {
+		t.Fatalf("rematch = %v, want %v", got, want)
+	}
+
+	finishArenaGame(t, a, "b", "a", game.Draw, false)
+	finishArenaGame(t, a, "c", "d", game.LightWon, false)
+
+	// a and c lead so they meet, b and d each avoid their last opponent
+	a.pairFreePlayers()
+	if got, want := arenaGames(a), []string{"a-c", "d-b"}; !slices.Equal(got, want) {
+		t.Fatalf("third pairing = %v, want %v", got, want)
+	}
+
+	finishArenaGame(t, a, "d", "b", "0-0", false)
+
+	snapshot := a.Snapshot()
+	for _, want := range []struct {
+		userId string
+		points float64
+		games  int
+	}{
+		// a's berserked win is 3, the draw 1
+		{"a", 4, 2},
+		{"c", 2, 1},
+		{"b", 1, 2},
+		// a game nobody finished doesn't count
+		{"d", 0, 1},
+	} {
+		standing, ok := snapshot.standing(want.userId)
+		if !ok || standing.Points != want.points || standing.Games != want.games {
+			t.Errorf("%s standing = %+v, want %v points from %d games", want.userId, standing, want.points, want.games)
+		}
+	}
+}
+
+func TestArenaSkipsAbsentAndPausedPlayers(t *testing.T) {
+	a, _ := newRunningArena(t, nil, "a", "b", "c")
+
+	if err := a.Withdraw("c"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Register("d", "d"); err != nil {
+		t.Fatal(err)
+	}
+
+	// d joined but hasn't opened the page and c withdrew, so only a and b can play
+	a.pairFreePlayers()
+	if got := arenaGames(a); len(got) != 1 || (got[0] != "a-b" && got[0] != "b-a") {
+		t.Fatalf("games = %v, want just a and b", got)
+	}
+
+	a.mu.Lock()
+	a.endsAt = time.Now()
+	a.mu.Unlock()
+
+	watch(a.watching, "d")
+	if err := a.Register("c", "c"); err == nil {
+		t.Fatal("registered after the arena closed")
+	}
+
+	a.pairFreePlayers()
+	if got := arenaGames(a); len(got) != 1 {
+		t.Fatalf("games = %v, a closed arena paired again", got)
+	}
+}
@@ -0,0 +1,238 @@
+package tournament
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/michaelgov-ctrl/bad-chess/game"
+)
+
+// maxPairingSteps bounds the backtracking so a round that can't be paired fails instead of searching forever
+const maxPairingSteps = 100000
+
+// how strongly a player wants a color, an absolute preference can't be ignored without breaking the color rules
+const (
+	noPreference = iota
+	mildPreference
+	strongPreference
+	absolutePreference
+)
+
+// pairingPlayer is a players history as far as pairing is concerned, colors only counts games that were paired
+type pairingPlayer struct {
+	id        string
+	seed      int
+	points    float64
+	opponents map[string]bool
+	colors    []game.PieceColor
+	hadBye    bool
+}
+
+type board struct {
+	light, dark *pairingPlayer
+}
+
+// pairDutch pairs a swiss round roughly the way the dutch system does: players are ranked by points then seed,
+// each score group is split in half with the top half playing the bottom half, and anyone who can't be paired in
+// their group floats down to the next one. Nobody plays the same opponent twice and nobody gets the same color three
+// times in a row or more than two more games with one color than the other, if the color rules make a round impossible
+// they're dropped for that round but repeat pairings never are
+func pairDutch(players []*pairingPlayer) ([]board, *pairingPlayer, error) {
+	ranked := slices.Clone(players)
+	slices.SortStableFunc(ranked, func(a, b *pairingPlayer) int {
+		if c := cmp.Compare(b.points, a.points); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.seed, b.seed)
+	})
+
+	for _, strict := range []bool{true, false} {
+		p := &pairer{strict: strict}
+		if boards, bye, ok := p.pairWithBye(ranked); ok {
+			return alternateFirstColors(boards), bye, nil
+		}
+	}
+
+	return nil, nil, ErrNoPairing
+}
+
+type pairer struct {
+	strict bool
+	steps  int
+}
+
+// pairWithBye gives the bye to the lowest ranked player who hasn't had one yet and can be left out
+func (p *pairer) pairWithBye(ranked []*pairingPlayer) ([]board, *pairingPlayer, bool) {
+	if len(ranked)%2 == 0 {
+		boards, ok := p.pair(ranked)
+		return boards, nil, ok
+	}
+
+	for i := len(ranked) - 1; i >= 0; i-- {
+		if ranked[i].hadBye {
+			continue
+		}
+
+		rest := slices.Delete(slices.Clone(ranked), i, i+1)
+		if boards, ok := p.pair(rest); ok {
+			return boards, ranked[i], true
+		}
+
+		if p.steps > maxPairingSteps {
+			break
+		}
+	}
+
+	return nil, nil, false
+}
+
+// pair takes the top ranked player and tries their candidates in order, backtracking when the rest can't be paired
+func (p *pairer) pair(ranked []*pairingPlayer) ([]board, bool) {
+	if len(ranked) == 0 {
+		return nil, true
+	}
+
+	p.steps++
+	if p.steps > maxPairingSteps {
+		return nil, false
+	}
+
+	top, rest := ranked[0], ranked[1:]
+	for _, opponent := range candidates(top, rest) {
+		if top.opponents[opponent.id] {
+			continue
+		}
+
+		b, ok := allocateColors(top, opponent, p.strict)
+		if !ok {
+			continue
+		}
+
+		remaining := slices.DeleteFunc(slices.Clone(rest), func(pp *pairingPlayer) bool {
+			return pp == opponent
+		})
+
+		if boards, ok := p.pair(remaining); ok {
+			return append([]board{b}, boards...), true
+		}
+	}
+
+	return nil, false
+}
+
+// candidates orders the opponents for the top player of a score group: the top of the bottom half first,
+// then the rest of the bottom half, then their own half from the bottom up and finally the players below
+// the score group. Pairing the top player with the middle of the group each time works out to the first
+// of the top half playing the first of the bottom half, the second the second and so on
+func candidates(top *pairingPlayer, rest []*pairingPlayer) []*pairingPlayer {
+	group := 0
+	for group < len(rest) && rest[group].points == top.points {
+		group++
+	}
+
+	// the score group is top plus the first group players of rest
+	half := max((group+1)/2-1, 0)
+
+	ordered := make([]*pairingPlayer, 0, len(rest))
+	ordered = append(ordered, rest[half:group]...)
+	for i := half - 1; i >= 0; i-- {
+		ordered = append(ordered, rest[i])
+	}
+
+	return append(ordered, rest[group:]...)
+}
+
+// allocateColors gives each player their preferred color where possible, the stronger preference wins
+// and the higher ranked player a wins a tie
+func allocateColors(a, b *pairingPlayer, strict bool) (board, bool) {
+	aPref, aStrength := colorPreference(a.colors)
+	bPref, bStrength := colorPreference(b.colors)
+
+	var aColor game.PieceColor
+	switch {
+	case aPref == game.NoColor && bPref == game.NoColor:
+		aColor = game.Light
+	case aPref == game.NoColor:
+		aColor = game.OpponentPieceColor(bPref)
+	case bPref == game.NoColor, aPref != bPref, aStrength > bStrength:
+		aColor = aPref
+	case bStrength > aStrength:
+		aColor = game.OpponentPieceColor(bPref)
+	default:
+		if strict && aStrength == absolutePreference {
+			return board{}, false
+		}
+		aColor = aPref
+	}
+
+	if strict && (breaksColorRules(a.colors, aColor) || breaksColorRules(b.colors, game.OpponentPieceColor(aColor))) {
+		return board{}, false
+	}
+
+	if aColor == game.Light {
+		return board{light: a, dark: b}, true
+	}
+
+	return board{light: b, dark: a}, true
+}
+
+func colorPreference(colors []game.PieceColor) (game.PieceColor, int) {
+	if len(colors) == 0 {
+		return game.NoColor, noPreference
+	}
+
+	diff := colorDifference(colors)
+	last := colors[len(colors)-1]
+	repeated := len(colors) >= 2 && colors[len(colors)-2] == last
+
+	switch {
+	case diff > 1, repeated && last == game.Light:
+		return game.Dark, absolutePreference
+	case diff < -1, repeated && last == game.Dark:
+		return game.Light, absolutePreference
+	case diff == 1:
+		return game.Dark, strongPreference
+	case diff == -1:
+		return game.Light, strongPreference
+	default:
+		return game.OpponentPieceColor(last), mildPreference
+	}
+}
+
+// breaksColorRules is whether playing color would mean three in a row or being more than two games off balance
+func breaksColorRules(colors []game.PieceColor, color game.PieceColor) bool {
+	colors = append(slices.Clone(colors), color)
+
+	if diff := colorDifference(colors); diff > 2 || diff < -2 {
+		return true
+	}
+
+	n := len(colors)
+	return n >= 3 && colors[n-3] == color && colors[n-2] == color
+}
+
+// colorDifference is how many more games a player has had with light than with dark
+func colorDifference(colors []game.PieceColor) int {
+	var diff int
+	for _, color := range colors {
+		switch color {
+		case game.Light:
+			diff++
+		case game.Dark:
+			diff--
+		}
+	}
+
+	return diff
+}
+
+// alternateFirstColors stops the higher ranked player getting light on every board when nobody has a preference yet
+func alternateFirstColors(boards []board) []board {
+	for i, b := range boards {
+		if i%2 == 1 && len(b.light.colors) == 0 && len(b.dark.colors) == 0 {
+			boards[i] = board{light: b.dark, dark: b.light}
+		}
+	}
+
+	return boards
+}
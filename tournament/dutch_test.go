@@ -0,0 +1,200 @@
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/michaelgov-ctrl/bad-chess/game"
+)
+
+// newPairingPlayers are p1 to pn seeded in that order with no games played
+func newPairingPlayers(n int) []*pairingPlayer {
+	players := make([]*pairingPlayer, n)
+	for i := range players {
+		players[i] = &pairingPlayer{id: fmt.Sprintf("p%d", i+1), seed: i + 1, opponents: make(map[string]bool)}
+	}
+
+	return players
+}
+
+// played records a game between light and dark for both players' pairing history
+func played(light, dark *pairingPlayer) {
+	light.opponents[dark.id], dark.opponents[light.id] = true, true
+	light.colors = append(light.colors, game.Light)
+	dark.colors = append(dark.colors, game.Dark)
+}
+
+// boardStrings are the boards written light-dark
+func boardStrings(boards []board) []string {
+	s := make([]string, len(boards))
+	for i, b := range boards {
+		s[i] = b.light.id + "-" + b.dark.id
+	}
+
+	return s
+}
+
+func ids(players []*pairingPlayer) []string {
+	s := make([]string, len(players))
+	for i, p := range players {
+		s[i] = p.id
+	}
+
+	return s
+}
+
+func TestPairDutchFirstRound(t *testing.T) {
+	boards, bye, err := pairDutch(newPairingPlayers(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the top half plays the bottom half with the first color alternating down the boards
+	want := []string{"p1-p5", "p6-p2", "p3-p7", "p8-p4"}
+	if got := boardStrings(boards); !slices.Equal(got, want) || bye != nil {
+		t.Fatalf("pairDutch = %v, bye %v, want %v and no bye", got, bye, want)
+	}
+}
+
+func TestPairDutchBye(t *testing.T) {
+	players := newPairingPlayers(5)
+
+	boards, bye, err := pairDutch(players)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bye != players[4] || !slices.Equal(boardStrings(boards), []string{"p1-p3", "p4-p2"}) {
+		t.Fatalf("pairDutch = %v, bye %v, want the lowest seed to sit out", boardStrings(boards), bye)
+	}
+
+	// nobody gets a second bye while someone else hasn't had one
+	players[4].hadBye = true
+
+	boards, bye, err = pairDutch(players)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bye != players[3] || !slices.Equal(boardStrings(boards), []string{"p1-p3", "p5-p2"}) {
+		t.Fatalf("pairDutch = %v, bye %v, want p4 to sit out", boardStrings(boards), bye)
+	}
+}
+
+func TestPairDutchNoRepeats(t *testing.T) {
+	players := newPairingPlayers(4)
+	p1, p2, p3, p4 := players[0], players[1], players[2], players[3]
+
+	played(p1, p3)
+	played(p4, p2)
+	p1.points, p4.points = 1, 1
+
+	boards, _, err := pairDutch(players)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the winners meet and so do the losers, each with the color they didn't have
+	want := []string{"p4-p1", "p2-p3"}
+	if got := boardStrings(boards); !slices.Equal(got, want) {
+		t.Fatalf("pairDutch = %v, want %v", got, want)
+	}
+
+	// with everyone already played there's nothing left to pair, repeats are never allowed even when colors are dropped
+	played(p1, p2)
+	played(p3, p4)
+	played(p4, p1)
+	played(p2, p3)
+
+	if _, _, err := pairDutch(players); !errors.Is(err, ErrNoPairing) {
+		t.Fatalf("pairDutch error = %v, want ErrNoPairing", err)
+	}
+}
+
+func TestPairDutchDropsColorRulesWhenItMust(t *testing.T) {
+	players := newPairingPlayers(2)
+	for _, p := range players {
+		p.colors = []game.PieceColor{game.Light, game.Light}
+	}
+
+	// both must have dark so there's no strict pairing, once the rules are dropped the higher seed keeps their color
+	if _, ok := allocateColors(players[0], players[1], true); ok {
+		t.Fatal("allocateColors(strict) paired two players who both must have dark")
+	}
+
+	boards, _, err := pairDutch(players)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := boardStrings(boards); !slices.Equal(got, []string{"p2-p1"}) {
+		t.Fatalf("pairDutch = %v, want p2-p1", got)
+	}
+}
+
+func TestAllocateColors(t *testing.T) {
+	L, D := game.Light, game.Dark
+
+	tests := []struct {
+		name   string
+		a, b   []game.PieceColor
+		strict bool
+		want   game.PieceColor // a's color
+		ok     bool
+	}{
+		{"no preferences", nil, nil, true, L, true},
+		{"only b has one", nil, []game.PieceColor{D}, true, D, true},
+		{"different preferences", []game.PieceColor{D}, []game.PieceColor{L}, true, L, true},
+		{"equal preferences go to a", []game.PieceColor{L, D}, []game.PieceColor{L, D}, true, L, true},
+		{"stronger preference wins", []game.PieceColor{L, D}, []game.PieceColor{D, L, D}, true, D, true},
+		{"forced color", []game.PieceColor{L, L}, []game.PieceColor{D, L}, true, D, true},
+		{"forced over forced", []game.PieceColor{D, D}, []game.PieceColor{D, D}, true, game.NoColor, false},
+		{"forced over forced loosened", []game.PieceColor{D, D}, []game.PieceColor{D, D}, false, L, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &pairingPlayer{id: "a", colors: tt.a}
+			b := &pairingPlayer{id: "b", colors: tt.b}
+
+			got, ok := allocateColors(a, b, tt.strict)
+			if ok != tt.ok {
+				t.Fatalf("allocateColors ok = %v, want %v", ok, tt.ok)
+			}
+
+			if !ok {
+				return
+			}
+
+			if color := colorOf(got, a); color != tt.want {
+				t.Fatalf("a got %v, want %v", color, tt.want)
+			}
+		})
+	}
+}
+
+func colorOf(b board, p *pairingPlayer) game.PieceColor {
+	switch p {
+	case b.light:
+		return game.Light
+	case b.dark:
+		return game.Dark
+	}
+
+	return game.NoColor
+}
+
+func TestCandidates(t *testing.T) {
+	players := newPairingPlayers(6)
+	for _, p := range players[:4] {
+		p.points = 1
+	}
+
+	// the score group is p1 to p4 so p1 tries p3 first, then the rest of that half, their own half and finally below
+	got := ids(candidates(players[0], players[1:]))
+	if want := []string{"p3", "p4", "p2", "p5", "p6"}; !slices.Equal(got, want) {
+		t.Fatalf("candidates = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,536 @@
+package tournament
+
+import (
+	"cmp"
+	"log/slog"
+	"math/bits"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/michaelgov-ctrl/bad-chess/game"
+)
+
+const KnockoutFormat = "knockout"
+
+// stages of a knockout match, the games proper have no stage
+const (
+	StageTieBreak   = "tiebreak"
+	StageArmageddon = "armageddon"
+)
+
+var (
+	// TieBreakGames are played at the tie-break time control when a match is level after its games
+	TieBreakGames = 2
+
+	// if the tie-breaks are level too a single armageddon game decides the match, dark has less time
+	// but goes through with a draw
+	ArmageddonTimeControl = game.NewTimeControl(5*time.Minute, 0, game.NoDelay)
+	ArmageddonDarkTime    = 4 * time.Minute
+)
+
+type KnockoutConfig struct {
+	Name        string
+	Organizer   string
+	TimeControl game.TimeControl
+	Variant     game.Variant
+	// Games is how many games each match is before any tie-breaks
+	Games               int
+	TieBreakTimeControl game.TimeControl
+}
+
+// KnockoutTournament is a single elimination bracket seeded by rating so the top seeds can only meet late on.
+// Each match is a few games with alternating colors, then tie-breaks and finally armageddon, the next bracket
+// round starts once every match in the current one has a winner
+type KnockoutTournament struct {
+	id        string
+	config    KnockoutConfig
+	scheduler MatchScheduler
+	logger    *slog.Logger
+	watching  *broadcaster
+	games     *roundScheduler
+
+	mu       sync.Mutex
+	state    State
+	entrants []*Entrant
+	bracket  [][]*tie
+	// eliminated is the bracket round each knocked out player lost in
+	eliminated map[string]int
+}
+
+// tie is a knockout match between two entrants, higher is the better seed. A bye has no lower entrant
+type tie struct {
+	higher *Entrant
+	lower  *Entrant
+	games  []*Pairing
+	winner *Entrant
+}
+
+// score is how each entrant has done in the tie's games at a stage
+func (t *tie) score(stage string) (higher, lower float64) {
+	for _, pairing := range t.games {
+		if pairing.Stage != stage {
+			continue
+		}
+
+		light, dark := pairing.scores()
+		if pairing.Light == t.higher.UserId {
+			higher, lower = higher+light, lower+dark
+		} else {
+			higher, lower = higher+dark, lower+light
+		}
+	}
+
+	return higher, lower
+}
+
+func (t *tie) played(stage string) int {
+	count := 0
+	for _, pairing := range t.games {
+		if pairing.Stage == stage {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (t *tie) playing() bool {
+	return len(t.games) > 0 && t.games[len(t.games)-1].Result == ""
+}
+
+func newKnockoutTournament(id string, config KnockoutConfig, scheduler MatchScheduler, logger *slog.Logger) *KnockoutTournament {
+	return &KnockoutTournament{
+		id:         id,
+		config:     config,
+		scheduler:  scheduler,
+		logger:     logger,
+		watching:   newBroadcaster(logger),
+		games:      newRoundScheduler(id, scheduler, logger),
+		state:      Registering,
+		eliminated: make(map[string]int),
+	}
+}
+
+func (k *KnockoutTournament) watchers() *broadcaster {
+	return k.watching
+}
+
+func (k *KnockoutTournament) Info() Info {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.info()
+}
+
+func (k *KnockoutTournament) info() Info {
+	return Info{
+		ID:          k.id,
+		Name:        k.config.Name,
+		Format:      KnockoutFormat,
+		Organizer:   k.config.Organizer,
+		State:       k.state,
+		TimeControl: k.config.TimeControl,
+		Variant:     k.config.Variant.String(),
+		Rounds:      bracketRounds(len(k.entrants)),
+		Round:       len(k.bracket),
+		Players:     len(k.entrants),
+	}
+}
+
+// bracketRounds is how many rounds it takes to get down to one player
+func bracketRounds(players int) int {
+	if players < 2 {
+		return 0
+	}
+
+	return bits.Len(uint(players - 1))
+}
+
+func (k *KnockoutTournament) Snapshot() Snapshot {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.snapshot()
+}
+
+func (k *KnockoutTournament) snapshot() Snapshot {
+	snapshot := Snapshot{
+		Info:      k.info(),
+		Standings: k.standings(),
+	}
+
+	if len(k.bracket) > 0 {
+		for _, t := range k.bracket[len(k.bracket)-1] {
+			for _, pairing := range t.games {
+				snapshot.Pairings = append(snapshot.Pairings, *pairing)
+			}
+		}
+	}
+
+	return snapshot
+}
+
+// standings rank players by how far they got, then by seed, callers must hold mu
+func (k *KnockoutTournament) standings() []Standing {
+	points := make(map[string]float64, len(k.entrants))
+	for _, round := range k.bracket {
+		for _, t := range round {
+			for _, pairing := range t.games {
+				light, dark := pairing.scores()
+				points[pairing.Light] += light
+				points[pairing.Dark] += dark
+			}
+		}
+	}
+
+	// players still in are ranked above everyone who's out
+	reached := func(userId string) int {
+		if round, ok := k.eliminated[userId]; ok {
+			return round
+		}
+		return len(k.bracket) + 1
+	}
+
+	ranked := slices.Clone(k.entrants)
+	slices.SortStableFunc(ranked, func(a, b *Entrant) int {
+		if c := cmp.Compare(reached(b.UserId), reached(a.UserId)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Seed, b.Seed)
+	})
+
+	table := make([]Standing, len(ranked))
+	for i, entrant := range ranked {
+		table[i] = Standing{
+			Rank:       i + 1,
+			UserId:     entrant.UserId,
+			Name:       entrant.Name,
+			Rating:     entrant.Rating.String(),
+			Points:     points[entrant.UserId],
+			Eliminated: k.eliminated[entrant.UserId],
+		}
+	}
+
+	return table
+}
+
+func (k *KnockoutTournament) Register(userId, name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.state != Registering {
+		return ErrRegistrationClosed
+	}
+
+	if slices.ContainsFunc(k.entrants, func(entrant *Entrant) bool { return entrant.UserId == userId }) {
+		return ErrAlreadyRegistered
+	}
+
+	k.entrants = append(k.entrants, &Entrant{
+		UserId: userId,
+		Name:   name,
+		Rating: k.scheduler.UserRating(userId, k.config.Variant, k.config.TimeControl),
+		Seed:   len(k.entrants) + 1,
+	})
+
+	k.publishStandings()
+
+	return nil
+}
+
+// Withdraw is only possible before the bracket is drawn
+func (k *KnockoutTournament) Withdraw(userId string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.state != Registering {
+		return ErrRegistrationClosed
+	}
+
+	i := slices.IndexFunc(k.entrants, func(entrant *Entrant) bool {
+		return entrant.UserId == userId
+	})
+	if i < 0 {
+		return ErrNotRegistered
+	}
+
+	k.entrants = slices.Delete(k.entrants, i, i+1)
+	k.publishStandings()
+
+	return nil
+}
+
+// Start seeds the players by rating and draws the bracket, the top seeds get byes when the field isn't a power of two
+func (k *KnockoutTournament) Start() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.state != Registering {
+		return ErrAlreadyStarted
+	}
+
+	if len(k.entrants) < 2 {
+		return ErrNotEnoughPlayers
+	}
+
+	seedByRating(k.entrants)
+	k.state = Running
+
+	var first []*tie
+	order := bracketOrder(1 << bracketRounds(len(k.entrants)))
+	for i := 0; i < len(order); i += 2 {
+		t := &tie{higher: k.entrants[order[i]-1]}
+		if order[i+1] <= len(k.entrants) {
+			t.lower = k.entrants[order[i+1]-1]
+		}
+		first = append(first, t)
+	}
+
+	go k.games.run(k.recordResult)
+	k.startRound(first)
+
+	return nil
+}
+
+// bracketOrder lists the seeds of a bracket of size players top to bottom, neighbouring seeds meet in the first
+// round and seeds 1 and 2 can only meet in the final
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, len(order)*2+1-seed)
+		}
+		order = next
+	}
+
+	return order
+}
+
+// startRound starts every match in a bracket round, byes go straight through. callers must hold mu
+func (k *KnockoutTournament) startRound(ties []*tie) {
+	k.bracket = append(k.bracket, ties)
+	round := len(k.bracket)
+
+	for _, t := range ties {
+		if t.lower == nil {
+			t.winner = t.higher
+			sendPairing(k.watching, k.logger, t.higher.UserId, PairingEvent{Round: round, Bye: true})
+			continue
+		}
+
+		k.nextGame(t)
+	}
+
+	k.logger.Info("tournament round started", "tournament", k.id, "round", round, "matches", len(ties))
+
+	if k.roundFinished() {
+		k.finishRound()
+		return
+	}
+
+	k.publishStandings()
+}
+
+func (k *KnockoutTournament) recordResult(outcome game.MatchOutcome) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	t, pairing := k.pairing(outcome.ID)
+	if pairing == nil || pairing.Result != "" {
+		k.logger.Error("result for unknown tournament match", "tournament", k.id, "MatchId", outcome.ID)
+		return
+	}
+
+	pairing.Result = pairingResult(outcome)
+	k.logger.Info("tournament result", "tournament", k.id, "MatchId", outcome.ID, "result", pairing.Result)
+
+	k.decide(t)
+	if t.winner == nil {
+		// players get a moment back on the tournament page before the next game of the match
+		time.AfterFunc(RoundBreak, func() {
+			k.mu.Lock()
+			defer k.mu.Unlock()
+
+			k.nextGame(t)
+			if k.roundFinished() {
+				k.finishRound()
+				return
+			}
+			k.publishStandings()
+		})
+	}
+
+	if k.roundFinished() {
+		k.finishRound()
+		return
+	}
+
+	k.publishStandings()
+}
+
+// pairing finds the current rounds game for a match, callers must hold mu
+func (k *KnockoutTournament) pairing(id game.MatchId) (*tie, *Pairing) {
+	if len(k.bracket) == 0 {
+		return nil, nil
+	}
+
+	for _, t := range k.bracket[len(k.bracket)-1] {
+		for _, pairing := range t.games {
+			if pairing.MatchID == id {
+				return t, pairing
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// decide settles a tie once one player is ahead at the end of a stage, armageddon always has a winner
+// and if nobody turned up for it the better seed goes through. callers must hold mu
+func (k *KnockoutTournament) decide(t *tie) {
+	if t.playing() {
+		return
+	}
+
+	stage, games := "", k.config.Games
+	switch {
+	case t.played(StageArmageddon) > 0:
+		armageddon := t.games[len(t.games)-1]
+		switch armageddon.Result {
+		case game.LightWon:
+			t.winner = k.entrant(armageddon.Light)
+		case game.DarkWon, game.Draw:
+			t.winner = k.entrant(armageddon.Dark)
+		default:
+			t.winner = t.higher
+		}
+		k.eliminate(t)
+		return
+	case t.played(StageTieBreak) > 0:
+		stage, games = StageTieBreak, TieBreakGames
+	}
+
+	if t.played(stage) < games {
+		return
+	}
+
+	switch higher, lower := t.score(stage); {
+	case higher > lower:
+		t.winner = t.higher
+	case lower > higher:
+		t.winner = t.lower
+	default:
+		return
+	}
+	k.eliminate(t)
+}
+
+func (k *KnockoutTournament) eliminate(t *tie) {
+	loser := t.lower
+	if t.winner == t.lower {
+		loser = t.higher
+	}
+
+	k.eliminated[loser.UserId] = len(k.bracket)
+}
+
+// nextGame schedules the next game of an undecided tie, colors alternate with the better seed taking light first
+// in each stage. callers must hold mu
+func (k *KnockoutTournament) nextGame(t *tie) {
+	if t.winner != nil || t.playing() {
+		return
+	}
+
+	stage, settings := "", gameSettings{timeControl: k.config.TimeControl, variant: k.config.Variant}
+	switch {
+	case t.played("") < k.config.Games:
+	case t.played(StageTieBreak) < TieBreakGames:
+		stage, settings.timeControl = StageTieBreak, k.config.TieBreakTimeControl
+	default:
+		stage, settings.timeControl, settings.darkTime = StageArmageddon, ArmageddonTimeControl, ArmageddonDarkTime
+	}
+
+	light, dark := t.higher, t.lower
+	if t.played(stage)%2 == 1 {
+		light, dark = dark, light
+	}
+
+	pairing := &Pairing{
+		Round:     len(k.bracket),
+		Board:     slices.Index(k.bracket[len(k.bracket)-1], t) + 1,
+		Game:      len(t.games) + 1,
+		Stage:     stage,
+		Light:     light.UserId,
+		LightName: light.Name,
+		Dark:      dark.UserId,
+		DarkName:  dark.Name,
+	}
+	t.games = append(t.games, pairing)
+
+	k.games.schedule(pairing, settings)
+	if pairing.Result != "" {
+		// a game that couldn't be scheduled still moves the tie on, armageddon always settles it
+		k.decide(t)
+		k.nextGame(t)
+		return
+	}
+
+	publishPairings(k.watching, k.logger, []*Pairing{pairing})
+}
+
+func (k *KnockoutTournament) roundFinished() bool {
+	for _, t := range k.bracket[len(k.bracket)-1] {
+		if t.winner == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// finishRound crowns the winner after the final, otherwise the winners are drawn into the next round after
+// a break. callers must hold mu
+func (k *KnockoutTournament) finishRound() {
+	ties := k.bracket[len(k.bracket)-1]
+	if len(ties) == 1 {
+		k.state = Finished
+		k.games.stop()
+		k.publishStandings()
+		return
+	}
+
+	k.publishStandings()
+
+	var next []*tie
+	for i := 0; i < len(ties); i += 2 {
+		a, b := ties[i].winner, ties[i+1].winner
+		if b.Seed < a.Seed {
+			a, b = b, a
+		}
+		next = append(next, &tie{higher: a, lower: b})
+	}
+
+	time.AfterFunc(RoundBreak, func() {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		k.startRound(next)
+	})
+}
+
+func (k *KnockoutTournament) entrant(userId string) *Entrant {
+	for _, entrant := range k.entrants {
+		if entrant.UserId == userId {
+			return entrant
+		}
+	}
+
+	return nil
+}
+
+// publishStandings sends everyone watching the current standings, callers must hold mu
+func (k *KnockoutTournament) publishStandings() {
+	publishStandings(k.watching, k.logger, k.snapshot())
+}
@@ -0,0 +1,193 @@
+package tournament
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/michaelgov-ctrl/bad-chess/game"
+)
+
+func TestBracketOrder(t *testing.T) {
+	tests := []struct {
+		size int
+		want []int
+	}{
+		{1, []int{1}},
+		{2, []int{1, 2}},
+		{4, []int{1, 4, 2, 3}},
+		{8, []int{1, 8, 4, 5, 2, 7, 3, 6}},
+	}
+
+	for _, tt := range tests {
+		if got := bracketOrder(tt.size); !slices.Equal(got, tt.want) {
+			t.Errorf("bracketOrder(%d) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestBergerTables(t *testing.T) {
+	// the FIDE berger tables, with five players whoever meets seed 6 sits the round out
+	tests := []struct {
+		players int
+		want    [][]string
+	}{
+		{4, [][]string{
+			{"1-4", "2-3"},
+			{"4-3", "1-2"},
+			{"2-4", "3-1"},
+		}},
+		{5, [][]string{
+			{"1-6", "2-5", "3-4"},
+			{"6-4", "5-3", "1-2"},
+			{"2-6", "3-1", "4-5"},
+			{"6-5", "1-4", "2-3"},
+			{"3-6", "4-2", "5-1"},
+		}},
+	}
+
+	for _, tt := range tests {
+		tables := bergerTables(tt.players)
+		if len(tables) != len(tt.want) {
+			t.Fatalf("bergerTables(%d) has %d rounds, want %d", tt.players, len(tables), len(tt.want))
+		}
+
+		for r, boards := range tables {
+			got := make([]string, len(boards))
+			for i, b := range boards {
+				got[i] = strconv.Itoa(b.light.seed) + "-" + strconv.Itoa(b.dark.seed)
+			}
+
+			if !slices.Equal(got, tt.want[r]) {
+				t.Errorf("bergerTables(%d) round %d = %v, want %v", tt.players, r+1, got, tt.want[r])
+			}
+		}
+	}
+}
+
+// newTestTie is a knockout match between seeds 1 and 2 with its first game scheduled
+func newTestTie(t *testing.T, games int) (*KnockoutTournament, *tie, *fakeScheduler) {
+	t.Helper()
+
+	scheduler := newFakeScheduler(nil)
+	config := KnockoutConfig{
+		TimeControl:         game.NewTimeControl(10*time.Minute, 0, game.NoDelay),
+		TieBreakTimeControl: game.NewTimeControl(3*time.Minute, 2*time.Second, game.FischerIncrement),
+		Variant:             game.Standard,
+		Games:               games,
+	}
+	k := newKnockoutTournament("knockout", config, scheduler, testLogger())
+
+	for seed := 1; seed <= 2; seed++ {
+		k.entrants = append(k.entrants, &Entrant{UserId: fmt.Sprintf("s%d", seed), Name: fmt.Sprintf("s%d", seed), Seed: seed})
+	}
+
+	tt := &tie{higher: k.entrants[0], lower: k.entrants[1]}
+	k.bracket = [][]*tie{{tt}}
+	k.nextGame(tt)
+
+	return k, tt, scheduler
+}
+
+// finishTieGame settles the tie's current game the way recordResult does, without waiting out the break
+func finishTieGame(k *KnockoutTournament, t *tie, result string) {
+	t.games[len(t.games)-1].Result = pairingResult(game.MatchOutcome{Outcome: result})
+	k.decide(t)
+	k.nextGame(t)
+}
+
+func TestKnockoutTie(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []string
+		// games are each game's stage and colors
+		games  []string
+		winner string
+	}{
+		{
+			name:    "won in the games",
+			results: []string{game.LightWon, game.Draw},
+			games:   []string{":s1-s2", ":s2-s1"},
+			winner:  "s1",
+		},
+		{
+			name:    "won in the tie-breaks",
+			results: []string{game.LightWon, game.LightWon, game.Draw, game.DarkWon},
+			games:   []string{":s1-s2", ":s2-s1", "tiebreak:s1-s2", "tiebreak:s2-s1"},
+			winner:  "s1",
+		},
+		{
+			name:    "armageddon drawn",
+			results: []string{game.Draw, game.Draw, game.LightWon, game.LightWon, game.Draw},
+			games:   []string{":s1-s2", ":s2-s1", "tiebreak:s1-s2", "tiebreak:s2-s1", "armageddon:s1-s2"},
+			winner:  "s2",
+		},
+		{
+			name:    "armageddon won by light",
+			results: []string{game.Draw, game.Draw, game.Draw, game.Draw, game.LightWon},
+			games:   []string{":s1-s2", ":s2-s1", "tiebreak:s1-s2", "tiebreak:s2-s1", "armageddon:s1-s2"},
+			winner:  "s1",
+		},
+		{
+			name:    "armageddon not played",
+			results: []string{game.Draw, game.Draw, game.Draw, game.Draw, "0-0"},
+			games:   []string{":s1-s2", ":s2-s1", "tiebreak:s1-s2", "tiebreak:s2-s1", "armageddon:s1-s2"},
+			winner:  "s1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, match, scheduler := newTestTie(t, 2)
+
+			for i, result := range tt.results {
+				if match.winner != nil {
+					t.Fatalf("tie decided after %d games, want %d", i, len(tt.results))
+				}
+				finishTieGame(k, match, result)
+			}
+
+			if match.winner == nil || match.winner.UserId != tt.winner {
+				t.Fatalf("winner = %v, want %s", match.winner, tt.winner)
+			}
+
+			var games []string
+			for _, pairing := range match.games {
+				games = append(games, pairing.Stage+":"+pairing.Light+"-"+pairing.Dark)
+			}
+
+			if !slices.Equal(games, tt.games) {
+				t.Fatalf("games = %v, want %v", games, tt.games)
+			}
+
+			if len(scheduler.scheduled) != len(tt.games) {
+				t.Fatalf("scheduled %d matches, want %d", len(scheduler.scheduled), len(tt.games))
+			}
+
+			for i, req := range scheduler.scheduled {
+				want := gameSettings{timeControl: k.config.TimeControl}
+				switch match.games[i].Stage {
+				case StageTieBreak:
+					want.timeControl = k.config.TieBreakTimeControl
+				case StageArmageddon:
+					want.timeControl, want.darkTime = ArmageddonTimeControl, ArmageddonDarkTime
+				}
+
+				if req.TimeControl != want.timeControl || req.DarkTime != want.darkTime {
+					t.Errorf("game %d played at %v with dark on %v, want %v and %v", i+1, req.TimeControl, req.DarkTime, want.timeControl, want.darkTime)
+				}
+			}
+
+			loser := "s1"
+			if tt.winner == "s1" {
+				loser = "s2"
+			}
+
+			if k.eliminated[loser] != 1 {
+				t.Fatalf("eliminated = %v, want %s out in round 1", k.eliminated, loser)
+			}
+		})
+	}
+}
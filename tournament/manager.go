@@ -0,0 +1,209 @@
+package tournament
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/michaelgov-ctrl/bad-chess/game"
+)
+
+// Manager keeps every tournament in memory, like matches they don't survive a restart
+type Manager struct {
+	scheduler MatchScheduler
+	logger    *slog.Logger
+
+	mu          sync.RWMutex
+	tournaments map[string]Tournament
+	// order is creation order so lists don't jump around
+	order []string
+}
+
+func NewManager(scheduler MatchScheduler, logger *slog.Logger) *Manager {
+	return &Manager{
+		scheduler:   scheduler,
+		logger:      logger,
+		tournaments: make(map[string]Tournament),
+	}
+}
+
+func (m *Manager) NewSwiss(config SwissConfig) (Tournament, error) {
+	if config.Name == "" {
+		return nil, errors.New("tournament: a tournament needs a name")
+	}
+
+	if config.Rounds < 1 {
+		return nil, errors.New("tournament: a tournament needs at least one round")
+	}
+
+	if _, ok := game.SupportedTimeControls[config.TimeControl]; !ok {
+		return nil, errors.New("tournament: unsupported time control")
+	}
+
+	if config.Variant == nil {
+		config.Variant = game.Standard
+	}
+
+	return m.add(func(id string) Tournament {
+		return newSwissTournament(id, config, m.scheduler, m.logger)
+	}), nil
+}
+
+func (m *Manager) NewArena(config ArenaConfig) (Tournament, error) {
+	if config.Name == "" {
+		return nil, errors.New("tournament: a tournament needs a name")
+	}
+
+	if config.Duration < time.Minute {
+		return nil, errors.New("tournament: an arena needs to last at least a minute")
+	}
+
+	if _, ok := game.SupportedTimeControls[config.TimeControl]; !ok {
+		return nil, errors.New("tournament: unsupported time control")
+	}
+
+	if config.Variant == nil {
+		config.Variant = game.Standard
+	}
+
+	return m.add(func(id string) Tournament {
+		return newArenaTournament(id, config, m.scheduler, m.logger)
+	}), nil
+}
+
+func (m *Manager) NewRoundRobin(config RoundRobinConfig) (Tournament, error) {
+	if config.Name == "" {
+		return nil, errors.New("tournament: a tournament needs a name")
+	}
+
+	if _, ok := game.SupportedTimeControls[config.TimeControl]; !ok {
+		return nil, errors.New("tournament: unsupported time control")
+	}
+
+	if config.Variant == nil {
+		config.Variant = game.Standard
+	}
+
+	return m.add(func(id string) Tournament {
+		return newRoundRobinTournament(id, config, m.scheduler, m.logger)
+	}), nil
+}
+
+// NewKnockout plays tie-breaks at the main time control unless given another one
+func (m *Manager) NewKnockout(config KnockoutConfig) (Tournament, error) {
+	if config.Name == "" {
+		return nil, errors.New("tournament: a tournament needs a name")
+	}
+
+	if config.Games < 1 {
+		return nil, errors.New("tournament: a knockout match needs at least one game")
+	}
+
+	if _, ok := game.SupportedTimeControls[config.TimeControl]; !ok {
+		return nil, errors.New("tournament: unsupported time control")
+	}
+
+	if config.TieBreakTimeControl == (game.TimeControl{}) {
+		config.TieBreakTimeControl = config.TimeControl
+	}
+
+	if _, ok := game.SupportedTimeControls[config.TieBreakTimeControl]; !ok {
+		return nil, errors.New("tournament: unsupported tie-break time control")
+	}
+
+	if config.Variant == nil {
+		config.Variant = game.Standard
+	}
+
+	return m.add(func(id string) Tournament {
+		return newKnockoutTournament(id, config, m.scheduler, m.logger)
+	}), nil
+}
+
+func (m *Manager) add(build func(id string) Tournament) Tournament {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := uuid.NewString()
+	t := build(id)
+
+	m.tournaments[id] = t
+	m.order = append(m.order, id)
+
+	return t
+}
+
+func (m *Manager) Get(id string) (Tournament, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.tournaments[id]
+	if !ok {
+		return nil, ErrNoTournament
+	}
+
+	return t, nil
+}
+
+// List is newest first
+func (m *Manager) List() []Info {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infos := make([]Info, 0, len(m.order))
+	for _, id := range slices.Backward(m.order) {
+		infos = append(infos, m.tournaments[id].Info())
+	}
+
+	return infos
+}
+
+// Start is left to whoever created the tournament
+func (m *Manager) Start(id, userId string) error {
+	t, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if t.Info().Organizer != userId {
+		return ErrNotOrganizer
+	}
+
+	return t.Start()
+}
+
+// ServeWS streams a tournaments standings and, for players, their pairings
+func (m *Manager) ServeWS(w http.ResponseWriter, r *http.Request) {
+	t, err := m.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	conn, err := websocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		m.logger.Error(err.Error())
+		return
+	}
+
+	watcher := &watcher{
+		connection: conn,
+		userId:     game.UserIdFromContext(r.Context()),
+		egress:     make(chan game.Event, watcherBufferSize),
+	}
+
+	// the watcher gets the tournament as it is before any updates
+	if event, err := game.NewOutgoingEvent(EventTournamentStandings, t.Snapshot()); err == nil {
+		watcher.egress <- event
+	}
+
+	b := t.watchers()
+	b.add(watcher)
+
+	go watcher.readEvents(b)
+	go watcher.writeEvents(b)
+}
@@ -0,0 +1,304 @@
+package tournament
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/michaelgov-ctrl/bad-chess/game"
+)
+
+const RoundRobinFormat = "roundrobin"
+
+type RoundRobinConfig struct {
+	Name        string
+	Organizer   string
+	TimeControl game.TimeControl
+	Variant     game.Variant
+	// Double plays every pairing twice, the second cycle with colors swapped
+	Double bool
+}
+
+// RoundRobinTournament has everyone play everyone else following the Berger tables, so it's known up front
+// who plays who in every round and with which pieces. Rounds are played one after the other like swiss
+type RoundRobinTournament struct {
+	id        string
+	config    RoundRobinConfig
+	scheduler MatchScheduler
+	logger    *slog.Logger
+	watching  *broadcaster
+	games     *roundScheduler
+
+	mu       sync.Mutex
+	state    State
+	entrants []*Entrant
+	// schedule is every rounds boards by seed, drawn up when the tournament starts
+	schedule [][]board
+	rounds   rounds
+}
+
+func newRoundRobinTournament(id string, config RoundRobinConfig, scheduler MatchScheduler, logger *slog.Logger) *RoundRobinTournament {
+	return &RoundRobinTournament{
+		id:        id,
+		config:    config,
+		scheduler: scheduler,
+		logger:    logger,
+		watching:  newBroadcaster(logger),
+		games:     newRoundScheduler(id, scheduler, logger),
+		state:     Registering,
+	}
+}
+
+func (rr *RoundRobinTournament) watchers() *broadcaster {
+	return rr.watching
+}
+
+func (rr *RoundRobinTournament) Info() Info {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	return rr.info()
+}
+
+func (rr *RoundRobinTournament) info() Info {
+	return Info{
+		ID:          rr.id,
+		Name:        rr.config.Name,
+		Format:      RoundRobinFormat,
+		Organizer:   rr.config.Organizer,
+		State:       rr.state,
+		TimeControl: rr.config.TimeControl,
+		Variant:     rr.config.Variant.String(),
+		Rounds:      roundRobinRounds(len(rr.entrants), rr.config.Double),
+		Round:       len(rr.rounds),
+		Players:     len(rr.entrants),
+		Double:      rr.config.Double,
+	}
+}
+
+func (rr *RoundRobinTournament) Snapshot() Snapshot {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	return rr.snapshot()
+}
+
+func (rr *RoundRobinTournament) snapshot() Snapshot {
+	snapshot := Snapshot{
+		Info:      rr.info(),
+		Standings: standings(rr.entrants, rr.rounds, false),
+	}
+
+	for _, pairing := range rr.rounds.current() {
+		snapshot.Pairings = append(snapshot.Pairings, *pairing)
+	}
+
+	return snapshot
+}
+
+func (rr *RoundRobinTournament) Register(userId, name string) error {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if rr.state != Registering {
+		return ErrRegistrationClosed
+	}
+
+	if slices.ContainsFunc(rr.entrants, func(entrant *Entrant) bool { return entrant.UserId == userId }) {
+		return ErrAlreadyRegistered
+	}
+
+	rr.entrants = append(rr.entrants, &Entrant{
+		UserId: userId,
+		Name:   name,
+		Rating: rr.scheduler.UserRating(userId, rr.config.Variant, rr.config.TimeControl),
+		Seed:   len(rr.entrants) + 1,
+	})
+
+	rr.publishStandings()
+
+	return nil
+}
+
+// Withdraw is only possible before the tables are drawn up
+func (rr *RoundRobinTournament) Withdraw(userId string) error {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if rr.state != Registering {
+		return ErrRegistrationClosed
+	}
+
+	i := slices.IndexFunc(rr.entrants, func(entrant *Entrant) bool {
+		return entrant.UserId == userId
+	})
+	if i < 0 {
+		return ErrNotRegistered
+	}
+
+	rr.entrants = slices.Delete(rr.entrants, i, i+1)
+	rr.publishStandings()
+
+	return nil
+}
+
+// Start seeds the players by rating, draws up the tables and starts the first round
+func (rr *RoundRobinTournament) Start() error {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if rr.state != Registering {
+		return ErrAlreadyStarted
+	}
+
+	if len(rr.entrants) < 2 {
+		return ErrNotEnoughPlayers
+	}
+
+	seedByRating(rr.entrants)
+	rr.schedule = bergerTables(len(rr.entrants))
+	if rr.config.Double {
+		for _, round := range slices.Clone(rr.schedule) {
+			swapped := make([]board, len(round))
+			for i, b := range round {
+				swapped[i] = board{light: b.dark, dark: b.light}
+			}
+			rr.schedule = append(rr.schedule, swapped)
+		}
+	}
+	rr.state = Running
+
+	go rr.games.run(rr.recordResult)
+	rr.startRound()
+
+	return nil
+}
+
+func (rr *RoundRobinTournament) recordResult(outcome game.MatchOutcome) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	pairing := rr.rounds.pairing(outcome.ID)
+	if pairing == nil || pairing.Result != "" {
+		rr.logger.Error("result for unknown tournament match", "tournament", rr.id, "MatchId", outcome.ID)
+		return
+	}
+
+	pairing.Result = pairingResult(outcome)
+	rr.logger.Info("tournament result", "tournament", rr.id, "MatchId", outcome.ID, "result", pairing.Result)
+
+	if !rr.rounds.finished() {
+		rr.publishStandings()
+		return
+	}
+
+	rr.finishRound()
+}
+
+// finishRound ends the tournament after the last round, otherwise the next round starts after a break.
+// callers must hold mu
+func (rr *RoundRobinTournament) finishRound() {
+	if len(rr.rounds) >= len(rr.schedule) {
+		rr.state = Finished
+		rr.games.stop()
+		rr.publishStandings()
+		return
+	}
+
+	rr.publishStandings()
+
+	time.AfterFunc(RoundBreak, func() {
+		rr.mu.Lock()
+		defer rr.mu.Unlock()
+
+		rr.startRound()
+	})
+}
+
+// startRound schedules the next rounds boards from the tables, with an odd number of players whoever would
+// have played the missing player sits the round out. callers must hold mu
+func (rr *RoundRobinTournament) startRound() {
+	round := len(rr.rounds) + 1
+
+	var pairings []*Pairing
+	for _, b := range rr.schedule[round-1] {
+		if b.light.seed > len(rr.entrants) || b.dark.seed > len(rr.entrants) {
+			sitting := b.light
+			if sitting.seed > len(rr.entrants) {
+				sitting = b.dark
+			}
+			sendPairing(rr.watching, rr.logger, rr.entrants[sitting.seed-1].UserId, PairingEvent{Round: round, Bye: true})
+			continue
+		}
+
+		light, dark := rr.entrants[b.light.seed-1], rr.entrants[b.dark.seed-1]
+		pairing := &Pairing{
+			Round:     round,
+			Board:     len(pairings) + 1,
+			Light:     light.UserId,
+			LightName: light.Name,
+			Dark:      dark.UserId,
+			DarkName:  dark.Name,
+		}
+
+		rr.games.schedule(pairing, gameSettings{timeControl: rr.config.TimeControl, variant: rr.config.Variant})
+		pairings = append(pairings, pairing)
+	}
+
+	rr.rounds = append(rr.rounds, pairings)
+	rr.publishStandings()
+	publishPairings(rr.watching, rr.logger, pairings)
+
+	rr.logger.Info("tournament round started", "tournament", rr.id, "round", round, "boards", len(pairings))
+
+	if rr.rounds.finished() {
+		rr.finishRound()
+	}
+}
+
+// publishStandings sends everyone watching the current standings, callers must hold mu
+func (rr *RoundRobinTournament) publishStandings() {
+	publishStandings(rr.watching, rr.logger, rr.snapshot())
+}
+
+// roundRobinRounds is how many rounds it takes everyone to play everyone, once or twice
+func roundRobinRounds(players int, double bool) int {
+	rounds := max(0, players+players%2-1)
+	if double {
+		rounds *= 2
+	}
+
+	return rounds
+}
+
+// bergerTables are the rounds of a round robin between seeds 1 to players. An odd number of players gets a
+// phantom seed, whoever it's paired with sits that round out.
+//
+// The last seed stays put while the others rotate around it by half the table each round, the last seed
+// alternates colors and everyone else takes light on the left of the board, which gives the FIDE tables
+func bergerTables(players int) [][]board {
+	n := players + players%2
+	ring := n - 1
+
+	tables := make([][]board, ring)
+	for r := range tables {
+		shift := r * n / 2
+		seat := func(i int) *pairingPlayer {
+			return &pairingPlayer{seed: (i+shift)%ring + 1}
+		}
+
+		fixed := &pairingPlayer{seed: n}
+		first := board{light: seat(0), dark: fixed}
+		if r%2 == 1 {
+			first = board{light: fixed, dark: seat(0)}
+		}
+
+		tables[r] = append(tables[r], first)
+		for i := 1; i < n/2; i++ {
+			tables[r] = append(tables[r], board{light: seat(i), dark: seat(ring - i)})
+		}
+	}
+
+	return tables
+}
@@ -0,0 +1,151 @@
+package tournament
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/michaelgov-ctrl/bad-chess/game"
+)
+
+// roundScheduler drives the matches for the formats played in rounds. Each game is scheduled with the matchmaking
+// manager, players who don't turn up lose by forfeit, and results are handed back to the tournament as they come in
+type roundScheduler struct {
+	id        string
+	scheduler MatchScheduler
+	logger    *slog.Logger
+
+	results chan game.MatchOutcome
+	done    chan struct{}
+}
+
+func newRoundScheduler(id string, scheduler MatchScheduler, logger *slog.Logger) *roundScheduler {
+	return &roundScheduler{
+		id:        id,
+		scheduler: scheduler,
+		logger:    logger,
+		results:   make(chan game.MatchOutcome),
+		done:      make(chan struct{}),
+	}
+}
+
+// gameSettings are what a tournament game is played with, darkTime is only set for armageddon games
+type gameSettings struct {
+	timeControl game.TimeControl
+	variant     game.Variant
+	darkTime    time.Duration
+}
+
+// schedule creates the match for a pairing, one that can't be scheduled is a double forfeit
+func (r *roundScheduler) schedule(pairing *Pairing, settings gameSettings) {
+	matchId, err := r.scheduler.ScheduleMatch(game.ScheduledMatch{
+		LightUserId: pairing.Light,
+		DarkUserId:  pairing.Dark,
+		TimeControl: settings.timeControl,
+		Variant:     settings.variant,
+		Results:     r.results,
+		DarkTime:    settings.darkTime,
+	})
+	if err != nil {
+		r.logger.Error("failed to schedule tournament match", "tournament", r.id, "round", pairing.Round, "error", err)
+		pairing.Result = DoubleForfeit
+		return
+	}
+
+	pairing.MatchID = matchId
+}
+
+// run hands each result to record until the tournament is stopped
+func (r *roundScheduler) run(record func(outcome game.MatchOutcome)) {
+	for {
+		select {
+		case outcome := <-r.results:
+			record(outcome)
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *roundScheduler) stop() {
+	close(r.done)
+}
+
+// rounds are the pairings of every round played so far, the last one is the current round
+type rounds [][]*Pairing
+
+func (r rounds) current() []*Pairing {
+	if len(r) == 0 {
+		return nil
+	}
+
+	return r[len(r)-1]
+}
+
+// pairing finds the current rounds pairing for a match
+func (r rounds) pairing(id game.MatchId) *Pairing {
+	for _, pairing := range r.current() {
+		if pairing.MatchID == id {
+			return pairing
+		}
+	}
+
+	return nil
+}
+
+func (r rounds) finished() bool {
+	for _, pairing := range r.current() {
+		if pairing.Result == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// publishStandings sends everyone watching the current standings
+func publishStandings(b *broadcaster, logger *slog.Logger, snapshot Snapshot) {
+	event, err := game.NewOutgoingEvent(EventTournamentStandings, snapshot)
+	if err != nil {
+		logger.Error("failed to create standings event", "tournament", snapshot.Info.ID, "error", err)
+		return
+	}
+
+	b.broadcast(event)
+}
+
+// publishPairings tells each player in the pairings where they're playing
+func publishPairings(b *broadcaster, logger *slog.Logger, pairings []*Pairing) {
+	for _, pairing := range pairings {
+		if pairing.isBye() {
+			sendPairing(b, logger, pairing.Light, PairingEvent{Round: pairing.Round, Bye: true})
+			continue
+		}
+
+		if pairing.MatchID == "" {
+			continue
+		}
+
+		sendPairing(b, logger, pairing.Light, PairingEvent{
+			Round:    pairing.Round,
+			MatchID:  pairing.MatchID,
+			Pieces:   game.Light.String(),
+			Opponent: pairing.DarkName,
+		})
+		sendPairing(b, logger, pairing.Dark, PairingEvent{
+			Round:    pairing.Round,
+			MatchID:  pairing.MatchID,
+			Pieces:   game.Dark.String(),
+			Opponent: pairing.LightName,
+		})
+	}
+}
+
+func sendPairing(b *broadcaster, logger *slog.Logger, userId string, pairingEvent PairingEvent) {
+	event, err := game.NewOutgoingEvent(EventTournamentPairing, pairingEvent)
+	if err != nil {
+		logger.Error("failed to create pairing event", "userId", userId, "error", err)
+		return
+	}
+
+	b.sendTo(userId, event)
+}
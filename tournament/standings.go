@@ -0,0 +1,81 @@
+package tournament
+
+import (
+	"cmp"
+	"slices"
+)
+
+// standings rank players by points, then Buchholz (the sum of their opponents points), then Sonneborn-Berger
+// (the points of the opponents they beat plus half the points of those they drew with) and finally their seed.
+// Only finished pairings count and byes add nothing to either tiebreak. Round robins leave Buchholz out as
+// everyone ends up with the same opponents
+func standings(entrants []*Entrant, rounds [][]*Pairing, withBuchholz bool) []Standing {
+	points := make(map[string]float64, len(entrants))
+	for _, round := range rounds {
+		for _, pairing := range round {
+			light, dark := pairing.scores()
+			points[pairing.Light] += light
+			if !pairing.isBye() {
+				points[pairing.Dark] += dark
+			}
+		}
+	}
+
+	buchholz := make(map[string]float64, len(entrants))
+	sonnebornBerger := make(map[string]float64, len(entrants))
+	for _, round := range rounds {
+		for _, pairing := range round {
+			if pairing.isBye() || pairing.Result == "" {
+				continue
+			}
+
+			light, dark := pairing.scores()
+			if withBuchholz {
+				buchholz[pairing.Light] += points[pairing.Dark]
+				buchholz[pairing.Dark] += points[pairing.Light]
+			}
+			sonnebornBerger[pairing.Light] += light * points[pairing.Dark]
+			sonnebornBerger[pairing.Dark] += dark * points[pairing.Light]
+		}
+	}
+
+	ranked := slices.Clone(entrants)
+	slices.SortStableFunc(ranked, func(a, b *Entrant) int {
+		if c := cmp.Compare(points[b.UserId], points[a.UserId]); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(buchholz[b.UserId], buchholz[a.UserId]); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(sonnebornBerger[b.UserId], sonnebornBerger[a.UserId]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Seed, b.Seed)
+	})
+
+	table := make([]Standing, len(ranked))
+	for i, entrant := range ranked {
+		table[i] = Standing{
+			Rank:            i + 1,
+			UserId:          entrant.UserId,
+			Name:            entrant.Name,
+			Rating:          entrant.Rating.String(),
+			Points:          points[entrant.UserId],
+			Buchholz:        buchholz[entrant.UserId],
+			SonnebornBerger: sonnebornBerger[entrant.UserId],
+		}
+	}
+
+	return table
+}
+
+// seedByRating numbers entrants from the highest rating down, ties keep the order they registered in
+func seedByRating(entrants []*Entrant) {
+	slices.SortStableFunc(entrants, func(a, b *Entrant) int {
+		return cmp.Compare(b.Rating.Rating, a.Rating.Rating)
+	})
+
+	for i, entrant := range entrants {
+		entrant.Seed = i + 1
+	}
+}
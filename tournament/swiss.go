@@ -0,0 +1,320 @@
+package tournament
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/michaelgov-ctrl/bad-chess/game"
+)
+
+const SwissFormat = "swiss"
+
+var (
+	// RoundBreak gives players a moment with the standings before the next round is paired
+	RoundBreak = 15 * time.Second
+
+	ErrAlreadyStarted = errors.New("tournament: already started")
+)
+
+type SwissConfig struct {
+	Name        string
+	Organizer   string
+	Rounds      int
+	TimeControl game.TimeControl
+	Variant     game.Variant
+}
+
+// SwissTournament pairs players with similar scores each round, results come back from the matchmaking
+// manager through the round scheduler and the next round is paired once every board in the current one has finished
+type SwissTournament struct {
+	id        string
+	config    SwissConfig
+	scheduler MatchScheduler
+	logger    *slog.Logger
+	watching  *broadcaster
+	games     *roundScheduler
+
+	mu       sync.Mutex
+	state    State
+	entrants []*Entrant
+	rounds   rounds
+}
+
+func newSwissTournament(id string, config SwissConfig, scheduler MatchScheduler, logger *slog.Logger) *SwissTournament {
+	return &SwissTournament{
+		id:        id,
+		config:    config,
+		scheduler: scheduler,
+		logger:    logger,
+		watching:  newBroadcaster(logger),
+		games:     newRoundScheduler(id, scheduler, logger),
+		state:     Registering,
+	}
+}
+
+func (s *SwissTournament) watchers() *broadcaster {
+	return s.watching
+}
+
+func (s *SwissTournament) Info() Info {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.info()
+}
+
+func (s *SwissTournament) info() Info {
+	return Info{
+		ID:          s.id,
+		Name:        s.config.Name,
+		Format:      SwissFormat,
+		Organizer:   s.config.Organizer,
+		State:       s.state,
+		TimeControl: s.config.TimeControl,
+		Variant:     s.config.Variant.String(),
+		Rounds:      s.config.Rounds,
+		Round:       len(s.rounds),
+		Players:     len(s.entrants),
+	}
+}
+
+func (s *SwissTournament) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.snapshot()
+}
+
+func (s *SwissTournament) snapshot() Snapshot {
+	snapshot := Snapshot{
+		Info:      s.info(),
+		Standings: standings(s.entrants, s.rounds, true),
+	}
+
+	for _, pairing := range s.rounds.current() {
+		snapshot.Pairings = append(snapshot.Pairings, *pairing)
+	}
+
+	return snapshot
+}
+
+func (s *SwissTournament) Register(userId, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != Registering {
+		return ErrRegistrationClosed
+	}
+
+	if s.entrant(userId) != nil {
+		return ErrAlreadyRegistered
+	}
+
+	s.entrants = append(s.entrants, &Entrant{
+		UserId: userId,
+		Name:   name,
+		Rating: s.scheduler.UserRating(userId, s.config.Variant, s.config.TimeControl),
+		Seed:   len(s.entrants) + 1,
+	})
+
+	s.publishStandings()
+
+	return nil
+}
+
+// Withdraw is only possible before the first round is paired
+func (s *SwissTournament) Withdraw(userId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != Registering {
+		return ErrRegistrationClosed
+	}
+
+	i := slices.IndexFunc(s.entrants, func(entrant *Entrant) bool {
+		return entrant.UserId == userId
+	})
+	if i < 0 {
+		return ErrNotRegistered
+	}
+
+	s.entrants = slices.Delete(s.entrants, i, i+1)
+	s.publishStandings()
+
+	return nil
+}
+
+func (s *SwissTournament) entrant(userId string) *Entrant {
+	for _, entrant := range s.entrants {
+		if entrant.UserId == userId {
+			return entrant
+		}
+	}
+
+	return nil
+}
+
+// Start closes registration and pairs the first round, there can't be more rounds than it takes everyone to play everyone
+func (s *SwissTournament) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != Registering {
+		return ErrAlreadyStarted
+	}
+
+	if len(s.entrants) < 2 {
+		return ErrNotEnoughPlayers
+	}
+
+	seedByRating(s.entrants)
+	s.config.Rounds = min(s.config.Rounds, maxSwissRounds(len(s.entrants)))
+	s.state = Running
+
+	go s.games.run(s.recordResult)
+	s.startRound()
+
+	return nil
+}
+
+// everyone can only play everyone else once, with an odd number of players each one also sits out a round
+func maxSwissRounds(players int) int {
+	if players%2 == 1 {
+		return players
+	}
+
+	return players - 1
+}
+
+func (s *SwissTournament) recordResult(outcome game.MatchOutcome) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pairing := s.rounds.pairing(outcome.ID)
+	if pairing == nil || pairing.Result != "" {
+		s.logger.Error("result for unknown tournament match", "tournament", s.id, "MatchId", outcome.ID)
+		return
+	}
+
+	pairing.Result = pairingResult(outcome)
+	s.logger.Info("tournament result", "tournament", s.id, "MatchId", outcome.ID, "result", pairing.Result)
+
+	if !s.rounds.finished() {
+		s.publishStandings()
+		return
+	}
+
+	s.finishRound()
+}
+
+// finishRound ends the tournament after the last round, otherwise the next round is paired after a break.
+// callers must hold mu
+func (s *SwissTournament) finishRound() {
+	if len(s.rounds) >= s.config.Rounds {
+		s.finish()
+		return
+	}
+
+	s.publishStandings()
+
+	time.AfterFunc(RoundBreak, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.startRound()
+	})
+}
+
+func (s *SwissTournament) finish() {
+	s.state = Finished
+	s.games.stop()
+	s.publishStandings()
+}
+
+// startRound pairs the next round and schedules its matches, a match that can't be scheduled is a double forfeit.
+// callers must hold mu
+func (s *SwissTournament) startRound() {
+	round := len(s.rounds) + 1
+
+	boards, bye, err := pairDutch(s.pairingPlayers())
+	if err != nil {
+		s.logger.Error("failed to pair round, ending tournament early", "tournament", s.id, "round", round, "error", err)
+		s.finish()
+		return
+	}
+
+	var pairings []*Pairing
+	for i, b := range boards {
+		pairing := &Pairing{
+			Round:     round,
+			Board:     i + 1,
+			Light:     b.light.id,
+			LightName: s.entrant(b.light.id).Name,
+			Dark:      b.dark.id,
+			DarkName:  s.entrant(b.dark.id).Name,
+		}
+		s.games.schedule(pairing, gameSettings{timeControl: s.config.TimeControl, variant: s.config.Variant})
+
+		pairings = append(pairings, pairing)
+	}
+
+	if bye != nil {
+		pairings = append(pairings, &Pairing{
+			Round:     round,
+			Board:     len(pairings) + 1,
+			Light:     bye.id,
+			LightName: s.entrant(bye.id).Name,
+			Result:    Bye,
+		})
+	}
+
+	s.rounds = append(s.rounds, pairings)
+	s.publishStandings()
+	publishPairings(s.watching, s.logger, pairings)
+
+	s.logger.Info("tournament round started", "tournament", s.id, "round", round, "boards", len(pairings))
+
+	if s.rounds.finished() {
+		s.finishRound()
+	}
+}
+
+// pairingPlayers are the entrants with their history so far, callers must hold mu
+func (s *SwissTournament) pairingPlayers() []*pairingPlayer {
+	players := make(map[string]*pairingPlayer, len(s.entrants))
+	list := make([]*pairingPlayer, len(s.entrants))
+	for i, entrant := range s.entrants {
+		list[i] = &pairingPlayer{id: entrant.UserId, seed: entrant.Seed, opponents: make(map[string]bool)}
+		players[entrant.UserId] = list[i]
+	}
+
+	for _, round := range s.rounds {
+		for _, pairing := range round {
+			light, dark := pairing.scores()
+			lightPlayer := players[pairing.Light]
+			lightPlayer.points += light
+
+			if pairing.isBye() {
+				lightPlayer.hadBye = true
+				continue
+			}
+
+			darkPlayer := players[pairing.Dark]
+			darkPlayer.points += dark
+
+			lightPlayer.opponents[darkPlayer.id], darkPlayer.opponents[lightPlayer.id] = true, true
+			lightPlayer.colors = append(lightPlayer.colors, game.Light)
+			darkPlayer.colors = append(darkPlayer.colors, game.Dark)
+		}
+	}
+
+	return list
+}
+
+// publishStandings sends everyone watching the current standings, callers must hold mu
+func (s *SwissTournament) publishStandings() {
+	publishStandings(s.watching, s.logger, s.snapshot())
+}
@@ -0,0 +1,175 @@
+// Package tournament runs tournaments on top of the matchmaking manager, paired players are given
+// scheduled matches and the outcomes of those matches are fed back into the standings
+package tournament
+
+import (
+	"errors"
+	"time"
+
+	"github.com/michaelgov-ctrl/bad-chess/game"
+	"github.com/michaelgov-ctrl/bad-chess/internal/rating"
+)
+
+var (
+	ErrNoTournament       = errors.New("tournament: no such tournament")
+	ErrRegistrationClosed = errors.New("tournament: registration is closed")
+	ErrAlreadyRegistered  = errors.New("tournament: already registered")
+	ErrNotEnoughPlayers   = errors.New("tournament: not enough players")
+	ErrNotRegistered      = errors.New("tournament: not registered")
+	ErrNotOrganizer       = errors.New("tournament: only the organizer can do that")
+	ErrNoPairing          = errors.New("tournament: no valid pairing")
+)
+
+type State string
+
+const (
+	Registering State = "registering"
+	Running     State = "running"
+	Finished    State = "finished"
+)
+
+// results a pairing can end with on top of game.LightWon, game.DarkWon and game.Draw
+const (
+	// DoubleForfeit is given when a match ended without a result, neither player scores
+	DoubleForfeit = "0-0"
+	// Bye is the unpaired player in a round, it scores a full point
+	Bye = "bye"
+)
+
+// MatchScheduler is the part of the matchmaking manager a tournament needs
+type MatchScheduler interface {
+	ScheduleMatch(req game.ScheduledMatch) (game.MatchId, error)
+	UserRating(userId string, variant game.Variant, timeControl game.TimeControl) rating.Rating
+}
+
+// Tournament is what the manager and web handlers need from every format
+type Tournament interface {
+	Info() Info
+	Register(userId, name string) error
+	Withdraw(userId string) error
+	Start() error
+	Snapshot() Snapshot
+
+	watchers() *broadcaster
+}
+
+type Info struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Format      string           `json:"format"`
+	Organizer   string           `json:"organizer"`
+	State       State            `json:"state"`
+	TimeControl game.TimeControl `json:"time_control"`
+	Variant     string           `json:"variant"`
+	Rounds      int              `json:"rounds,omitempty"`
+	Round       int              `json:"round,omitempty"`
+	Players     int              `json:"players"`
+	// Double round robins play everyone twice
+	Double bool `json:"double,omitempty"`
+	// EndsAt is when a running arena stops pairing
+	EndsAt *time.Time `json:"ends_at,omitempty"`
+}
+
+// Entrant is a registered player, Rating is taken when they register and decides the seeding
+type Entrant struct {
+	UserId string
+	Name   string
+	Rating rating.Rating
+	Seed   int
+}
+
+// Pairing is one board in a round, byes have no dark player and no match
+type Pairing struct {
+	Round     int          `json:"round"`
+	Board     int          `json:"board"`
+	MatchID   game.MatchId `json:"match_id,omitempty"`
+	Light     string       `json:"light"`
+	LightName string       `json:"light_name"`
+	Dark      string       `json:"dark,omitempty"`
+	DarkName  string       `json:"dark_name,omitempty"`
+	Result    string       `json:"result,omitempty"`
+	// knockout matches are several games, Stage is empty for the games proper
+	Game  int    `json:"game,omitempty"`
+	Stage string `json:"stage,omitempty"`
+}
+
+func (p *Pairing) isBye() bool {
+	return p.Dark == ""
+}
+
+// scores are the points each side got from the pairing, unfinished pairings are worth nothing yet
+func (p *Pairing) scores() (light, dark float64) {
+	switch p.Result {
+	case game.LightWon, Bye:
+		return 1, 0
+	case game.DarkWon:
+		return 0, 1
+	case game.Draw:
+		return 0.5, 0.5
+	default:
+		return 0, 0
+	}
+}
+
+// pairingResult maps a match outcome to a result, anything that isn't a win or a draw is a double forfeit
+func pairingResult(outcome game.MatchOutcome) string {
+	switch outcome.Outcome {
+	case game.LightWon, game.DarkWon, game.Draw:
+		return outcome.Outcome
+	default:
+		return DoubleForfeit
+	}
+}
+
+type Standing struct {
+	Rank            int     `json:"rank"`
+	UserId          string  `json:"user_id"`
+	Name            string  `json:"name"`
+	Rating          string  `json:"rating"`
+	Points          float64 `json:"points"`
+	Buchholz        float64 `json:"buchholz,omitempty"`
+	SonnebornBerger float64 `json:"sonneborn_berger,omitempty"`
+	// arena standings show each games points and whether the player is on a winning streak
+	Games  int    `json:"games,omitempty"`
+	Sheet  string `json:"sheet,omitempty"`
+	OnFire bool   `json:"on_fire,omitempty"`
+	Paused bool   `json:"paused,omitempty"`
+	// Eliminated is the round a knockout player went out in
+	Eliminated int `json:"eliminated,omitempty"`
+}
+
+// Snapshot is everything a page needs to show a tournament, Pairings are the current rounds
+type Snapshot struct {
+	Info      Info       `json:"info"`
+	Standings []Standing `json:"standings"`
+	Pairings  []Pairing  `json:"pairings"`
+}
+
+func (s Snapshot) standing(userId string) (Standing, bool) {
+	for _, standing := range s.Standings {
+		if standing.UserId == userId {
+			return standing, true
+		}
+	}
+
+	return Standing{}, false
+}
+
+// CanJoin is whether the user can join now, arenas take players until they finish and welcome back those who withdrew
+func (s Snapshot) CanJoin(userId string) bool {
+	standing, registered := s.standing(userId)
+	if s.Info.Format == ArenaFormat {
+		return s.Info.State != Finished && (!registered || standing.Paused)
+	}
+
+	return s.Info.State == Registering && !registered
+}
+
+func (s Snapshot) CanWithdraw(userId string) bool {
+	standing, registered := s.standing(userId)
+	if s.Info.Format == ArenaFormat {
+		return s.Info.State != Finished && registered && !standing.Paused
+	}
+
+	return s.Info.State == Registering && registered
+}
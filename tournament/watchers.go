@@ -0,0 +1,175 @@
+package tournament
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/michaelgov-ctrl/bad-chess/game"
+)
+
+var (
+	pongWait     = 10 * time.Second
+	pingInterval = (pongWait * 9) / 10 // 90% of pongWait
+
+	watcherBufferSize = 8
+
+	websocketUpgrader = websocket.Upgrader{
+		CheckOrigin:     game.CheckOrigin,
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+)
+
+const (
+	EventTournamentStandings = "tournament_standings"
+	EventTournamentPairing   = "tournament_pairing"
+)
+
+// PairingEvent tells a player where to go for their next game, byes have no match and arena games have no round
+type PairingEvent struct {
+	Round    int          `json:"round,omitempty"`
+	MatchID  game.MatchId `json:"match_id,omitempty"`
+	Pieces   string       `json:"pieces,omitempty"`
+	Opponent string       `json:"opponent,omitempty"`
+	Bye      bool         `json:"bye"`
+}
+
+// watcher is a connection to a tournament page, the tournament only ever writes to it
+type watcher struct {
+	connection *websocket.Conn
+	userId     string
+	egress     chan game.Event
+}
+
+// broadcaster holds a tournaments watchers, sends never block so a slow watcher can't hold up the tournament
+type broadcaster struct {
+	mu       sync.RWMutex
+	watching map[*watcher]bool
+	logger   *slog.Logger
+}
+
+func newBroadcaster(logger *slog.Logger) *broadcaster {
+	return &broadcaster{
+		watching: make(map[*watcher]bool),
+		logger:   logger,
+	}
+}
+
+func (b *broadcaster) add(w *watcher) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.watching[w] = true
+}
+
+func (b *broadcaster) remove(w *watcher) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.watching[w]; ok {
+		w.connection.Close()
+		close(w.egress)
+		delete(b.watching, w)
+	}
+}
+
+// present is whether the user has the tournament open
+func (b *broadcaster) present(userId string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for w := range b.watching {
+		if w.userId == userId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *broadcaster) broadcast(event game.Event) {
+	b.send(event, func(*watcher) bool { return true })
+}
+
+func (b *broadcaster) sendTo(userId string, event game.Event) {
+	b.send(event, func(w *watcher) bool { return w.userId == userId })
+}
+
+func (b *broadcaster) send(event game.Event, to func(*watcher) bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for w := range b.watching {
+		if !to(w) {
+			continue
+		}
+
+		select {
+		case w.egress <- event:
+		default:
+			b.logger.Debug("dropped event for slow tournament watcher", "event", event.Type)
+		}
+	}
+}
+
+// readEvents only keeps the connection alive, watchers have nothing to say to a tournament
+func (w *watcher) readEvents(b *broadcaster) {
+	defer b.remove(w)
+
+	if err := w.connection.SetReadDeadline(time.Now().Add(pongWait)); err != nil {
+		b.logger.Error(err.Error())
+		return
+	}
+
+	w.connection.SetReadLimit(512)
+	w.connection.SetPongHandler(func(string) error {
+		return w.connection.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		if _, _, err := w.connection.ReadMessage(); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				b.logger.Error("error reading message", "error", err)
+			}
+			return
+		}
+	}
+}
+
+func (w *watcher) writeEvents(b *broadcaster) {
+	defer b.remove(w)
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message, ok := <-w.egress:
+			if !ok {
+				if err := w.connection.WriteMessage(websocket.CloseMessage, nil); err != nil {
+					b.logger.Error("connection closed", "error", err)
+				}
+				return
+			}
+
+			data, err := json.Marshal(message)
+			if err != nil {
+				b.logger.Error("error marshalling message", "error", err)
+				return
+			}
+
+			if err := w.connection.WriteMessage(websocket.TextMessage, data); err != nil {
+				b.logger.Error("failed to send message", "error", err)
+				return
+			}
+		case <-ticker.C:
+			if err := w.connection.WriteMessage(websocket.PingMessage, []byte(``)); err != nil {
+				b.logger.Error("ping error", "error", err)
+				return
+			}
+		}
+	}
+}
@@ -220,6 +220,31 @@ var websocketDragDrop = function(gameManager) {
         
         const startId = Number(startPositionId);
         const targetId = Number(e.target.getAttribute("square-id") || e.target.parentNode.parentNode.getAttribute('square-id'));
+
+        // pieces dragged out of a crazyhouse pocket are dropped as P@e4, the server checks the square is free
+        const dropPiece = draggedElement.parentNode.getAttribute("drop-piece");
+        if ( dropPiece ) {
+            const drop = dropPiece + "@" + squareIdToAlgebraicNotation(targetId);
+            gameManager.send(new EventMessage("make_move", `{"move":"${drop}"}`));
+            gameManager.interrupt()
+                .catch((error) => {
+                    temporaryMessage(JSON.stringify(error));
+                });
+            return
+        }
+
+        // dropping the king on its own rook castles with that rook, it's the only way to castle in chess960
+        const targetPiece = e.target.parentNode.getAttribute("id") ?? "";
+        if ( draggedElement.id.includes("king") && targetPiece.includes(playerTurn + "-rook") ) {
+            const castle = targetId > startId ? "O-O" : "O-O-O";
+            gameManager.send(new EventMessage("make_move", `{"move":"${castle}"}`));
+            gameManager.interrupt()
+                .catch((error) => {
+                    temporaryMessage(JSON.stringify(error));
+                });
+            return
+        }
+
         if ( !checkIfValidMove(startId, targetId, playerTurn) ) {
             temporaryMessage("invalid move");
             return
@@ -10,6 +10,30 @@ const gameManager = new GameManager();
 const queryString = window.location.search;
 const urlParams = new URLSearchParams(queryString);
 const elo = urlParams.get('elo');
-const connectionMessage = new EventMessage("new_engine_match", `{"elo":${elo}}`);
+const timecontrol = urlParams.get('timecontrol');
+const fen = urlParams.get('fen');
+const variant = urlParams.get('variant');
+const newEngineMatch = { elo: Number(elo) };
+if ( timecontrol ) {
+    newEngineMatch.time_control = timecontrol;
+}
+if ( variant ) {
+    newEngineMatch.variant = variant;
+}
+if ( fen ) {
+    newEngineMatch.fen = fen;
+}
+const connectionMessage = new EventMessage("new_engine_match", JSON.stringify(newEngineMatch));
 
-gameManager.connect('/engines/ws', connectionMessage);
\ No newline at end of file
+gameManager.connect('/engines/ws', connectionMessage);
+
+document.querySelector("#hint-button")?.addEventListener('click', () => sendMatchAction("request_hint"));
+document.querySelector("#takeback-button")?.addEventListener('click', () => sendMatchAction("takeback"));
+
+const evaluationToggle = document.querySelector("#evaluation-toggle");
+if ( evaluationToggle ) {
+    evaluationToggle.addEventListener("change", () => {
+        gameManager.send(new EventMessage("toggle_evaluation", `{"enabled":${evaluationToggle.checked}}`));
+        document.querySelector("#evaluation-display").hidden = !evaluationToggle.checked;
+    });
+}
\ No newline at end of file
@@ -0,0 +1,110 @@
+// the lobby keeps its own copy of the seeks and matches, the snapshot fills it and updates patch it
+const lobby = {
+    seeks: new Map(),
+    matches: new Map(),
+};
+
+function connectLobby() {
+    const socket = new WebSocket('wss://bad-chess.com/lobby/ws');
+
+    socket.addEventListener('message', (evt) => {
+        const msg = JSON.parse(evt.data);
+
+        switch (msg.type) {
+            case "lobby_snapshot":
+                lobby.seeks.clear();
+                lobby.matches.clear();
+                for (const seek of msg.payload.seeks || []) {
+                    lobby.seeks.set(seek.id, seek);
+                }
+                for (const match of msg.payload.matches || []) {
+                    lobby.matches.set(match.match_id, match);
+                }
+                renderSeeks(socket);
+                renderMatches();
+                break;
+            case "lobby_seek_opened":
+                lobby.seeks.set(msg.payload.id, msg.payload);
+                renderSeeks(socket);
+                break;
+            case "lobby_seek_closed":
+                lobby.seeks.delete(msg.payload.id);
+                renderSeeks(socket);
+                break;
+            case "lobby_match_started":
+                lobby.matches.set(msg.payload.match_id, msg.payload);
+                renderMatches();
+                break;
+            case "lobby_match_ended":
+                lobby.matches.delete(msg.payload.match_id);
+                renderMatches();
+                break;
+            case "seek_accepted":
+                window.location.href = "/matches?id=" + msg.payload.match_id;
+                break;
+            case "match_error":
+                document.getElementById("lobby-message").textContent = msg.payload.error;
+                break;
+            default:
+                console.log("unsupported message type", msg.type);
+        }
+    });
+
+    socket.addEventListener('close', () => {
+        console.log('lobby ws conn closed');
+    });
+}
+
+function renderSeeks(socket) {
+    const seeks = document.getElementById("lobby-seeks");
+    seeks.replaceChildren();
+
+    const sorted = [...lobby.seeks.values()].sort((a, b) => new Date(a.created_at) - new Date(b.created_at));
+    for (const seek of sorted) {
+        const row = tableRow([seek.creator, seek.rating, seek.time_control, seek.variant, seek.color, ""]);
+
+        const button = document.createElement("button");
+        button.textContent = "Accept";
+        button.addEventListener('click', () => {
+            socket.send(JSON.stringify({ type: "accept_seek", payload: { seek_id: seek.id } }));
+        });
+        row.lastChild.appendChild(button);
+
+        seeks.appendChild(row);
+    }
+}
+
+function renderMatches() {
+    const matches = document.getElementById("lobby-matches");
+    matches.replaceChildren();
+
+    for (const match of lobby.matches.values()) {
+        const row = tableRow([
+            match.light + " (" + match.light_rating + ")",
+            match.dark + " (" + match.dark_rating + ")",
+            match.time_control,
+            match.variant,
+            "",
+        ]);
+
+        const link = document.createElement("a");
+        link.href = "/matches/spectate?id=" + match.match_id;
+        link.textContent = "watch";
+        row.lastChild.appendChild(link);
+
+        matches.appendChild(row);
+    }
+}
+
+function tableRow(cells) {
+    const row = document.createElement("tr");
+    for (const cell of cells) {
+        const td = document.createElement("td");
+        td.textContent = cell;
+        row.appendChild(td);
+    }
+
+    return row;
+}
+
+connectLobby();
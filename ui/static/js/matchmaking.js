@@ -3,6 +3,17 @@ const gameManager = new GameManager();
 const queryString = window.location.search;
 const urlParams = new URLSearchParams(queryString);
 const timecontrol = urlParams.get('timecontrol');
-const connectionMessage = new EventMessage("join_match", `{"time_control":"${timecontrol}"}`);
+const matchId = urlParams.get('id');
+const isPrivate = urlParams.get('private') === 'true';
+const fen = urlParams.get('fen');
+const variant = urlParams.get('variant') ?? "standard";
+const color = urlParams.get('color') ?? "random";
 
-gameManager.connect('/matches/ws', connectionMessage);
\ No newline at end of file
+let connectionMessage = new EventMessage("join_match", JSON.stringify({ time_control: timecontrol, variant: variant, color: color }));
+if ( matchId ) {
+    connectionMessage = new EventMessage("join_match_by_id", `{"match_id":"${matchId}"}`);
+} else if ( isPrivate ) {
+    connectionMessage = new EventMessage("new_match", JSON.stringify({ time_control: timecontrol, variant: variant, fen: fen ?? "" }));
+}
+
+gameManager.connect('/matches/ws', connectionMessage);
@@ -0,0 +1,8 @@
+const gameManager = new GameManager();
+
+const queryString = window.location.search;
+const urlParams = new URLSearchParams(queryString);
+const matchId = urlParams.get('id');
+const connectionMessage = new EventMessage("spectate_match", `{"match_id":"${matchId}"}`);
+
+gameManager.connect('/matches/ws', connectionMessage);
@@ -0,0 +1,113 @@
+const tournamentId = document.getElementById("tournament").dataset.id;
+
+// standings and pairings are redrawn from every update, a pairing for us sends us to our board
+function connectTournament() {
+    const socket = new WebSocket('wss://bad-chess.com/tournaments/' + tournamentId + '/ws');
+
+    socket.addEventListener('message', (evt) => {
+        const msg = JSON.parse(evt.data);
+
+        switch (msg.type) {
+            case "tournament_standings":
+                renderTournament(msg.payload);
+                break;
+            case "tournament_pairing":
+                renderPairing(msg.payload);
+                break;
+            default:
+                console.log("unsupported message type", msg.type);
+        }
+    });
+
+    socket.addEventListener('close', () => {
+        console.log('tournament ws conn closed');
+    });
+}
+
+function renderTournament(snapshot) {
+    const info = snapshot.info;
+    const arena = info.format === "arena";
+    const knockout = info.format === "knockout";
+
+    if (arena) {
+        if (info.ends_at) {
+            document.getElementById("tournament-round").textContent = "ends at " + new Date(info.ends_at).toLocaleTimeString();
+        }
+    } else {
+        document.getElementById("tournament-round").textContent = "round " + (info.round ?? 0) + " of " + info.rounds;
+    }
+    document.getElementById("tournament-state").textContent = info.state;
+
+    // swiss players can only join or leave before the first round, arenas until they finish
+    const registration = document.getElementById("tournament-registration");
+    if (registration && (info.state === "finished" || (!arena && info.state !== "registering"))) {
+        registration.remove();
+    }
+
+    const standings = document.getElementById("tournament-standings");
+    standings.replaceChildren();
+    for (const standing of snapshot.standings || []) {
+        const name = standing.name + (standing.on_fire ? " (on fire)" : "") + (standing.paused ? " (paused)" : "");
+        let tiebreaks = [standing.buchholz ?? 0, standing.sonneborn_berger ?? 0];
+        switch (info.format) {
+            case "arena":
+                tiebreaks = [standing.games ?? 0, standing.sheet ?? ""];
+                break;
+            case "knockout":
+                tiebreaks = [standing.eliminated ?? ""];
+                break;
+            case "roundrobin":
+                tiebreaks = [standing.sonneborn_berger ?? 0];
+                break;
+        }
+
+        standings.appendChild(tableRow([standing.rank, name, standing.rating, standing.points, ...tiebreaks]));
+    }
+
+    const pairings = document.getElementById("tournament-pairings");
+    pairings.replaceChildren();
+    for (const pairing of snapshot.pairings || []) {
+        const game = knockout ? [pairing.game + (pairing.stage ? " (" + pairing.stage + ")" : "")] : [];
+        const row = tableRow([pairing.board, ...game, pairing.light_name, pairing.dark_name || "", pairing.result || ""]);
+
+        if (!pairing.result && pairing.match_id) {
+            const link = document.createElement("a");
+            link.href = "/matches/spectate?id=" + pairing.match_id;
+            link.textContent = "watch";
+            row.lastChild.appendChild(link);
+        }
+
+        pairings.appendChild(row);
+    }
+}
+
+function renderPairing(pairing) {
+    const display = document.getElementById("tournament-pairing");
+
+    if (pairing.bye) {
+        display.textContent = "You have a bye in round " + pairing.round + ".";
+        return;
+    }
+
+    const round = pairing.round ? "Round " + pairing.round + ": " : "";
+    display.textContent = round + "you have the " + pairing.pieces + " pieces against " + pairing.opponent + ", taking you to your board...";
+
+    // the match page sends us back here when the game is over
+    sessionStorage.setItem("tournament", window.location.pathname);
+    setTimeout(() => {
+        window.location.href = "/matches?id=" + pairing.match_id;
+    }, 3000);
+}
+
+function tableRow(cells) {
+    const row = document.createElement("tr");
+    for (const cell of cells) {
+        const td = document.createElement("td");
+        td.textContent = cell;
+        row.appendChild(td);
+    }
+
+    return row;
+}
+
+connectTournament();
@@ -72,6 +72,7 @@ function NewMatch(assignedEvtMsg) {
 
     matchInfoDisplay.textContent = "Match ID: " + matchId;
     createBoard(player);
+    showPGNLink(matchId);
 
     const allSquares = document.querySelectorAll(".square");
     allSquares.forEach( square => {
@@ -80,12 +81,23 @@ function NewMatch(assignedEvtMsg) {
         square.addEventListener('drop', websocketDragDrop(gameManager));
     })
 
-    const queryString = window.location.search;
-    const urlParams = new URLSearchParams(queryString);
-    const timecontrol = urlParams.get('timecontrol');
+    const timecontrol = assignedEvtMsg.payload?.time_control;
     
     playerClock.textContent = timecontrol;
     opponentClock.textContent = timecontrol;
+
+    if ( assignedEvtMsg.payload?.berserk_allowed ) {
+        document.querySelector("#berserk-button")?.removeAttribute("hidden");
+    }
+}
+
+function HandlePrivateMatchCreated(privateMatchEvtMsg) {
+    const joinUrl = privateMatchEvtMsg.payload?.join_url;
+    if ( !joinUrl ) {
+        throw new Error("no link for private match");
+    }
+
+    matchInfoDisplay.textContent = "Share this link with your opponent: " + window.location.origin + joinUrl;
 }
 
 function HandlePositionPropagation(propagationEvtMsg) {
@@ -95,10 +107,17 @@ function HandlePositionPropagation(propagationEvtMsg) {
         throw new Error("something went awry");
     }
 
-    const currentPosition = fen.substring(0, fen.indexOf(' '));
+    renderPosition(fen);
+    renderPockets(propagationEvtMsg.payload?.pockets);
+    changePlayer();
+}
+
+function renderPosition(fen) {
+    // crazyhouse fens carry the pockets in brackets after the board and mark promoted pieces with ~
+    const currentPosition = fen.substring(0, fen.indexOf(' ')).split('[')[0];
     let squareId = 0;
     for (const c of currentPosition) {
-        if ( c == '/' ) {
+        if ( c == '/' || c == '~' ) {
             continue;
         }
 
@@ -115,8 +134,112 @@ function HandlePositionPropagation(propagationEvtMsg) {
         square.firstChild.setAttribute('draggable', true)
         squareId++;
     }
+}
 
-    changePlayer();
+// crazyhouse pockets sit above and below the board, only the players own pocket can be dragged from
+function renderPockets(pockets) {
+    if ( !pockets ) {
+        return;
+    }
+
+    const perspective = playerPieces ?? "light";
+    const opponent = perspective === "light" ? "dark" : "light";
+    fillPocket(document.querySelector("#player-pocket"), pockets[perspective], perspective);
+    fillPocket(document.querySelector("#opponent-pocket"), pockets[opponent], opponent);
+}
+
+function fillPocket(pocketDisplay, pieces, color) {
+    if ( !pocketDisplay || !pieces ) {
+        return;
+    }
+
+    pocketDisplay.innerHTML = '';
+    pocketDisplay.removeAttribute("hidden");
+
+    for (const letter of ["Q", "R", "B", "N", "P"]) {
+        const count = pieces[letter] ?? 0;
+        if ( count === 0 ) {
+            continue;
+        }
+
+        const pocketPiece = document.createElement('div');
+        pocketPiece.classList.add('pocket-piece');
+        pocketPiece.setAttribute('drop-piece', letter);
+        pocketPiece.innerHTML = fenCharToPiece(color === "light" ? letter : letter.toLowerCase());
+        pocketPiece.firstChild.setAttribute('draggable', color === playerPieces);
+        pocketPiece.addEventListener('dragstart', dragStart);
+
+        const countDisplay = document.createElement('span');
+        countDisplay.textContent = count;
+        pocketPiece.append(countDisplay);
+
+        pocketDisplay.append(pocketPiece);
+    }
+}
+
+// spectators get a snapshot of the match on join and are always shown the light perspective
+function HandleMatchState(matchStateEvtMsg) {
+    const matchId = matchStateEvtMsg.payload?.match_id;
+    const fen = matchStateEvtMsg.payload?.fen;
+    const turn = matchStateEvtMsg.payload?.turn;
+    if ( !matchId || !fen || !turn ) {
+        throw new Error("could not read match state");
+    }
+
+    if ( playerPieces === null ) {
+        matchInfoDisplay.textContent = "Spectating Match ID: " + matchId;
+        createBoard("light");
+    }
+
+    renderPosition(fen);
+    renderPockets(matchStateEvtMsg.payload?.pockets);
+
+    playerTurn = turn;
+    playerDisplay.textContent = turn;
+
+    HandleClockUpdate({ payload: { clock_owner: "light", time_remaining: matchStateEvtMsg.payload?.light_clock } });
+    HandleClockUpdate({ payload: { clock_owner: "dark", time_remaining: matchStateEvtMsg.payload?.dark_clock } });
+}
+
+function HandleSeekCreated(seekEvtMsg) {
+    const rating = seekEvtMsg.payload?.rating;
+    matchInfoDisplay.textContent = "Searching for an opponent" + (rating ? " near " + rating : "") + "...";
+    document.querySelector("#cancel-seek-button")?.removeAttribute("hidden");
+}
+
+function HandleSeekEnded(msg) {
+    matchInfoDisplay.textContent = msg;
+    document.querySelector("#cancel-seek-button")?.setAttribute("hidden", "");
+}
+
+function HandleEvaluation(evaluationEvtMsg) {
+    const evaluationDisplay = document.querySelector("#evaluation-display");
+    if ( !evaluationDisplay || !evaluationEvtMsg.payload ) {
+        return;
+    }
+
+    const { cp, mate, depth, pv } = evaluationEvtMsg.payload;
+    let score = (cp / 100).toFixed(2);
+    if ( mate ) {
+        score = "#" + mate;
+    } else if ( cp > 0 ) {
+        score = "+" + score;
+    }
+
+    evaluationDisplay.textContent = score + " (depth " + depth + ") " + (pv ?? []).join(" ");
+}
+
+function HandleMatchStarted(matchStartedEvtMsg) {
+    const lightRating = matchStartedEvtMsg.payload?.light_rating;
+    const darkRating = matchStartedEvtMsg.payload?.dark_rating;
+    const playerRating = document.querySelector("#player-rating");
+    const opponentRating = document.querySelector("#opponent-rating");
+    if ( !lightRating || !darkRating || !playerRating || !opponentRating ) {
+        return;
+    }
+
+    playerRating.textContent = "(" + (playerPieces === "light" ? lightRating : darkRating) + ")";
+    opponentRating.textContent = "(" + (playerPieces === "light" ? darkRating : lightRating) + ")";
 }
 
 function HandleClockUpdate(clockUpdateEvtMsg) {
@@ -134,9 +257,73 @@ function HandleClockUpdate(clockUpdateEvtMsg) {
     }
 }
 
+function HandleDrawOffered() {
+    document.getElementById("draw-offer-window").style.display = "block";
+}
+
+// engine games are served under /engines, everything else under /matches
+function showPGNLink(matchId) {
+    const pgnLink = document.getElementById("pgn-link");
+    if ( !pgnLink ) {
+        return;
+    }
+
+    const base = window.location.pathname.startsWith("/engines") ? "/engines/" : "/matches/";
+    pgnLink.href = base + encodeURIComponent(matchId) + "/pgn";
+    pgnLink.hidden = false;
+}
+
+function HandleBerserked(berserkedEvtMsg) {
+    const player = berserkedEvtMsg.payload?.player;
+    if ( player === playerPieces ) {
+        document.querySelector("#berserk-button")?.setAttribute("hidden", "");
+    }
+
+    temporaryMessage((player ?? "") + " berserked!");
+}
+
+function HandleMatchOver(matchOverEvtMsg) {
+    const outcome = matchOverEvtMsg.payload?.outcome;
+    const method = matchOverEvtMsg.payload?.method;
+
+    matchInfoDisplay.textContent = "match over" + (outcome ? ": " + outcome : "") + (method ? " by " + method : "");
+    turnDisplay.textContent = "";
+    document.querySelector("#berserk-button")?.setAttribute("hidden", "");
+
+    // tournament games send players back to the tournament to be paired again
+    const tournament = sessionStorage.getItem("tournament");
+    if ( tournament ) {
+        sessionStorage.removeItem("tournament");
+        setTimeout(() => window.location.href = tournament, 5000);
+    }
+}
+
+function sendMatchAction(type) {
+    gameManager.send(new EventMessage(type, `{}`));
+    gameManager.interrupt()
+        .catch((error) => {
+            temporaryMessage(JSON.stringify(error));
+        });
+}
+
+function answerDrawOffer(type) {
+    document.getElementById("draw-offer-window").style.display = "none";
+    sendMatchAction(type);
+}
+
+document.querySelector("#resign-button")?.addEventListener('click', () => sendMatchAction("resign"));
+document.querySelector("#offer-draw-button")?.addEventListener('click', () => sendMatchAction("offer_draw"));
+document.querySelector("#abort-button")?.addEventListener('click', () => sendMatchAction("abort"));
+document.querySelector("#berserk-button")?.addEventListener('click', () => sendMatchAction("berserk"));
+document.querySelector("#cancel-seek-button")?.addEventListener('click', () => sendMatchAction("cancel_seek"));
+document.querySelector("#accept-draw-button")?.addEventListener('click', () => answerDrawOffer("accept_draw"));
+document.querySelector("#decline-draw-button")?.addEventListener('click', () => answerDrawOffer("decline_draw"));
+
 class GameManager {
     socket = null;
     interuptMessage = null;
+    // set when the server seats us back into a match we dropped out of
+    matchAssigned = false;
 
     connect(endpoint, connMsg) {
         this.socket = new WebSocket('wss://bad-chess.com' + endpoint);
@@ -145,6 +332,10 @@ class GameManager {
             console.log('ws conn opened');
 
             setTimeout(function(){
+                if ( gameManager.matchAssigned ) {
+                    return;
+                }
+
                 gameManager.send(connMsg);
                 gameManager.interrupt()
                     .catch((error) => {
@@ -164,7 +355,9 @@ class GameManager {
 
         this.socket.addEventListener('close', (c) => {
             console.log("ws conn closed", c)
-            matchInfoDisplay.textContent = "match over";
+            if ( !matchInfoDisplay.textContent.startsWith("match over") ) {
+                matchInfoDisplay.textContent = "match over";
+            }
             turnDisplay.textContent = "";
             this.socket = null;
         });
@@ -200,6 +393,8 @@ class GameManager {
     
         switch (evtMsg.type) {
             case "assigned_match":
+                this.matchAssigned = true;
+                document.querySelector("#cancel-seek-button")?.setAttribute("hidden", "");
                 try {
                     NewMatch(evtMsg);
                 } catch (error) {
@@ -214,12 +409,60 @@ class GameManager {
                     this.interuptMessage = error;
                 }
 
+                break;
+            case "private_match_created":
+                try {
+                    HandlePrivateMatchCreated(evtMsg);
+                } catch (error) {
+                    this.interuptMessage = error;
+                }
+
+                break;
+            case "seek_created":
+                HandleSeekCreated(evtMsg);
+                break;
+            case "seek_cancelled":
+                HandleSeekEnded("search cancelled");
+                break;
+            case "seek_expired":
+                HandleSeekEnded("no opponent found, seek expired");
+                break;
+            case "match_started":
+                HandleMatchStarted(evtMsg);
+                break;
+            case "evaluation":
+                HandleEvaluation(evtMsg);
+                break;
+            case "hint":
+                temporaryMessage("hint: " + (evtMsg.payload?.move ?? ""));
+                break;
+            case "match_state":
+                try {
+                    HandleMatchState(evtMsg);
+                } catch (error) {
+                    this.interuptMessage = error;
+                }
+
                 break;
             case "match_over":
-                matchInfoDisplay.textContent = "match over";
-                turnDisplay.textContent = "";
+                HandleMatchOver(evtMsg);
                 this.socket.close(1000, 'User initiated closure');
                 break;
+            case "draw_offered":
+                HandleDrawOffered();
+                break;
+            case "berserked":
+                HandleBerserked(evtMsg);
+                break;
+            case "draw_declined":
+                temporaryMessage("draw declined");
+                break;
+            case "opponent_disconnected":
+                temporaryMessage("opponent disconnected, waiting " + (evtMsg.payload?.grace_period ?? "") + " for them to return");
+                break;
+            case "opponent_reconnected":
+                temporaryMessage("opponent reconnected");
+                break;
             case "match_error":
                 this.interuptMessage = evtMsg.payload;
                 